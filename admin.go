@@ -0,0 +1,387 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mattn/go-sqlite3"
+)
+
+// =========================================================
+// [管理員 API：修改、下架與稽核紀錄]
+// =========================================================
+
+// ErrDuplicateURL 新增或修改時 url 與既有資料重複
+var ErrDuplicateURL = errors.New("url 已存在")
+
+// AdminMeme 是後台看到的資料，比一般 API 多了下架時間
+type AdminMeme struct {
+	Meme
+	DeletedAt *string `json:"deleted_at"`
+}
+
+type AuditEntry struct {
+	ID        int64  `json:"id"`
+	MemeID    int64  `json:"meme_id"`
+	Action    string `json:"action"`
+	Actor     string `json:"actor"`
+	Before    string `json:"before,omitempty"`
+	After     string `json:"after,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+// writeAudit 在同一個交易中寫入稽核紀錄，before/after 為 nil 時留空
+func writeAudit(tx *sql.Tx, memeID int64, action, actor string, before, after any) error {
+	encode := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	_, err := tx.Exec(`INSERT INTO audit_log (meme_id, action, actor, before_json, after_json) VALUES (?, ?, ?, ?, ?)`,
+		memeID, action, actor, encode(before), encode(after))
+	return err
+}
+
+// queryRower 讓查詢可以在交易內外共用 (*sql.DB 與 *sql.Tx 皆符合)
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func getAdminMeme(q queryRower, id int64) (AdminMeme, error) {
+	var am AdminMeme
+	var err error
+	am.Meme, err = scanMeme(q.QueryRow(`SELECT `+memeColumns+`, deleted_at FROM memes WHERE id = ?`, id), &am.DeletedAt)
+	if err == sql.ErrNoRows {
+		return AdminMeme{}, ErrNotFound
+	}
+	return am, err
+}
+
+// ListAdminMemes 供後台列表使用，可選擇是否包含已下架的項目
+func ListAdminMemes(query string, includeDeleted bool, limit, offset int) ([]AdminMeme, error) {
+	sqlQuery := `SELECT ` + memeColumns + `, deleted_at FROM memes WHERE (title LIKE ? OR tags LIKE ? OR url LIKE ?)`
+	if !includeDeleted {
+		sqlQuery += ` AND ` + visibleSQL
+	}
+	sqlQuery += ` ORDER BY id DESC LIMIT ? OFFSET ?`
+
+	likeQuery := "%" + query + "%"
+	rows, err := db.Query(sqlQuery, likeQuery, likeQuery, likeQuery, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memes := []AdminMeme{}
+	for rows.Next() {
+		var am AdminMeme
+		var err error
+		if am.Meme, err = scanMeme(rows, &am.DeletedAt); err != nil {
+			return nil, err
+		}
+		memes = append(memes, am)
+	}
+	return memes, rows.Err()
+}
+
+// CreateMeme 新增一筆資料並回傳含 id 的結果；與 InsertMeme 不同，url 重複時會回報錯誤
+func CreateMeme(m Meme, actor string) (Meme, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Meme{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO memes (title, url, tags, source_url) VALUES (?, ?, ?, ?)`, m.Title, m.URL, m.Tags, m.SourceURL)
+	if isUniqueViolation(err) {
+		return Meme{}, ErrDuplicateURL
+	}
+	if err != nil {
+		return Meme{}, err
+	}
+	m.ID, _ = res.LastInsertId()
+
+	if err := writeAudit(tx, m.ID, "create", actor, nil, m); err != nil {
+		return Meme{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Meme{}, err
+	}
+	// 和爬蟲寫入的資料一樣推送給 /api/stream 與已儲存的搜尋
+	memeEvents.Publish(m)
+	return m, nil
+}
+
+// UpdateMeme 修改標題、內容、標籤與來源，已下架的項目也可以修改
+func UpdateMeme(id int64, m Meme, actor string) (Meme, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return Meme{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := getAdminMeme(tx, id)
+	if err != nil {
+		return Meme{}, err
+	}
+
+	// 換了 url 時，舊的鏡像檔與連結檢查結果都不再適用，一起清掉 (之後由鏡像與連結檢查重新處理)
+	_, err = tx.Exec(`UPDATE memes SET title = ?, tags = ?, source_url = ?,
+			media_hash = CASE WHEN url = ? THEN media_hash END,
+			media_thumbnails = CASE WHEN url = ? THEN media_thumbnails ELSE 0 END,
+			link_status = CASE WHEN url = ? THEN link_status END,
+			link_checked_at = CASE WHEN url = ? THEN link_checked_at END,
+			link_failures = CASE WHEN url = ? THEN link_failures ELSE 0 END,
+			url = ?
+		WHERE id = ?`, m.Title, m.Tags, m.SourceURL, m.URL, m.URL, m.URL, m.URL, m.URL, m.URL, id)
+	if isUniqueViolation(err) {
+		return Meme{}, ErrDuplicateURL
+	}
+	if err != nil {
+		return Meme{}, err
+	}
+	m.ID = id
+
+	if err := writeAudit(tx, id, "update", actor, before.Meme, m); err != nil {
+		return Meme{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Meme{}, err
+	}
+	memeEdits.Add(1)
+	return m, nil
+}
+
+// SetMemeDeleted 軟刪除 (deleted=true) 或復原 (deleted=false) 一筆資料
+func SetMemeDeleted(id int64, deleted bool, actor string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := getAdminMeme(tx, id)
+	if err != nil {
+		return err
+	}
+
+	action := "restore"
+	stmt := `UPDATE memes SET deleted_at = NULL WHERE id = ?`
+	if deleted {
+		action = "delete"
+		stmt = `UPDATE memes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`
+	}
+	if _, err := tx.Exec(stmt, id); err != nil {
+		return err
+	}
+
+	if err := writeAudit(tx, id, action, actor, before.Meme, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	memeEdits.Add(1)
+	return nil
+}
+
+// ListAuditLog 依時間倒序列出稽核紀錄，memeID 為 0 時列出全部
+func ListAuditLog(memeID int64, limit int) ([]AuditEntry, error) {
+	sqlQuery := `SELECT id, meme_id, action, actor, before_json, after_json, created_at FROM audit_log`
+	args := []any{}
+	if memeID > 0 {
+		sqlQuery += ` WHERE meme_id = ?`
+		args = append(args, memeID)
+	}
+	sqlQuery += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.MemeID, &e.Action, &e.Actor, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+// adminToken 由環境變數 ADMIN_TOKEN 設定；未設定時管理 API 一律拒絕
+func adminToken() string {
+	return os.Getenv("ADMIN_TOKEN")
+}
+
+// adminAuth 驗證 Authorization: Bearer <token> 或 X-Admin-Token 標頭
+func adminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := adminToken()
+		if expected == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "伺服器未設定 ADMIN_TOKEN，管理功能停用"})
+			return
+		}
+
+		token := c.GetHeader("X-Admin-Token")
+		if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "管理員驗證失敗"})
+			return
+		}
+
+		actor := c.GetHeader("X-Admin-User")
+		if actor == "" {
+			actor = "admin"
+		}
+		c.Set("actor", actor)
+		c.Next()
+	}
+}
+
+// memeInput 是新增/修改時接受的欄位
+type memeInput struct {
+	Title     string `json:"title"`
+	URL       string `json:"url" binding:"required"`
+	Tags      string `json:"tags"`
+	SourceURL string `json:"source_url"`
+}
+
+func (in memeInput) toMeme() Meme {
+	return Meme{Title: strings.TrimSpace(in.Title), URL: strings.TrimSpace(in.URL), Tags: strings.TrimSpace(in.Tags), SourceURL: strings.TrimSpace(in.SourceURL)}
+}
+
+// respondStoreError 把資料層錯誤轉成對應的 HTTP 狀態碼
+func respondStoreError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDuplicateURL):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func queryInt(c *gin.Context, key string, def, max int) int {
+	n, err := strconv.Atoi(c.Query(key))
+	if err != nil || n < 0 {
+		return def
+	}
+	if max > 0 && n > max {
+		return max
+	}
+	return n
+}
+
+// registerAdminRoutes 回傳管理員 API 群組，讓其他功能也能掛在同一套驗證底下
+func registerAdminRoutes(r *gin.Engine) *gin.RouterGroup {
+	// 後台頁面本身不含資料，登入用的 token 由頁面存在瀏覽器裡
+	r.GET("/admin", func(c *gin.Context) {
+		c.HTML(http.StatusOK, "admin.html", nil)
+	})
+
+	admin := r.Group("/api/admin", adminAuth())
+
+	admin.GET("/memes", func(c *gin.Context) {
+		memes, err := ListAdminMemes(c.Query("q"), c.Query("include_deleted") == "1", queryInt(c, "limit", 50, 200), queryInt(c, "offset", 0, 0))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, memes)
+	})
+
+	admin.POST("/memes", func(c *gin.Context) {
+		var in memeInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		meme, err := CreateMeme(in.toMeme(), c.GetString("actor"))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, meme)
+	})
+
+	admin.PUT("/memes/:id", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		var in memeInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		meme, err := UpdateMeme(id, in.toMeme(), c.GetString("actor"))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, meme)
+	})
+
+	admin.DELETE("/memes/:id", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		if err := SetMemeDeleted(id, true, c.GetString("actor")); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.POST("/memes/:id/restore", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		if err := SetMemeDeleted(id, false, c.GetString("actor")); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.GET("/audit", func(c *gin.Context) {
+		memeID, _ := strconv.ParseInt(c.Query("meme_id"), 10, 64)
+		entries, err := ListAuditLog(memeID, queryInt(c, "limit", 100, 500))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+	})
+
+	return admin
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func adminRequest(r http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminCRUDAndSoftDelete(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t)
+
+	// 沒帶 token 要被擋下
+	if w := doRequest(r, "GET", "/api/admin/memes"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("未驗證預期 401，得到 %d", w.Code)
+	}
+
+	w := adminRequest(r, "POST", "/api/admin/memes", memeInput{Title: "錯字標提", URL: "一段垃圾廣告文", Tags: "Threads", SourceURL: "https://www.threads.net/@spam"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("新增預期 201，得到 %d: %s", w.Code, w.Body.String())
+	}
+	var created Meme
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	// url 重複要回 409
+	if w := adminRequest(r, "POST", "/api/admin/memes", memeInput{URL: "一段垃圾廣告文"}); w.Code != http.StatusConflict {
+		t.Errorf("重複 url 預期 409，得到 %d", w.Code)
+	}
+
+	w = adminRequest(r, "PUT", fmt.Sprintf("/api/admin/memes/%d", created.ID), memeInput{Title: "正確標題", URL: "一段垃圾廣告文", Tags: "Threads"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("修改預期 200，得到 %d", w.Code)
+	}
+	if m, _ := GetMemeByID(created.ID); m.Title != "正確標題" {
+		t.Errorf("標題未更新: %q", m.Title)
+	}
+
+	if w := adminRequest(r, "DELETE", fmt.Sprintf("/api/admin/memes/%d", created.ID), nil); w.Code != http.StatusNoContent {
+		t.Fatalf("下架預期 204，得到 %d", w.Code)
+	}
+
+	// 下架後搜尋、隨機、詳細頁都看不到
+	if results, _ := SearchMemes("垃圾", "all"); len(results) != 0 {
+		t.Errorf("下架後仍搜尋得到: %+v", results)
+	}
+	if _, err := GetRandomMeme("all"); err != ErrNotFound {
+		t.Errorf("下架後仍抽得到: %v", err)
+	}
+	if w := doRequest(r, "GET", fmt.Sprintf("/api/memes/%d", created.ID)); w.Code != http.StatusNotFound {
+		t.Errorf("下架後詳細資料預期 404，得到 %d", w.Code)
+	}
+
+	// 後台仍可看到並復原
+	var listed []AdminMeme
+	json.Unmarshal(adminRequest(r, "GET", "/api/admin/memes?include_deleted=1", nil).Body.Bytes(), &listed)
+	if len(listed) != 1 || listed[0].DeletedAt == nil {
+		t.Fatalf("後台列表應包含已下架項目: %+v", listed)
+	}
+	adminRequest(r, "POST", fmt.Sprintf("/api/admin/memes/%d/restore", created.ID), nil)
+	if results, _ := SearchMemes("垃圾", "all"); len(results) != 1 {
+		t.Error("復原後應該搜尋得到")
+	}
+
+	var entries []AuditEntry
+	json.Unmarshal(adminRequest(r, "GET", fmt.Sprintf("/api/admin/audit?meme_id=%d", created.ID), nil).Body.Bytes(), &entries)
+	actions := []string{}
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	if fmt.Sprint(actions) != "[restore delete update create]" {
+		t.Errorf("稽核紀錄不符: %v", actions)
+	}
+}
+
+func TestAdminUpdateURLClearsMedia(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t,
+		ExportMeme{Title: "舊圖", URL: "https://example.com/old.gif"},
+		ExportMeme{Title: "不變", URL: "https://example.com/same.gif"},
+	)
+	db.Exec(`UPDATE memes SET media_hash = 'abc', media_thumbnails = 1, link_status = 404, link_checked_at = CURRENT_TIMESTAMP, link_failures = 5`)
+
+	w := adminRequest(r, "PUT", "/api/admin/memes/1", memeInput{Title: "新圖", URL: "https://example.com/new.gif"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("修改預期 200，得到 %d: %s", w.Code, w.Body.String())
+	}
+	w = adminRequest(r, "PUT", "/api/admin/memes/2", memeInput{Title: "只改標題", URL: "https://example.com/same.gif"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("修改預期 200，得到 %d: %s", w.Code, w.Body.String())
+	}
+
+	type state struct {
+		URL        string
+		MediaHash  *string
+		Thumbnails int
+		Status     *int
+		CheckedAt  *string
+		Failures   int
+	}
+	load := func(id int) state {
+		var s state
+		db.QueryRow(`SELECT url, media_hash, media_thumbnails, link_status, link_checked_at, link_failures FROM memes WHERE id = ?`, id).
+			Scan(&s.URL, &s.MediaHash, &s.Thumbnails, &s.Status, &s.CheckedAt, &s.Failures)
+		return s
+	}
+
+	// 換了 url：鏡像與連結檢查結果都清掉，之後會重新鏡像
+	if s := load(1); s.URL != "https://example.com/new.gif" || s.MediaHash != nil || s.Thumbnails != 0 || s.Status != nil || s.CheckedAt != nil || s.Failures != 0 {
+		t.Errorf("換 url 後應清除鏡像與連結狀態: %+v", s)
+	}
+	if m, _ := GetMemeByID(1); m.MediaURL != "" {
+		t.Errorf("換 url 後不應再指向舊的鏡像檔: %q", m.MediaURL)
+	}
+	// url 沒變：保留原本的狀態
+	if s := load(2); s.MediaHash == nil || *s.MediaHash != "abc" || s.Thumbnails != 1 || s.Failures != 5 {
+		t.Errorf("url 沒變時不應清除: %+v", s)
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [搜尋分析：查詢紀錄、零結果與點擊率]
+// =========================================================
+
+const maxLoggedQueryLen = 200
+
+var ErrInvalidSearchAction = errors.New("action 只接受 click 或 copy")
+var ErrNotInSearch = errors.New("這筆資料不在該次搜尋的結果中")
+
+// analyticsSalt 用來把使用者識別雜湊成匿名代號。
+// 沒有設定 ANALYTICS_SALT 時每次啟動隨機產生，重新啟動後就無法對應回之前的代號
+var analyticsSalt = func() string {
+	if s := os.Getenv("ANALYTICS_SALT"); s != "" {
+		return s
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+// anonymousClient 回傳當天的匿名代號：同一天內可以計算不重複人數，跨日則無法串連
+func anonymousClient(c *gin.Context) string {
+	return anonymousID(voterID(c))
+}
+
+// anonymousID 把使用者識別 (user:1、key:2、ip:...) 雜湊成當天的匿名代號
+func anonymousID(id string) string {
+	sum := sha256.Sum256([]byte(analyticsSalt + "|" + time.Now().UTC().Format(dateLayout) + "|" + id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SearchLogEntry 是一次搜尋的紀錄
+type SearchLogEntry struct {
+	Query       string
+	Mode        string
+	ResultCount int
+	Latency     time.Duration
+	Client      string
+	ResultIDs   []int64 // 第一頁結果的 id，回報點擊時用來驗證
+}
+
+// normalizeLoggedQuery 統一空白與大小寫，過長的查詢截斷
+func normalizeLoggedQuery(q string) string {
+	q = strings.ToLower(strings.Join(strings.Fields(q), " "))
+	if utf8.RuneCountInString(q) > maxLoggedQueryLen {
+		q = string([]rune(q)[:maxLoggedQueryLen])
+	}
+	return q
+}
+
+// LogSearch 記錄一次搜尋，回傳的 id 讓前端回報點擊
+func LogSearch(e SearchLogEntry) (int64, error) {
+	ids := make([]string, len(e.ResultIDs))
+	for i, id := range e.ResultIDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	res, err := db.Exec(`INSERT INTO search_log (query, mode, result_count, latency_ms, client, result_ids) VALUES (?, ?, ?, ?, ?, ?)`,
+		normalizeLoggedQuery(e.Query), e.Mode, e.ResultCount, e.Latency.Milliseconds(), e.Client, strings.Join(ids, ","))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RecordSearchClick 記錄使用者從某次搜尋結果中點擊或複製了哪一筆，同一筆重複點擊只算一次。
+// 只接受該次搜尋實際回傳、而且目前仍看得到的資料；名次以搜尋當時的順序為準，不採用前端回報的值
+func RecordSearchClick(searchID, memeID int64, action string) error {
+	if action != "click" && action != "copy" {
+		return ErrInvalidSearchAction
+	}
+	var resultIDs string
+	err := db.QueryRow(`SELECT result_ids FROM search_log WHERE id = ?`, searchID).Scan(&resultIDs)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	position := slices.Index(strings.Split(resultIDs, ","), strconv.FormatInt(memeID, 10))
+	if position < 0 {
+		return ErrNotInSearch
+	}
+	if _, err := GetMemeByID(memeID); err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT OR IGNORE INTO search_clicks (search_id, meme_id, action, position) VALUES (?, ?, ?, ?)`,
+		searchID, memeID, action, position)
+	return err
+}
+
+// PruneSearchLog 刪除超過 days 天的搜尋紀錄與對應的點擊，回傳刪除的搜尋筆數
+func PruneSearchLog(days int) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	since := fmt.Sprintf("-%d days", days)
+	if _, err := tx.Exec(`DELETE FROM search_clicks WHERE search_id IN
+		(SELECT id FROM search_log WHERE created_at < datetime('now', ?))`, since); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM search_log WHERE created_at < datetime('now', ?)`, since)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// startSearchLogCleanup 啟動時與之後每天清理一次超過保留天數的搜尋紀錄，days 為 0 時永久保留
+func startSearchLogCleanup(days int) {
+	if days <= 0 {
+		return
+	}
+	go func() {
+		for ; ; time.Sleep(24 * time.Hour) {
+			if n, err := PruneSearchLog(days); err != nil {
+				log.Printf("[Analytics] 清理搜尋紀錄失敗: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 已刪除 %d 筆超過 %d 天的搜尋紀錄", n, days)
+			}
+		}
+	}()
+}
+
+// QueryStat 是單一查詢的統計
+type QueryStat struct {
+	Query          string  `json:"query"`
+	Searches       int     `json:"searches"`
+	Clients        int     `json:"clients"`
+	AvgResults     float64 `json:"avg_results"`
+	CTR            float64 `json:"ctr"` // 有結果的搜尋中，至少點擊或複製一筆的比例
+	LastSearchedAt string  `json:"last_searched_at"`
+}
+
+// SourceClicks 是各來源被點擊的次數，用來判斷該多爬哪個網站
+type SourceClicks struct {
+	Source string `json:"source"`
+	Clicks int    `json:"clicks"`
+	Copies int    `json:"copies"`
+}
+
+// SearchAnalytics 是 days 天內的搜尋報表
+type SearchAnalytics struct {
+	Days           int            `json:"days"`
+	Searches       int            `json:"searches"`
+	Clients        int            `json:"clients"`
+	ZeroResultRate float64        `json:"zero_result_rate"`
+	CTR            float64        `json:"ctr"`
+	AvgLatencyMs   float64        `json:"avg_latency_ms"`
+	TopQueries     []QueryStat    `json:"top_queries"`
+	ZeroResults    []QueryStat    `json:"zero_result_queries"`
+	ClicksBySource []SourceClicks `json:"clicks_by_source"`
+}
+
+// searchClickedSQL 判斷一次搜尋是否有任何點擊或複製
+const searchClickedSQL = `EXISTS (SELECT 1 FROM search_clicks WHERE search_clicks.search_id = search_log.id)`
+
+// sourceNameSQL 把來源網址歸類成網站名稱
+const sourceNameSQL = `CASE
+	WHEN memes.source_url LIKE '%gif-vif.com%' THEN 'gif'
+	WHEN memes.source_url LIKE '%ptt.cc%' THEN 'ptt'
+	WHEN memes.source_url LIKE '%threads.net%' THEN 'threads'
+	WHEN memes.source_url LIKE '%plurk.com%' THEN 'plurk'
+	ELSE 'other' END`
+
+// sourceName 是 sourceNameSQL 的 Go 版本，兩者的規則需一致
+func sourceName(sourceURL string) string {
+	for _, s := range []struct{ pattern, name string }{
+		{"gif-vif.com", "gif"}, {"ptt.cc", "ptt"}, {"threads.net", "threads"}, {"plurk.com", "plurk"},
+	} {
+		if strings.Contains(strings.ToLower(sourceURL), s.pattern) {
+			return s.name
+		}
+	}
+	return "other"
+}
+
+// SourceCount 是各來源目前的資料筆數
+type SourceCount struct {
+	Name  string
+	Count int
+}
+
+// CountMemesBySource 依來源統計看得到的資料筆數
+func CountMemesBySource() ([]SourceCount, error) {
+	rows, err := db.Query(`SELECT ` + sourceNameSQL + ` AS source, COUNT(*) FROM memes
+		WHERE ` + visibleSQL + ` GROUP BY source ORDER BY COUNT(*) DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := []SourceCount{}
+	for rows.Next() {
+		var s SourceCount
+		if err := rows.Scan(&s.Name, &s.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, s)
+	}
+	return counts, rows.Err()
+}
+
+func GetSearchAnalytics(days, limit int) (SearchAnalytics, error) {
+	since := fmt.Sprintf("-%d days", days)
+	a := SearchAnalytics{Days: days}
+
+	var zero, withResults, clicked int
+	var latency sql.NullFloat64
+	err := db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT client),
+		COALESCE(SUM(result_count = 0), 0), COALESCE(SUM(result_count > 0), 0),
+		COALESCE(SUM(result_count > 0 AND `+searchClickedSQL+`), 0), AVG(latency_ms)
+		FROM search_log WHERE created_at >= datetime('now', ?)`, since).
+		Scan(&a.Searches, &a.Clients, &zero, &withResults, &clicked, &latency)
+	if err != nil {
+		return a, err
+	}
+	a.ZeroResultRate = ratio(zero, a.Searches)
+	a.CTR = ratio(clicked, withResults)
+	a.AvgLatencyMs = latency.Float64
+
+	if a.TopQueries, err = queryStats(`result_count > 0`, `searches DESC`, since, limit); err != nil {
+		return a, err
+	}
+	if a.ZeroResults, err = queryStats(`result_count = 0`, `searches DESC`, since, limit); err != nil {
+		return a, err
+	}
+
+	rows, err := db.Query(`SELECT `+sourceNameSQL+` AS source,
+		SUM(search_clicks.action = 'click'), SUM(search_clicks.action = 'copy')
+		FROM search_clicks JOIN memes ON memes.id = search_clicks.meme_id
+		WHERE search_clicks.created_at >= datetime('now', ?)
+		GROUP BY source ORDER BY COUNT(*) DESC`, since)
+	if err != nil {
+		return a, err
+	}
+	defer rows.Close()
+	a.ClicksBySource = []SourceClicks{}
+	for rows.Next() {
+		var s SourceClicks
+		if err := rows.Scan(&s.Source, &s.Clicks, &s.Copies); err != nil {
+			return a, err
+		}
+		a.ClicksBySource = append(a.ClicksBySource, s)
+	}
+	return a, rows.Err()
+}
+
+// queryStats 依查詢字串分組統計，where 決定只看有結果或零結果的搜尋
+func queryStats(where, order, since string, limit int) ([]QueryStat, error) {
+	rows, err := db.Query(`SELECT query, COUNT(*) AS searches, COUNT(DISTINCT client), AVG(result_count),
+		SUM(result_count > 0), SUM(result_count > 0 AND `+searchClickedSQL+`), MAX(created_at)
+		FROM search_log WHERE `+where+` AND query != '' AND created_at >= datetime('now', ?)
+		GROUP BY query ORDER BY `+order+`, MAX(created_at) DESC LIMIT ?`, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []QueryStat{}
+	for rows.Next() {
+		var s QueryStat
+		var withResults, clicked int
+		if err := rows.Scan(&s.Query, &s.Searches, &s.Clients, &s.AvgResults, &withResults, &clicked, &s.LastSearchedAt); err != nil {
+			return nil, err
+		}
+		s.CTR = ratio(clicked, withResults)
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+// registerSearchClickRoutes 讓前端回報搜尋結果的點擊與複製
+func registerSearchClickRoutes(api *gin.RouterGroup) {
+	// POST /api/search/click，body 為 {"search_id": 1, "meme_id": 2, "action": "click"}。
+	// 舊版前端會帶 position，名次改由伺服器依搜尋結果決定，這個欄位會被忽略
+	api.POST("/search/click", func(c *gin.Context) {
+		var in struct {
+			SearchID int64  `json:"search_id" binding:"required"`
+			MemeID   int64  `json:"meme_id" binding:"required"`
+			Action   string `json:"action"`
+		}
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		if in.Action == "" {
+			in.Action = "click"
+		}
+		err := RecordSearchClick(in.SearchID, in.MemeID, in.Action)
+		if errors.Is(err, ErrInvalidSearchAction) || errors.Is(err, ErrNotInSearch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// registerAnalyticsRoutes 掛在管理員 API 底下
+func registerAnalyticsRoutes(admin *gin.RouterGroup) {
+	// GET /api/admin/analytics/search?days=7&limit=20
+	admin.GET("/analytics/search", func(c *gin.Context) {
+		days := queryInt(c, "days", 7, 365)
+		if days == 0 {
+			days = 7
+		}
+		a, err := GetSearchAnalytics(days, queryInt(c, "limit", 20, 100))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, a)
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestSearchAnalytics(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t,
+		ExportMeme{Title: "川普演講", URL: "川普又說了好笑的話", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.A.html"},
+		ExportMeme{Title: "川普跳舞", URL: "https://example.com/dance.gif", SourceURL: "https://www.gif-vif.com/gifs/dance"},
+	)
+
+	search := func(q string) int64 {
+		t.Helper()
+		w := doRequest(r, "GET", "/api/search?q="+url.QueryEscape(q))
+		id, _ := strconv.ParseInt(w.Header().Get("X-Search-ID"), 10, 64)
+		return id
+	}
+	click := func(body map[string]any) int {
+		b, _ := json.Marshal(body)
+		return doJSON(r, "POST", "/api/search/click", string(b)).Code
+	}
+
+	first := search("川普")
+	search(" 川普 ")
+	search("跳舞")
+	search("不存在的梗")
+	search("不存在的梗")
+	if search("") != 0 {
+		t.Errorf("空白查詢不應記錄")
+	}
+	if first == 0 {
+		t.Fatalf("搜尋應回傳 X-Search-ID")
+	}
+
+	if code := click(map[string]any{"search_id": first, "meme_id": 2}); code != http.StatusNoContent {
+		t.Fatalf("回報點擊預期 204，得到 %d", code)
+	}
+	click(map[string]any{"search_id": first, "meme_id": 2}) // 重複點擊只算一次
+	click(map[string]any{"search_id": first, "meme_id": 1, "action": "copy"})
+	if code := click(map[string]any{"search_id": first, "meme_id": 1, "action": "share"}); code != http.StatusBadRequest {
+		t.Errorf("不支援的 action 預期 400，得到 %d", code)
+	}
+	if code := click(map[string]any{"search_id": 999, "meme_id": 1}); code != http.StatusNotFound {
+		t.Errorf("不存在的搜尋預期 404，得到 %d", code)
+	}
+
+	if w := doRequest(r, "GET", "/api/admin/analytics/search"); w.Code != http.StatusUnauthorized {
+		t.Errorf("分析報表需要管理員權限，得到 %d", w.Code)
+	}
+	var a SearchAnalytics
+	json.Unmarshal(adminRequest(r, "GET", "/api/admin/analytics/search?days=7", nil).Body.Bytes(), &a)
+
+	if a.Searches != 5 || a.Clients != 1 {
+		t.Errorf("搜尋次數或人數不符: %+v", a)
+	}
+	if a.ZeroResultRate != 0.4 || a.CTR != 1.0/3 {
+		t.Errorf("零結果比例或點擊率不符: %v %v", a.ZeroResultRate, a.CTR)
+	}
+	if len(a.TopQueries) != 2 || a.TopQueries[0].Query != "川普" || a.TopQueries[0].Searches != 2 || a.TopQueries[0].CTR != 0.5 {
+		t.Errorf("熱門查詢不符 (空白與大小寫應合併): %+v", a.TopQueries)
+	}
+	if len(a.ZeroResults) != 1 || a.ZeroResults[0].Query != "不存在的梗" || a.ZeroResults[0].Searches != 2 {
+		t.Errorf("零結果查詢不符: %+v", a.ZeroResults)
+	}
+	if len(a.ClicksBySource) != 2 || a.ClicksBySource[0].Clicks+a.ClicksBySource[1].Clicks != 1 {
+		t.Errorf("各來源點擊不符: %+v", a.ClicksBySource)
+	}
+}
+
+func TestSearchClickValidation(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "川普演講", URL: "川普又說了好笑的話", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.A.html"},
+		ExportMeme{Title: "川普跳舞", URL: "https://example.com/dance.gif", SourceURL: "https://www.gif-vif.com/gifs/dance"},
+		ExportMeme{Title: "貓咪", URL: "https://example.com/cat.gif", SourceURL: "https://www.gif-vif.com/gifs/cat"},
+	)
+	w := doRequest(r, "GET", "/api/search?q="+url.QueryEscape("川普"))
+	searchID, _ := strconv.ParseInt(w.Header().Get("X-Search-ID"), 10, 64)
+	var results []Meme
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil || len(results) != 2 {
+		t.Fatalf("預期 2 筆結果: %s (%v)", w.Body.String(), err)
+	}
+
+	click := func(memeID int64) int {
+		return doJSON(r, "POST", "/api/search/click", fmt.Sprintf(`{"search_id": %d, "meme_id": %d, "position": 99}`, searchID, memeID)).Code
+	}
+	// 不在這次搜尋結果裡的資料不能灌點擊
+	if code := click(3); code != http.StatusBadRequest {
+		t.Errorf("不在結果中的資料預期 400，得到 %d", code)
+	}
+	if code := click(results[1].ID); code != http.StatusNoContent {
+		t.Fatalf("回報點擊預期 204，得到 %d", code)
+	}
+	// 名次以搜尋當時的順序為準，不採用前端回報的值
+	var position int
+	db.QueryRow(`SELECT position FROM search_clicks WHERE search_id = ?`, searchID).Scan(&position)
+	if position != 1 {
+		t.Errorf("名次應為 1，得到 %d", position)
+	}
+	// 搜尋之後被刪除的資料不再接受
+	SetMemeDeleted(results[0].ID, true, "test")
+	if code := click(results[0].ID); code != http.StatusNotFound {
+		t.Errorf("已刪除的資料預期 404，得到 %d", code)
+	}
+}
+
+func TestPruneSearchLog(t *testing.T) {
+	setupTestServer(t, ExportMeme{Title: "川普跳舞", URL: "https://example.com/dance.gif", SourceURL: "https://www.gif-vif.com/gifs/dance"})
+
+	old, _ := LogSearch(SearchLogEntry{Query: "舊的", ResultCount: 1, ResultIDs: []int64{1}})
+	recent, _ := LogSearch(SearchLogEntry{Query: "新的", ResultCount: 1, ResultIDs: []int64{1}})
+	RecordSearchClick(old, 1, "click")
+	RecordSearchClick(recent, 1, "click")
+	db.Exec(`UPDATE search_log SET created_at = datetime('now', '-100 days') WHERE id = ?`, old)
+
+	if n, err := PruneSearchLog(90); err != nil || n != 1 {
+		t.Fatalf("預期刪除 1 筆，得到 %d (%v)", n, err)
+	}
+	var logs, clicks int
+	db.QueryRow(`SELECT COUNT(*) FROM search_log`).Scan(&logs)
+	db.QueryRow(`SELECT COUNT(*) FROM search_clicks`).Scan(&clicks)
+	if logs != 1 || clicks != 1 {
+		t.Errorf("應只留下最近的搜尋與點擊，得到 %d / %d", logs, clicks)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [API Key 驗證與限流]
+// =========================================================
+
+// ErrInvalidAPIKey 金鑰不存在或已被撤銷
+var ErrInvalidAPIKey = errors.New("API key 無效或已撤銷")
+
+// APIKey 只保存金鑰的 SHA-256，原始金鑰僅在核發時顯示一次
+type APIKey struct {
+	ID         int64   `json:"id"`
+	Name       string  `json:"name"`
+	Prefix     string  `json:"prefix"`
+	RatePerMin int     `json:"rate_per_min"`
+	CreatedAt  string  `json:"created_at"`
+	RevokedAt  *string `json:"revoked_at"`
+}
+
+const apiKeyPrefix = "mk_"
+
+// hashToken 是 API key 與 session token 共用的雜湊，資料庫不保存原始值
+func hashToken(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIKey 產生一組新金鑰，ratePerMin 為 0 時使用伺服器預設值
+func IssueAPIKey(name string, ratePerMin int) (string, APIKey, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", APIKey{}, err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(buf)
+	prefix := key[:len(apiKeyPrefix)+8]
+
+	res, err := db.Exec(`INSERT INTO api_keys (name, key_hash, prefix, rate_per_min) VALUES (?, ?, ?, ?)`,
+		name, hashToken(key), prefix, ratePerMin)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	id, _ := res.LastInsertId()
+	k, err := getAPIKey(`id = ?`, id)
+	return key, k, err
+}
+
+func getAPIKey(where string, arg any) (APIKey, error) {
+	var k APIKey
+	err := db.QueryRow(`SELECT id, name, prefix, rate_per_min, created_at, revoked_at FROM api_keys WHERE `+where, arg).
+		Scan(&k.ID, &k.Name, &k.Prefix, &k.RatePerMin, &k.CreatedAt, &k.RevokedAt)
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+// LookupAPIKey 驗證請求帶來的原始金鑰
+func LookupAPIKey(key string) (APIKey, error) {
+	k, err := getAPIKey(`key_hash = ?`, hashToken(key))
+	if errors.Is(err, ErrNotFound) || (err == nil && k.RevokedAt != nil) {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+	return k, err
+}
+
+// RevokeAPIKey 以 id 或 prefix 撤銷金鑰
+func RevokeAPIKey(idOrPrefix string) error {
+	where, arg := `prefix = ?`, any(idOrPrefix)
+	if id, err := strconv.ParseInt(idOrPrefix, 10, 64); err == nil {
+		where, arg = `id = ?`, id
+	}
+	res, err := db.Exec(`UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE revoked_at IS NULL AND `+where, arg)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func ListAPIKeys() ([]APIKey, error) {
+	rows, err := db.Query(`SELECT id, name, prefix, rate_per_min, created_at, revoked_at FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.Prefix, &k.RatePerMin, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// ---------------------------------------------------------
+// Middleware
+// ---------------------------------------------------------
+
+// RateLimitConfig 由環境變數設定，未設定時使用預設值
+type RateLimitConfig struct {
+	RequireKey   bool // REQUIRE_API_KEY=1 時沒有金鑰的請求一律拒絕
+	IPPerMinute  int  // RATE_LIMIT_IP_PER_MIN：匿名請求每個 IP 每分鐘上限
+	IPBurst      int  // RATE_LIMIT_IP_BURST
+	KeyPerMinute int  // RATE_LIMIT_KEY_PER_MIN：金鑰未自訂上限時的預設值
+}
+
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return n
+	}
+	return def
+}
+
+func rateLimitConfigFromEnv() RateLimitConfig {
+	return RateLimitConfig{
+		RequireKey:   os.Getenv("REQUIRE_API_KEY") == "1",
+		IPPerMinute:  envInt("RATE_LIMIT_IP_PER_MIN", 60),
+		IPBurst:      envInt("RATE_LIMIT_IP_BURST", 20),
+		KeyPerMinute: envInt("RATE_LIMIT_KEY_PER_MIN", 600),
+	}
+}
+
+// requestAPIKey 只從 X-API-Key 標頭取得金鑰，不接受網址參數 (會留在存取紀錄與 Referer 裡)
+func requestAPIKey(c *gin.Context) string {
+	return c.GetHeader("X-API-Key")
+}
+
+func tooManyRequests(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	abortWithError(c, http.StatusTooManyRequests, codeRateLimited, "請求過於頻繁，請稍後再試")
+}
+
+// apiKeyAuth 驗證 API key 並套用限流：有金鑰時以金鑰計算，匿名請求以 IP 計算
+func apiKeyAuth(cfg RateLimitConfig, limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := requestAPIKey(c)
+		if key == "" {
+			if cfg.RequireKey {
+				abortWithError(c, http.StatusUnauthorized, codeUnauthorized, "缺少 API key")
+				return
+			}
+			if ok, wait := limiter.Allow("ip:"+c.ClientIP(), cfg.IPPerMinute, cfg.IPBurst); !ok {
+				tooManyRequests(c, wait)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		k, err := LookupAPIKey(key)
+		if errors.Is(err, ErrInvalidAPIKey) {
+			abortWithError(c, http.StatusUnauthorized, codeUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			abortWithError(c, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+
+		rate := k.RatePerMin
+		if rate == 0 {
+			rate = cfg.KeyPerMinute
+		}
+		if ok, wait := limiter.Allow("key:"+strconv.FormatInt(k.ID, 10), rate, 0); !ok {
+			tooManyRequests(c, wait)
+			return
+		}
+		c.Set("api_key_id", k.ID)
+		c.Next()
+	}
+}
+
+// ipRateLimit 只依 IP 限流 (伺服器端渲染的頁面用)，與匿名 API 請求共用同一個額度
+func ipRateLimit(cfg RateLimitConfig, limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, wait := limiter.Allow("ip:"+c.ClientIP(), cfg.IPPerMinute, cfg.IPBurst); !ok {
+			tooManyRequests(c, wait)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ---------------------------------------------------------
+// CLI：go run ... apikey issue|revoke|list
+// ---------------------------------------------------------
+
+func runAPIKeyCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: apikey issue <名稱> [-rate 每分鐘上限] | apikey revoke <id 或 prefix> | apikey list")
+	}
+
+	switch args[0] {
+	case "issue":
+		fs := flag.NewFlagSet("apikey issue", flag.ContinueOnError)
+		rate := fs.Int("rate", 0, "每分鐘請求上限 (0 = 使用伺服器預設值)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		name := strings.Join(fs.Args(), " ")
+		if name == "" {
+			return fmt.Errorf("請提供金鑰名稱，例如: apikey issue slack-bot")
+		}
+		key, k, err := IssueAPIKey(name, *rate)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已核發 API key #%d (%s)\n", k.ID, k.Name)
+		fmt.Printf("金鑰只會顯示這一次，請妥善保存：\n%s\n", key)
+	case "revoke":
+		if len(args) < 2 {
+			return fmt.Errorf("請提供要撤銷的 id 或 prefix")
+		}
+		if err := RevokeAPIKey(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("已撤銷 API key %s\n", args[1])
+	case "list":
+		keys, err := ListAPIKeys()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\t名稱\tPrefix\t每分鐘上限\t建立時間\t狀態")
+		for _, k := range keys {
+			status := "有效"
+			if k.RevokedAt != nil {
+				status = "已撤銷 " + *k.RevokedAt
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.RatePerMin, k.CreatedAt, status)
+		}
+		w.Flush()
+	default:
+		return fmt.Errorf("未知的指令: %s", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+
+	// 每分鐘 60 次、容量 2：連續兩次成功，第三次需等 1 秒
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("ip:1.2.3.4", 60, 2); !ok {
+			t.Fatalf("第 %d 次不應被限流", i+1)
+		}
+	}
+	ok, wait := l.Allow("ip:1.2.3.4", 60, 2)
+	if ok || wait != time.Second {
+		t.Fatalf("預期被限流並等待 1 秒，得到 ok=%v wait=%v", ok, wait)
+	}
+	// 其他 IP 不受影響
+	if ok, _ := l.Allow("ip:5.6.7.8", 60, 2); !ok {
+		t.Error("不同 IP 應有各自的桶子")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("ip:1.2.3.4", 60, 2); !ok {
+		t.Error("等待 1 秒後應補充 1 個 token")
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_PER_MIN", "60")
+	t.Setenv("RATE_LIMIT_IP_BURST", "1")
+	r := setupTestServer(t)
+
+	key, k, err := IssueAPIKey("chatbot", 2)
+	if err != nil {
+		t.Fatalf("核發金鑰失敗: %v", err)
+	}
+
+	withKey := func(apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/search?q=", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := withKey("mk_wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("錯誤金鑰預期 401，得到 %d", w.Code)
+	}
+
+	// 金鑰上限每分鐘 2 次 (容量同為 2)
+	for i := 0; i < 2; i++ {
+		if w := withKey(key); w.Code != http.StatusOK {
+			t.Fatalf("第 %d 次預期 200，得到 %d", i+1, w.Code)
+		}
+	}
+	w := withKey(key)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("預期 429 與 Retry-After: 30，得到 %d / %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// 匿名請求依 IP 限流 (容量 1)
+	if w := doRequest(r, "GET", "/api/random"); w.Code == http.StatusTooManyRequests {
+		t.Error("第一次匿名請求不應被限流")
+	}
+	if w := doRequest(r, "GET", "/api/random"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("第二次匿名請求預期 429，得到 %d", w.Code)
+	}
+
+	if err := RevokeAPIKey(k.Prefix); err != nil {
+		t.Fatalf("撤銷失敗: %v", err)
+	}
+	if w := withKey(key); w.Code != http.StatusUnauthorized {
+		t.Errorf("撤銷後預期 401，得到 %d", w.Code)
+	}
+}
+
+func TestPageRateLimit(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_PER_MIN", "60")
+	t.Setenv("RATE_LIMIT_IP_BURST", "2")
+	r := setupTestServer(t, ExportMeme{Title: "好笑", URL: "好笑的文字"})
+
+	for i := 0; i < 2; i++ {
+		if w := doRequest(r, "GET", "/search?q="); w.Code != http.StatusOK {
+			t.Fatalf("第 %d 次預期 200，得到 %d", i+1, w.Code)
+		}
+	}
+	w := doRequest(r, "GET", "/search?q=")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("第三次搜尋頁預期 429 與 Retry-After，得到 %d / %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	// 同一個 IP 的隨機頁與永久連結頁共用額度
+	for _, path := range []string{"/random", "/m/1"} {
+		if w := doRequest(r, "GET", path); w.Code != http.StatusTooManyRequests {
+			t.Errorf("%s 預期 429，得到 %d", path, w.Code)
+		}
+	}
+}
+
+func TestAPIKeyHeaderOnly(t *testing.T) {
+	t.Setenv("REQUIRE_API_KEY", "1")
+	r := setupTestServer(t)
+
+	key, _, err := IssueAPIKey("chatbot", 60)
+	if err != nil {
+		t.Fatalf("核發金鑰失敗: %v", err)
+	}
+	if w := doRequest(r, "GET", "/api/search?q=&api_key="+key); w.Code != http.StatusUnauthorized {
+		t.Errorf("網址參數的金鑰不應被接受，預期 401，得到 %d", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/search?q=", nil)
+	req.Header.Set("X-API-Key", key)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("標頭的金鑰預期 200，得到 %d", w.Code)
+	}
+}
+
+func TestRateLimiterStopCleanup(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter()
+	l.now = func() time.Time { return now }
+	l.Allow("ip:1.2.3.4", 60, 1)
+	now = now.Add(time.Minute)
+
+	stop := l.StartCleanup(time.Millisecond)
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.mu.Lock()
+		n := len(l.buckets)
+		l.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("閒置的桶子應被清理")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stop()
+	stop() // 重複呼叫不應 panic
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [版本化 API：/api/v1]
+// =========================================================
+// 舊的 /api/search、/api/random 等路由維持原樣給首頁使用；
+// 對外整合請使用 /api/v1，所有回應都有固定的外層格式，規格見 openapi.json
+
+const apiV1Prefix = "/api/v1/"
+const v1DefaultLimit = 20
+const v1MaxLimit = 50
+const v1MaxOffset = 1000
+
+// v1Modes 是 mode 參數接受的值
+var v1Modes = []string{"all", "image", "text"}
+
+// APIError 是 v1 錯誤回應的內容，Code 是給程式判斷用的固定字串
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"` // 出錯的參數名稱
+}
+
+// v1 錯誤代碼
+const (
+	codeInvalidParameter = "invalid_parameter"
+	codeNotFound         = "not_found"
+	codeUnauthorized     = "unauthorized"
+	codeRateLimited      = "rate_limited"
+	codeInternal         = "internal_error"
+)
+
+// respondV1 回傳成功的結果：{"data": ..., "meta": {...}}
+func respondV1(c *gin.Context, data any, meta gin.H) {
+	body := gin.H{"data": data}
+	if meta != nil {
+		body["meta"] = meta
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// respondV1Error 回傳錯誤：{"error": {"code": ..., "message": ..., "param": ...}}
+func respondV1Error(c *gin.Context, status int, e APIError) {
+	c.AbortWithStatusJSON(status, gin.H{"error": e})
+}
+
+// respondV1StoreError 依資料層錯誤決定狀態碼
+func respondV1StoreError(c *gin.Context, err error) {
+	if errors.Is(err, ErrNotFound) {
+		respondV1Error(c, http.StatusNotFound, APIError{Code: codeNotFound, Message: err.Error()})
+		return
+	}
+	respondV1Error(c, http.StatusInternalServerError, APIError{Code: codeInternal, Message: err.Error()})
+}
+
+// abortWithError 讓共用的中介層 (API key、限流) 在 v1 底下也回傳 v1 的錯誤格式
+func abortWithError(c *gin.Context, status int, code, message string) {
+	if strings.HasPrefix(c.Request.URL.Path, apiV1Prefix) {
+		respondV1Error(c, status, APIError{Code: code, Message: message})
+		return
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// invalidParam 回應 400 並指出是哪個參數
+func invalidParam(c *gin.Context, param, message string) {
+	respondV1Error(c, http.StatusBadRequest, APIError{Code: codeInvalidParameter, Message: message, Param: param})
+}
+
+// v1Mode 讀取並驗證 mode (預設 all)
+func v1Mode(c *gin.Context) (string, bool) {
+	mode := c.DefaultQuery("mode", "all")
+	for _, m := range v1Modes {
+		if mode == m {
+			return mode, true
+		}
+	}
+	invalidParam(c, "mode", "mode 只接受 "+strings.Join(v1Modes, "、"))
+	return "", false
+}
+
+// v1Int 讀取整數參數，超出 [lo, hi] 時回應 400 (和 queryInt 不同，不會默默改成預設值)
+func v1Int(c *gin.Context, key string, def, lo, hi int) (int, bool) {
+	s, ok := c.GetQuery(key)
+	if !ok {
+		return def, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		invalidParam(c, key, key+" 需為 "+strconv.Itoa(lo)+" 到 "+strconv.Itoa(hi)+" 的整數")
+		return 0, false
+	}
+	return n, true
+}
+
+// v1NoRoute 讓 /api/v1 底下不存在的路徑也回傳 v1 格式的 404
+func v1NoRoute(c *gin.Context) {
+	if strings.HasPrefix(c.Request.URL.Path, apiV1Prefix) {
+		respondV1Error(c, http.StatusNotFound, APIError{Code: codeNotFound, Message: "沒有這個 API"})
+		return
+	}
+	c.String(http.StatusNotFound, "404 page not found")
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+// registerV1Routes 掛在 /api 群組底下，沿用 API key 驗證與限流
+func registerV1Routes(api *gin.RouterGroup, syn *synonymIndex) {
+	v1 := api.Group("/v1")
+
+	// 規格本身不套外層格式，方便直接餵給產生 client 的工具
+	v1.GET("/openapi.json", func(c *gin.Context) {
+		spec, err := fs.ReadFile(assetFS(), "openapi.json")
+		if err != nil {
+			respondV1StoreError(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+
+	// GET /api/v1/search?q=&mode=&sort=&limit=&offset=
+	v1.GET("/search", func(c *gin.Context) {
+		mode, ok := v1Mode(c)
+		if !ok {
+			return
+		}
+		limit, ok := v1Int(c, "limit", v1DefaultLimit, 1, v1MaxLimit)
+		if !ok {
+			return
+		}
+		offset, ok := v1Int(c, "offset", 0, 0, v1MaxOffset)
+		if !ok {
+			return
+		}
+		q, sort := c.Query("q"), c.Query("sort")
+		res, err := runSearch(syn, searchRequest{
+			Query: q, Mode: mode, Sort: sort, Limit: limit, Offset: offset, Client: anonymousClient(c),
+		})
+		if errors.Is(err, ErrInvalidSort) {
+			invalidParam(c, "sort", err.Error())
+			return
+		}
+		if isSearchInputError(err) {
+			invalidParam(c, "q", err.Error())
+			return
+		}
+		if err != nil {
+			respondV1StoreError(c, err)
+			return
+		}
+
+		meta := gin.H{
+			"query": q, "mode": mode, "sort": sort, "limit": limit, "offset": offset,
+			"count": len(res.Memes), "expansions": nonNil(res.Expansions),
+		}
+		// 這一頁是滿的才提供下一頁的位置 (下一頁可能是空的)
+		if len(res.Memes) == limit && offset+limit <= v1MaxOffset {
+			meta["next_offset"] = offset + limit
+		}
+		if res.SearchID != 0 {
+			meta["search_id"] = res.SearchID
+		}
+		respondV1(c, nonNil(res.Memes), meta)
+	})
+
+	// GET /api/v1/random?mode=&count=&seed=，一律回傳陣列
+	v1.GET("/random", func(c *gin.Context) {
+		mode, ok := v1Mode(c)
+		if !ok {
+			return
+		}
+		count, ok := v1Int(c, "count", 1, 1, maxRandomCount)
+		if !ok {
+			return
+		}
+		opts := RandomOptions{Mode: mode, Count: count}
+		if seed := c.Query("seed"); seed != "" {
+			opts.Rand = seededRand(seed + "|" + mode)
+		}
+		memes, err := GetRandomMemes(opts)
+		if err != nil {
+			respondV1StoreError(c, err)
+			return
+		}
+		if len(memes) == 0 {
+			respondV1StoreError(c, ErrNotFound)
+			return
+		}
+		for _, m := range memes {
+			recordView(m.ID)
+		}
+		respondV1(c, memes, gin.H{"mode": mode, "count": len(memes)})
+	})
+
+	// GET /api/v1/memes/:id
+	v1.GET("/memes/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			invalidParam(c, "id", "id 需為正整數")
+			return
+		}
+		meme, err := GetMemeByID(id)
+		if err != nil {
+			respondV1StoreError(c, err)
+			return
+		}
+		recordView(meme.ID)
+		respondV1(c, meme, nil)
+	})
+
+	// GET /api/v1/daily?date=&mode=
+	v1.GET("/daily", func(c *gin.Context) {
+		mode, ok := v1Mode(c)
+		if !ok {
+			return
+		}
+		date := time.Now()
+		if s := c.Query("date"); s != "" {
+			var err error
+			if date, err = time.ParseInLocation(dateLayout, s, time.Local); err != nil {
+				invalidParam(c, "date", "date 格式需為 YYYY-MM-DD")
+				return
+			}
+		}
+		d, err := GetDailyMeme(date, mode)
+		if errors.Is(err, ErrFutureDate) || errors.Is(err, ErrDateTooEarly) {
+			invalidParam(c, "date", err.Error())
+			return
+		}
+		if err != nil {
+			respondV1StoreError(c, err)
+			return
+		}
+		respondV1(c, d, nil)
+	})
+}
+
+// nonNil 讓空結果輸出成 [] 而不是 null
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
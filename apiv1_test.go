@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadOpenAPI 讀取內嵌的規格
+func loadOpenAPI(t *testing.T) map[string]any {
+	t.Helper()
+	data, err := embeddedAssets.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatalf("讀取 openapi.json 失敗: %v", err)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("openapi.json 不是合法的 JSON: %v", err)
+	}
+	return spec
+}
+
+// resolveRef 展開 {"$ref": "#/components/..."}，其他值原樣回傳
+func resolveRef(spec map[string]any, node map[string]any) map[string]any {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var cur any = spec
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			cur = cur.(map[string]any)[part]
+		}
+		node = cur.(map[string]any)
+	}
+}
+
+// validateSchema 檢查 value 是否符合 schema (只支援規格中用到的部分：type / required / properties / items / enum)。
+// 物件多出規格沒有的欄位也視為錯誤，避免程式改了規格卻沒跟著改
+func validateSchema(spec, schema map[string]any, value any, path string) []string {
+	schema = resolveRef(spec, schema)
+	var errs []string
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: %v 不在 enum %v 之內", path, value, enum))
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: 應為物件，得到 %T", path, value))
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for _, key := range schema["required"].([]any) {
+			if _, ok := obj[key.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: 缺少必要欄位 %s", path, key))
+			}
+		}
+		for key, v := range obj {
+			sub, ok := props[key].(map[string]any)
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s: 規格沒有欄位 %s", path, key))
+				continue
+			}
+			errs = append(errs, validateSchema(spec, sub, v, path+"."+key)...)
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: 應為陣列，得到 %T", path, value))
+		}
+		for i, v := range arr {
+			errs = append(errs, validateSchema(spec, schema["items"].(map[string]any), v, path+"["+strconv.Itoa(i)+"]")...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			errs = append(errs, fmt.Sprintf("%s: 應為字串，得到 %T", path, value))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			errs = append(errs, fmt.Sprintf("%s: 應為整數，得到 %v", path, value))
+		}
+	}
+	return errs
+}
+
+// specOperation 找出路徑與方法對應的規格 (路徑為 /api/v1 之後的部分，參數寫成 {id})
+func specOperation(spec map[string]any, path, method string) (map[string]any, bool) {
+	item, ok := spec["paths"].(map[string]any)[path].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	op, ok := item[strings.ToLower(method)].(map[string]any)
+	return op, ok
+}
+
+var ginParamPattern = regexp.MustCompile(`:(\w+)`)
+
+func TestV1RoutesMatchOpenAPI(t *testing.T) {
+	spec := loadOpenAPI(t)
+	r := setupTestServer(t)
+
+	// 程式裡的每一條 /api/v1 路由都要寫進規格
+	registered := map[string]bool{}
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, apiV1Prefix) {
+			continue
+		}
+		path := ginParamPattern.ReplaceAllString(strings.TrimPrefix(route.Path, "/api/v1"), "{$1}")
+		registered[route.Method+" "+path] = true
+		if _, ok := specOperation(spec, path, route.Method); !ok {
+			t.Errorf("規格缺少 %s %s", route.Method, path)
+		}
+	}
+
+	// 規格裡的每一條路徑都要真的存在
+	var documented []string
+	for path, item := range spec["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(documented)
+	for _, op := range documented {
+		if !registered[op] {
+			t.Errorf("規格中的 %s 沒有對應的路由", op)
+		}
+	}
+}
+
+func TestV1ResponsesMatchOpenAPI(t *testing.T) {
+	t.Setenv("RATE_LIMIT_IP_BURST", "10000")
+	spec := loadOpenAPI(t)
+	r := setupTestServer(t,
+		ExportMeme{Title: "貓咪跳舞", URL: "https://example.com/cat.gif", Tags: "GIF", SourceURL: "https://www.gif-vif.com/gifs/cat", Description: "一隻貓在跳舞"},
+		ExportMeme{Title: "複製文", URL: "今天也要跳舞", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/a.html", Author: "someone"},
+	)
+	future := time.Now().AddDate(0, 0, 2).Format(dateLayout)
+
+	cases := []struct {
+		path   string // 規格中的路徑
+		url    string
+		status int
+		param  string // 400 時預期的 param
+	}{
+		{"/search", "/api/v1/search?q=" + url.QueryEscape("跳舞"), http.StatusOK, ""},
+		{"/search", "/api/v1/search?q=" + url.QueryEscape("不存在的東西"), http.StatusOK, ""},
+		{"/search", "/api/v1/search?limit=1", http.StatusOK, ""},
+		{"/search", "/api/v1/search?mode=gif", http.StatusBadRequest, "mode"},
+		{"/search", "/api/v1/search?limit=0", http.StatusBadRequest, "limit"},
+		{"/search", "/api/v1/search?sort=old", http.StatusBadRequest, "sort"},
+		{"/search", "/api/v1/search?q=" + url.QueryEscape("after:昨天"), http.StatusBadRequest, "q"},
+		{"/random", "/api/v1/random", http.StatusOK, ""},
+		{"/random", "/api/v1/random?count=2&seed=abc", http.StatusOK, ""},
+		{"/random", "/api/v1/random?count=99", http.StatusBadRequest, "count"},
+		{"/random", "/api/v1/random?mode=video", http.StatusBadRequest, "mode"},
+		{"/memes/{id}", "/api/v1/memes/1", http.StatusOK, ""},
+		{"/memes/{id}", "/api/v1/memes/999", http.StatusNotFound, ""},
+		{"/memes/{id}", "/api/v1/memes/abc", http.StatusBadRequest, "id"},
+		{"/daily", "/api/v1/daily?mode=text", http.StatusOK, ""},
+		{"/daily", "/api/v1/daily?date=" + future, http.StatusBadRequest, "date"},
+		{"/daily", "/api/v1/daily?date=2024/01/01", http.StatusBadRequest, "date"},
+		{"/daily", "/api/v1/daily?mode=", http.StatusBadRequest, "mode"},
+	}
+	for _, tc := range cases {
+		w := doRequest(r, "GET", tc.url)
+		if w.Code != tc.status {
+			t.Errorf("%s 預期 %d，得到 %d: %s", tc.url, tc.status, w.Code, w.Body.String())
+			continue
+		}
+		checkAgainstSpec(t, spec, tc.path, tc.url, w.Code, w.Body.Bytes())
+
+		if tc.status == http.StatusBadRequest {
+			var body struct{ Error APIError }
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if body.Error.Code != codeInvalidParameter || body.Error.Param != tc.param {
+				t.Errorf("%s 應指出參數 %s: %+v", tc.url, tc.param, body.Error)
+			}
+		}
+	}
+
+	// 找不到資料時是 404，不是 200 加上錯誤訊息
+	w := doRequest(r, "GET", "/api/v1/random?mode=image&seed=x&count=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("有 GIF 時應抽得到: %d", w.Code)
+	}
+	SetMemeDeleted(1, true, "test")
+	w = doRequest(r, "GET", "/api/v1/random?mode=image")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("沒有資料時應回傳 404，得到 %d", w.Code)
+	}
+	checkAgainstSpec(t, spec, "/random", "/api/v1/random?mode=image", w.Code, w.Body.Bytes())
+
+	// 不存在的 v1 路徑也是同樣的錯誤格式
+	w = doRequest(r, "GET", "/api/v1/nope")
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"code":"not_found"`) {
+		t.Errorf("不存在的 v1 路徑應回傳 v1 格式的 404: %d %s", w.Code, w.Body.String())
+	}
+
+	// 規格本身可以下載
+	w = doRequest(r, "GET", "/api/v1/openapi.json")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"openapi"`) {
+		t.Errorf("應能下載 openapi.json: %d", w.Code)
+	}
+}
+
+func TestV1MiddlewareErrorsUseEnvelope(t *testing.T) {
+	t.Setenv("REQUIRE_API_KEY", "1")
+	spec := loadOpenAPI(t)
+	r := setupTestServer(t)
+
+	w := doRequest(r, "GET", "/api/v1/search")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("缺少金鑰應回傳 401，得到 %d", w.Code)
+	}
+	checkAgainstSpec(t, spec, "/search", "/api/v1/search", w.Code, w.Body.Bytes())
+
+	// 舊的路由維持原本的格式
+	w = doRequest(r, "GET", "/api/search")
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), `"error":"缺少 API key"`) {
+		t.Errorf("舊路由的錯誤格式不應改變: %s", w.Body.String())
+	}
+}
+
+// checkAgainstSpec 確認狀態碼有寫在規格裡，且回應內容符合對應的 schema
+func checkAgainstSpec(t *testing.T, spec map[string]any, path, url string, status int, body []byte) {
+	t.Helper()
+	op, ok := specOperation(spec, path, "GET")
+	if !ok {
+		t.Fatalf("規格缺少 GET %s", path)
+	}
+	resp, ok := op["responses"].(map[string]any)[strconv.Itoa(status)].(map[string]any)
+	if !ok {
+		t.Errorf("%s: 規格沒有列出狀態碼 %d", url, status)
+		return
+	}
+	resp = resolveRef(spec, resp)
+	schema, _ := resp["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	if schema == nil {
+		return
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Errorf("%s: 回應不是 JSON: %v", url, err)
+		return
+	}
+	for _, e := range validateSchema(spec, schema, value, "$") {
+		t.Errorf("%s (%d): %s", url, status, e)
+	}
+}
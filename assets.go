@@ -0,0 +1,49 @@
+package main
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// =========================================================
+// [內嵌的模板與資源檔]
+// =========================================================
+
+// embeddedAssets 把模板與內建資料編譯進執行檔，部署時只需要一個檔案，
+// 也不再依賴執行時的目前目錄
+//
+//go:embed index.html meme.html admin.html search.html meme_card.html synonyms.txt openapi.json
+var embeddedAssets embed.FS
+
+// templateFiles 是 setupRouter 載入的模板
+var templateFiles = []string{"index.html", "meme.html", "admin.html", "search.html", "meme_card.html"}
+
+// assetFS 回傳目前使用的資源檔：開發模式讀取 AssetDir 底下的原始檔，否則使用內嵌的版本
+func assetFS() fs.FS {
+	if AppConfig.Dev {
+		return os.DirFS(AppConfig.AssetDir)
+	}
+	return embeddedAssets
+}
+
+// loadTemplates 設定 r 的模板。開發模式每次請求都重新從磁碟讀取，修改 HTML 後不必重新編譯
+func loadTemplates(r *gin.Engine) {
+	if !AppConfig.Dev {
+		r.SetHTMLTemplate(template.Must(template.ParseFS(embeddedAssets, templateFiles...)))
+		return
+	}
+
+	files := make([]string, len(templateFiles))
+	for i, name := range templateFiles {
+		files[i] = filepath.Join(AppConfig.AssetDir, name)
+	}
+	log.Printf("[Dev] 開發模式：模板從 %s 即時載入", AppConfig.AssetDir)
+	r.HTMLRender = render.HTMLDebug{Files: files}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withConfig 在測試期間套用 cfg，結束後還原所有路徑
+func withConfig(t *testing.T, cfg Config) {
+	t.Helper()
+	saved, dbFile, exportFile, mediaDir, cacheDir := AppConfig, DBFile, ExportFile, MediaDir, CacheDir
+	t.Cleanup(func() {
+		AppConfig, DBFile, ExportFile, MediaDir, CacheDir = saved, dbFile, exportFile, mediaDir, cacheDir
+	})
+	if err := cfg.Apply(); err != nil {
+		t.Fatalf("套用設定失敗: %v", err)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("DATA_DIR", "")
+	cfg, args, err := ParseConfig([]string{"--data-dir", "data", "mirror", "10"}, io.Discard)
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if !filepath.IsAbs(cfg.DataDir) || filepath.Base(cfg.DataDir) != "data" {
+		t.Errorf("資料目錄應轉成絕對路徑: %s", cfg.DataDir)
+	}
+	if len(args) != 2 || args[0] != "mirror" || args[1] != "10" {
+		t.Errorf("子指令參數應原樣保留: %v", args)
+	}
+
+	// 沒有 --data-dir 時讀取環境變數
+	dir := t.TempDir()
+	t.Setenv("DATA_DIR", dir)
+	cfg, _, err = ParseConfig(nil, io.Discard)
+	if err != nil || cfg.DataDir != dir || cfg.Dev {
+		t.Fatalf("應使用 DATA_DIR: %+v (%v)", cfg, err)
+	}
+
+	if _, _, err := ParseConfig([]string{"--unknown"}, io.Discard); err == nil {
+		t.Errorf("未知參數應回傳錯誤")
+	}
+
+	withConfig(t, cfg)
+	if DBFile != filepath.Join(dir, "memes.db") || MediaDir != filepath.Join(dir, "media") {
+		t.Errorf("路徑應指到資料目錄: %s %s", DBFile, MediaDir)
+	}
+}
+
+func TestEmbeddedTemplatesIgnoreWorkingDirectory(t *testing.T) {
+	// 從別的目錄啟動也要能渲染頁面 (過去 LoadHTMLFiles 會 panic)
+	t.Chdir(t.TempDir())
+	withConfig(t, Config{DataDir: t.TempDir()})
+	r := setupTestServer(t, ExportMeme{Title: "內嵌模板", URL: "https://example.com/a.gif", SourceURL: "https://www.gif-vif.com/gifs/a"})
+
+	for _, path := range []string{"/", "/search?q=" + "%E5%85%A7%E5%B5%8C", "/m/1"} {
+		w := doRequest(r, "GET", path)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s 應回傳 200，得到 %d", path, w.Code)
+		}
+	}
+
+	// 內建的同義詞也從內嵌檔案匯入
+	if n, err := SeedSynonyms(assetFS(), SynonymSeedFile); err != nil || n == 0 {
+		t.Errorf("應從內嵌檔案匯入同義詞，得到 %d (%v)", n, err)
+	}
+}
+
+func TestDevModeReloadsTemplates(t *testing.T) {
+	// 複製一份模板到暫存目錄，修改後不需重新啟動就能看到
+	assets := t.TempDir()
+	for _, name := range templateFiles {
+		data, err := embeddedAssets.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(assets, name), data, 0644)
+	}
+	withConfig(t, Config{DataDir: t.TempDir(), Dev: true, AssetDir: assets})
+	r := setupTestServer(t)
+
+	if w := doRequest(r, "GET", "/search"); !strings.Contains(w.Body.String(), "Meme Search Engine") {
+		t.Fatalf("開發模式應能渲染模板: %d", w.Code)
+	}
+
+	path := filepath.Join(assets, "search.html")
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("Meme Search Engine"), []byte("開發中的標題"), 1), 0644)
+	if w := doRequest(r, "GET", "/search"); !strings.Contains(w.Body.String(), "開發中的標題") {
+		t.Errorf("修改後的模板應立即生效")
+	}
+}
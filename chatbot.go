@@ -0,0 +1,366 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [聊天機器人指令 (/meme)]
+// =========================================================
+
+// 指令的用法：
+//
+//	/meme <關鍵字>                 搜尋 (支援搜尋語法與同義詞)，從前幾筆結果挑一則
+//	/meme random [image|text]     隨機抽一則
+//	/meme help                    說明
+const memeCommandHelp = "用法：`/meme <關鍵字>` 搜尋 (支援 tag:、kind: 等搜尋語法)，`/meme random [image|text]` 隨機抽一則"
+
+const (
+	chatMaxBody      = 64 << 10
+	chatMaxAge       = 5 * time.Minute // 時間戳超過這麼久的請求視為重送攻擊
+	chatPickFrom     = 10              // 搜尋時從前幾筆結果隨機挑，重複輸入同一個指令才會有變化
+	chatMaxTextRunes = 1500            // 複製文在訊息中最多顯示的字數
+)
+
+// ErrBadSignature 簽章不符或時間戳過期
+var ErrBadSignature = errors.New("簽章驗證失敗")
+
+// chatReply 是指令的結果，Meme 為 nil 時只回覆 Text (說明或找不到資料)，只有下指令的人看得到
+type chatReply struct {
+	Meme *Meme
+	Text string
+}
+
+// runMemeCommand 解析 /meme 後面的文字並查詢，client 為搜尋分析用的使用者識別
+func runMemeCommand(syn *synonymIndex, text, client string) (chatReply, error) {
+	text = strings.TrimSpace(text)
+	fields := strings.Fields(text)
+	if len(fields) == 0 || fields[0] == "help" {
+		return chatReply{Text: memeCommandHelp}, nil
+	}
+
+	if fields[0] == "random" {
+		mode := "all"
+		if len(fields) > 1 {
+			mode = fields[1]
+		}
+		if len(fields) > 2 || (mode != "all" && mode != "image" && mode != "text") {
+			return chatReply{Text: memeCommandHelp}, nil
+		}
+		m, err := GetRandomMeme(mode)
+		if errors.Is(err, ErrNotFound) {
+			return chatReply{Text: "資料庫裡還沒有資料 🥲"}, nil
+		}
+		if err != nil {
+			return chatReply{}, err
+		}
+		recordView(m.ID)
+		return chatReply{Meme: &m}, nil
+	}
+
+	res, err := runSearch(syn, searchRequest{Query: text, Mode: "all", Limit: chatPickFrom, Client: anonymousID(client)})
+	if isSearchInputError(err) {
+		return chatReply{Text: err.Error()}, nil
+	}
+	if err != nil {
+		return chatReply{}, err
+	}
+	if len(res.Memes) == 0 {
+		return chatReply{Text: fmt.Sprintf("找不到「%s」的結果 🥲", text)}, nil
+	}
+	m := res.Memes[rand.Intn(len(res.Memes))]
+	recordView(m.ID)
+	return chatReply{Meme: &m}, nil
+}
+
+// chatMediaURL 回傳可以直接顯示的圖片網址，有本地鏡像就用鏡像 (需為絕對網址)；不是圖片時回傳空字串
+func chatMediaURL(base string, m Meme) string {
+	if !isImageURL(m.URL) {
+		return ""
+	}
+	if m.MediaURL != "" {
+		return base + m.MediaURL
+	}
+	return m.URL
+}
+
+// chatBody 是訊息的內文：複製文顯示內容，影片與圖片顯示標籤
+func chatBody(m Meme) string {
+	if isImageURL(m.URL) || isVideoURL(m.URL) {
+		return m.Tags
+	}
+	return truncateRunes(m.URL, chatMaxTextRunes)
+}
+
+func permalink(base string, m Meme) string {
+	return base + "/m/" + strconv.FormatInt(m.ID, 10)
+}
+
+// readChatBody 讀取原始內容，簽章需要以原始 bytes 計算
+func readChatBody(c *gin.Context) ([]byte, bool) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, chatMaxBody+1))
+	if err != nil || len(body) > chatMaxBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "內容過大"})
+		return nil, false
+	}
+	return body, true
+}
+
+// checkTimestamp 檢查 Unix 秒數的時間戳是否在允許範圍內
+func checkTimestamp(ts string, now time.Time) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > chatMaxAge || d < -chatMaxAge {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ---------------------------------------------------------
+// Slack (Slash Commands)
+// ---------------------------------------------------------
+
+// verifySlackSignature 依 Slack 的規則驗證：X-Slack-Signature = "v0=" + HMAC-SHA256(signing secret, "v0:時間戳:body")
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	if err := checkTimestamp(ts, now); err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// slackEscape 跳脫 Slack mrkdwn 的控制字元
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// slackMessage 組出 Block Kit 訊息，找到資料時發到頻道，否則只有下指令的人看得到
+func slackMessage(base string, r chatReply) gin.H {
+	if r.Meme == nil {
+		return gin.H{"response_type": "ephemeral", "text": r.Text}
+	}
+	m := *r.Meme
+	title := m.Title
+	if title == "" {
+		title = "#" + strconv.FormatInt(m.ID, 10)
+	}
+	blocks := []gin.H{{
+		"type": "section",
+		"text": gin.H{"type": "mrkdwn", "text": fmt.Sprintf("*<%s|%s>*", permalink(base, m), slackEscape(title))},
+	}}
+	if img := chatMediaURL(base, m); img != "" {
+		blocks = append(blocks, gin.H{"type": "image", "image_url": img, "alt_text": title})
+	}
+	if body := chatBody(m); body != "" {
+		blocks = append(blocks, gin.H{"type": "section", "text": gin.H{"type": "plain_text", "text": body}})
+	}
+	blocks = append(blocks, gin.H{
+		"type":     "context",
+		"elements": []gin.H{{"type": "mrkdwn", "text": fmt.Sprintf("來源：<%s|%s>", m.SourceURL, sourceName(m.SourceURL))}},
+	})
+	return gin.H{"response_type": "in_channel", "text": title, "blocks": blocks}
+}
+
+func slackCommandHandler(syn *synonymIndex) gin.HandlerFunc {
+	secret := os.Getenv("SLACK_SIGNING_SECRET")
+	return func(c *gin.Context) {
+		if secret == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "尚未設定 SLACK_SIGNING_SECRET"})
+			return
+		}
+		body, ok := readChatBody(c)
+		if !ok {
+			return
+		}
+		if err := verifySlackSignature(secret, c.Request.Header, body, time.Now()); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "資料格式錯誤"})
+			return
+		}
+
+		reply, err := runMemeCommand(syn, form.Get("text"), "slack:"+form.Get("team_id")+":"+form.Get("user_id"))
+		if err != nil {
+			log.Printf("[Chat] Slack 指令失敗: %v", err)
+			reply = chatReply{Text: "查詢失敗，請稍後再試"}
+		}
+		c.JSON(http.StatusOK, slackMessage(baseURL(c), reply))
+	}
+}
+
+// ---------------------------------------------------------
+// Discord (Interactions)
+// ---------------------------------------------------------
+
+// Discord 互動的類型與回應類型
+const (
+	discordPing               = 1
+	discordApplicationCommand = 2
+
+	discordPong                = 1
+	discordChannelMessage      = 4
+	discordEphemeral           = 1 << 6
+	discordEmbedColor          = 0x007bff
+	discordMaxEmbedDescription = 4096
+	discordMaxEmbedTitleRunes  = 256
+	discordQueryOption         = "query"
+)
+
+// discordInteraction 只取用得到的欄位；在伺服器中 user 放在 member 底下，私訊時直接是 user
+type discordInteraction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	GuildID string `json:"guild_id"`
+	Member  *struct {
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"member"`
+	User *struct {
+		ID string `json:"id"`
+	} `json:"user"`
+}
+
+func (in discordInteraction) userID() string {
+	if in.Member != nil {
+		return in.Member.User.ID
+	}
+	if in.User != nil {
+		return in.User.ID
+	}
+	return ""
+}
+
+// commandText 把選項還原成文字指令，註冊指令時只有一個字串選項 query
+func (in discordInteraction) commandText() string {
+	for _, opt := range in.Data.Options {
+		var s string
+		if opt.Name == discordQueryOption && json.Unmarshal(opt.Value, &s) == nil {
+			return s
+		}
+	}
+	return ""
+}
+
+// verifyDiscordSignature 依 Discord 的規則驗證：X-Signature-Ed25519 為 Ed25519(時間戳 + body) 的簽章
+func verifyDiscordSignature(publicKey ed25519.PublicKey, header http.Header, body []byte, now time.Time) error {
+	ts := header.Get("X-Signature-Timestamp")
+	if err := checkTimestamp(ts, now); err != nil {
+		return err
+	}
+	sig, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return ErrBadSignature
+	}
+	if !ed25519.Verify(publicKey, append([]byte(ts), body...), sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// discordMessage 組出 embed 訊息，找到資料時所有人看得到，否則只有下指令的人看得到
+func discordMessage(base string, r chatReply) gin.H {
+	if r.Meme == nil {
+		return gin.H{"type": discordChannelMessage, "data": gin.H{"content": r.Text, "flags": discordEphemeral}}
+	}
+	m := *r.Meme
+	title := m.Title
+	if title == "" {
+		title = "#" + strconv.FormatInt(m.ID, 10)
+	}
+	embed := gin.H{
+		"title":       truncateRunes(title, discordMaxEmbedTitleRunes-1),
+		"url":         permalink(base, m),
+		"description": truncateRunes(chatBody(m), discordMaxEmbedDescription-1),
+		"color":       discordEmbedColor,
+		"footer":      gin.H{"text": "來源：" + sourceName(m.SourceURL)},
+	}
+	if img := chatMediaURL(base, m); img != "" {
+		embed["image"] = gin.H{"url": img}
+	}
+	return gin.H{"type": discordChannelMessage, "data": gin.H{"embeds": []gin.H{embed}}}
+}
+
+func discordInteractionHandler(syn *synonymIndex) gin.HandlerFunc {
+	var publicKey ed25519.PublicKey
+	if key, err := hex.DecodeString(os.Getenv("DISCORD_PUBLIC_KEY")); err == nil && len(key) == ed25519.PublicKeySize {
+		publicKey = key
+	} else if os.Getenv("DISCORD_PUBLIC_KEY") != "" {
+		log.Printf("[警告] DISCORD_PUBLIC_KEY 格式錯誤，需為 64 個十六進位字元")
+	}
+	return func(c *gin.Context) {
+		if publicKey == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "尚未設定 DISCORD_PUBLIC_KEY"})
+			return
+		}
+		body, ok := readChatBody(c)
+		if !ok {
+			return
+		}
+		// Discord 設定網址時會故意送出簽章錯誤的請求，必須回 401
+		if err := verifyDiscordSignature(publicKey, c.Request.Header, body, time.Now()); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		var in discordInteraction
+		if err := json.Unmarshal(body, &in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "資料格式錯誤"})
+			return
+		}
+
+		switch in.Type {
+		case discordPing:
+			c.JSON(http.StatusOK, gin.H{"type": discordPong})
+		case discordApplicationCommand:
+			reply, err := runMemeCommand(syn, in.commandText(), "discord:"+in.GuildID+":"+in.userID())
+			if err != nil {
+				log.Printf("[Chat] Discord 指令失敗: %v", err)
+				reply = chatReply{Text: "查詢失敗，請稍後再試"}
+			}
+			c.JSON(http.StatusOK, discordMessage(baseURL(c), reply))
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支援的互動類型"})
+		}
+	}
+}
+
+// registerChatRoutes 掛上聊天機器人的端點。驗證改用各平台的簽章，不經過 API key 與限流
+func registerChatRoutes(r *gin.Engine, syn *synonymIndex) {
+	r.POST("/integrations/slack/commands", slackCommandHandler(syn))
+	r.POST("/integrations/discord/interactions", discordInteractionHandler(syn))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var chatTestMemes = []ExportMeme{
+	{Title: "貓咪跳舞", URL: "https://example.com/cat.gif", Tags: "GIF cat", SourceURL: "https://www.gif-vif.com/gifs/cat"},
+	{Title: "<好喝茶> & 複製文", URL: "每天都要喝一杯好喝茶", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html"},
+}
+
+// slackCommand 以 secret 簽章送出 Slack 的 slash command，ts 為 0 時使用現在時間
+func slackCommand(r http.Handler, secret, text string, ts int64) *httptest.ResponseRecorder {
+	if ts == 0 {
+		ts = time.Now().Unix()
+	}
+	body := url.Values{"command": {"/meme"}, "text": {text}, "team_id": {"T1"}, "user_id": {"U1"}}.Encode()
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%d:%s", ts, body)
+
+	req := httptest.NewRequest("POST", "/integrations/slack/commands", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// slackResponse 只取測試需要的欄位
+type slackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+	Blocks       []struct {
+		Type     string `json:"type"`
+		ImageURL string `json:"image_url"`
+		Text     struct {
+			Text string `json:"text"`
+		} `json:"text"`
+	} `json:"blocks"`
+}
+
+func TestSlackMemeCommand(t *testing.T) {
+	t.Setenv("SLACK_SIGNING_SECRET", "slack-secret")
+	r := setupTestServer(t, chatTestMemes...)
+
+	w := slackCommand(r, "slack-secret", "好喝茶", 0)
+	var res slackResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || res.ResponseType != "in_channel" || len(res.Blocks) != 3 {
+		t.Fatalf("搜尋結果應發到頻道: %d %s", w.Code, w.Body.String())
+	}
+	if got := res.Blocks[0].Text.Text; got != "*<http://example.com/m/2|&lt;好喝茶&gt; &amp; 複製文>*" {
+		t.Errorf("標題應附上永久連結並跳脫: %s", got)
+	}
+	if res.Blocks[1].Text.Text != "每天都要喝一杯好喝茶" {
+		t.Errorf("複製文應顯示內容: %s", w.Body.String())
+	}
+
+	w = slackCommand(r, "slack-secret", "random image", 0)
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.ResponseType != "in_channel" || res.Blocks[1].Type != "image" || res.Blocks[1].ImageURL != "https://example.com/cat.gif" {
+		t.Errorf("random image 應回傳圖片: %s", w.Body.String())
+	}
+
+	for _, text := range []string{"", "help", "random gif", "找不到的東西", "after:昨天"} {
+		w = slackCommand(r, "slack-secret", text, 0)
+		res = slackResponse{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		if w.Code != http.StatusOK || res.ResponseType != "ephemeral" || res.Text == "" || len(res.Blocks) != 0 {
+			t.Errorf("%q 應只回覆給下指令的人: %d %s", text, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestSlackSignatureVerification(t *testing.T) {
+	t.Setenv("SLACK_SIGNING_SECRET", "slack-secret")
+	r := setupTestServer(t, chatTestMemes...)
+
+	if w := slackCommand(r, "wrong-secret", "好喝茶", 0); w.Code != http.StatusUnauthorized {
+		t.Errorf("簽章錯誤應回傳 401，得到 %d", w.Code)
+	}
+	if w := slackCommand(r, "slack-secret", "好喝茶", time.Now().Add(-10*time.Minute).Unix()); w.Code != http.StatusUnauthorized {
+		t.Errorf("過期的請求應回傳 401，得到 %d", w.Code)
+	}
+
+	// 沒有設定金鑰時不接受任何請求
+	t.Setenv("SLACK_SIGNING_SECRET", "")
+	r = setupRouter()
+	if w := slackCommand(r, "", "好喝茶", 0); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("未設定金鑰應回傳 503，得到 %d", w.Code)
+	}
+}
+
+// discordInteract 以 key 簽章送出 Discord 的互動
+func discordInteract(r http.Handler, key ed25519.PrivateKey, body string, ts int64) *httptest.ResponseRecorder {
+	if ts == 0 {
+		ts = time.Now().Unix()
+	}
+	stamp := strconv.FormatInt(ts, 10)
+	req := httptest.NewRequest("POST", "/integrations/discord/interactions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature-Timestamp", stamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(stamp+body))))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func discordCommandBody(query string) string {
+	body, _ := json.Marshal(map[string]any{
+		"type":     discordApplicationCommand,
+		"guild_id": "G1",
+		"member":   map[string]any{"user": map[string]any{"id": "U1"}},
+		"data":     map[string]any{"name": "meme", "options": []map[string]any{{"name": "query", "type": 3, "value": query}}},
+	})
+	return string(body)
+}
+
+// discordResponse 只取測試需要的欄位
+type discordResponse struct {
+	Type int `json:"type"`
+	Data struct {
+		Content string `json:"content"`
+		Flags   int    `json:"flags"`
+		Embeds  []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			Image       *struct {
+				URL string `json:"url"`
+			} `json:"image"`
+		} `json:"embeds"`
+	} `json:"data"`
+}
+
+func TestDiscordMemeCommand(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	t.Setenv("DISCORD_PUBLIC_KEY", hex.EncodeToString(pub))
+	r := setupTestServer(t, chatTestMemes...)
+
+	// Discord 設定網址時會先送 PING
+	if w := discordInteract(r, key, `{"type":1}`, 0); w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"type":1}` {
+		t.Errorf("PING 應回傳 PONG: %d %s", w.Code, w.Body.String())
+	}
+
+	w := discordInteract(r, key, discordCommandBody("tag:cat"), 0)
+	var res discordResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if w.Code != http.StatusOK || res.Type != discordChannelMessage || res.Data.Flags != 0 || len(res.Data.Embeds) != 1 {
+		t.Fatalf("搜尋結果應發到頻道: %d %s", w.Code, w.Body.String())
+	}
+	embed := res.Data.Embeds[0]
+	if embed.Title != "貓咪跳舞" || embed.URL != "http://example.com/m/1" || embed.Image == nil || embed.Image.URL != "https://example.com/cat.gif" {
+		t.Errorf("embed 錯誤: %s", w.Body.String())
+	}
+
+	w = discordInteract(r, key, discordCommandBody("random text"), 0)
+	res = discordResponse{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if len(res.Data.Embeds) != 1 || res.Data.Embeds[0].Description != "每天都要喝一杯好喝茶" || res.Data.Embeds[0].Image != nil {
+		t.Errorf("random text 應回傳複製文: %s", w.Body.String())
+	}
+
+	w = discordInteract(r, key, discordCommandBody("找不到的東西"), 0)
+	res = discordResponse{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if res.Data.Flags != discordEphemeral || res.Data.Content == "" || len(res.Data.Embeds) != 0 {
+		t.Errorf("找不到時應只回覆給下指令的人: %s", w.Body.String())
+	}
+}
+
+func TestDiscordSignatureVerification(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	t.Setenv("DISCORD_PUBLIC_KEY", hex.EncodeToString(pub))
+	r := setupTestServer(t, chatTestMemes...)
+
+	if w := discordInteract(r, otherKey, `{"type":1}`, 0); w.Code != http.StatusUnauthorized {
+		t.Errorf("簽章錯誤應回傳 401，得到 %d", w.Code)
+	}
+	if w := discordInteract(r, key, `{"type":1}`, time.Now().Add(-10*time.Minute).Unix()); w.Code != http.StatusUnauthorized {
+		t.Errorf("過期的請求應回傳 401，得到 %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/integrations/discord/interactions", `{"type":1}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("沒有簽章應回傳 401，得到 %d", w.Code)
+	}
+	if w := discordInteract(r, key, `{"type":3}`, 0); w.Code != http.StatusBadRequest {
+		t.Errorf("不支援的互動應回傳 400，得到 %d", w.Code)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// =========================================================
+// [執行設定：資料目錄與開發模式]
+// =========================================================
+
+// Config 是啟動參數。所有檔案路徑都由 DataDir 推導並轉成絕對路徑，
+// 不論從哪個目錄執行都會讀寫同一份資料
+type Config struct {
+	DataDir  string // 資料庫、匯出檔、鏡像檔與爬蟲快取的根目錄
+	Dev      bool   // 開發模式：模板與靜態檔每次請求都從 AssetDir 重新讀取
+	AssetDir string // 開發模式讀取模板的目錄 (通常是原始碼目錄)
+}
+
+// AppConfig 是目前生效的設定，預設值與舊版相同 (目前目錄、使用內嵌的模板)
+var AppConfig = Config{DataDir: ".", AssetDir: "."}
+
+// CacheDir 是爬蟲 (Colly) 的網頁快取目錄
+var CacheDir = "./cache"
+
+// ParseConfig 解析 --data-dir / --dev / --assets，回傳剩下的參數 (子指令)。
+// 沒有指定 --data-dir 時使用環境變數 DATA_DIR，再沒有就是目前目錄
+func ParseConfig(args []string, output io.Writer) (Config, []string, error) {
+	cfg := AppConfig
+	if env := os.Getenv("DATA_DIR"); env != "" {
+		cfg.DataDir = env
+	}
+
+	fs := flag.NewFlagSet("meme", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&cfg.DataDir, "data-dir", cfg.DataDir, "資料庫、匯出檔與鏡像檔的目錄 (環境變數 DATA_DIR)")
+	fs.BoolVar(&cfg.Dev, "dev", false, "開發模式：每次請求都從磁碟重新讀取模板")
+	fs.StringVar(&cfg.AssetDir, "assets", cfg.AssetDir, "開發模式讀取模板的目錄")
+	if err := fs.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	var err error
+	if cfg.DataDir, err = filepath.Abs(cfg.DataDir); err != nil {
+		return cfg, nil, fmt.Errorf("資料目錄錯誤: %v", err)
+	}
+	if cfg.AssetDir, err = filepath.Abs(cfg.AssetDir); err != nil {
+		return cfg, nil, fmt.Errorf("模板目錄錯誤: %v", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// Apply 建立資料目錄並把各個路徑指到裡面
+func (cfg Config) Apply() error {
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		return fmt.Errorf("建立資料目錄失敗: %v", err)
+	}
+	DBFile = filepath.Join(cfg.DataDir, "memes.db")
+	ExportFile = filepath.Join(cfg.DataDir, "memes_raw_data.json")
+	MediaDir = filepath.Join(cfg.DataDir, "media")
+	CacheDir = filepath.Join(cfg.DataDir, "cache")
+	AppConfig = cfg
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"sync"
+)
+
+// =========================================================
+// [爬蟲執行紀錄]
+// =========================================================
+
+// 爬蟲執行的狀態
+const (
+	CrawlRunning = "running"
+	CrawlOK      = "ok"
+	CrawlFailed  = "failed"
+)
+
+// CrawlRun 是某個來源的一次爬取，Source 與搜尋分析的來源名稱相同 (gif / ptt / threads / plurk)
+type CrawlRun struct {
+	ID         int64  `json:"id"`
+	Source     string `json:"source"`
+	Status     string `json:"status"`
+	StartedAt  string `json:"started_at"`
+	FinishedAt string `json:"finished_at,omitempty"`
+	Found      int    `json:"found"`  // 解析出的筆數
+	Saved      int    `json:"saved"`  // 寫入資料庫成功的筆數 (已存在的網址也算，INSERT OR IGNORE)
+	Errors     int    `json:"errors"` // 請求或解析失敗的次數
+	Message    string `json:"message,omitempty"`
+}
+
+// CrawlRecorder 在爬取過程中累計數字，Colly 的 callback 可能同時執行所以需要上鎖。
+// 寫入紀錄失敗只會記 log，不影響爬蟲本身
+type CrawlRecorder struct {
+	mu  sync.Mutex
+	run CrawlRun
+}
+
+// StartCrawlRun 新增一筆執行中的紀錄
+func StartCrawlRun(source string) *CrawlRecorder {
+	rec := &CrawlRecorder{run: CrawlRun{Source: source, Status: CrawlRunning}}
+	res, err := db.Exec(`INSERT INTO crawl_runs (source, status) VALUES (?, ?)`, source, CrawlRunning)
+	if err != nil {
+		log.Printf("[CrawlRun] 無法建立 %s 的執行紀錄: %v", source, err)
+		return rec
+	}
+	rec.run.ID, _ = res.LastInsertId()
+	return rec
+}
+
+// Found 記錄解析出一筆資料，saveErr 為寫入資料庫的結果
+func (r *CrawlRecorder) Found(saveErr error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run.Found++
+	if saveErr == nil {
+		r.run.Saved++
+	} else {
+		r.run.Errors++
+	}
+}
+
+// Error 記錄一次失敗，最後一個錯誤訊息會寫進紀錄
+func (r *CrawlRecorder) Error(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run.Errors++
+	r.run.Message = err.Error()
+}
+
+// Finish 結束這次執行；fatal 不為 nil 表示整個來源都沒辦法爬 (例如連不上瀏覽器)
+func (r *CrawlRecorder) Finish(fatal error) CrawlRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.run.Status = CrawlOK
+	if fatal != nil {
+		r.run.Status = CrawlFailed
+		r.run.Message = fatal.Error()
+	}
+	if r.run.ID == 0 {
+		return r.run
+	}
+	_, err := db.Exec(`UPDATE crawl_runs SET status = ?, finished_at = CURRENT_TIMESTAMP, found = ?, saved = ?, errors = ?, message = ?
+		WHERE id = ?`, r.run.Status, r.run.Found, r.run.Saved, r.run.Errors, r.run.Message, r.run.ID)
+	if err != nil {
+		log.Printf("[CrawlRun] 無法更新執行紀錄 #%d: %v", r.run.ID, err)
+	}
+	log.Printf("[CrawlRun] %s: %s，解析 %d 筆、寫入 %d 筆、失敗 %d 次", r.run.Source, r.run.Status, r.run.Found, r.run.Saved, r.run.Errors)
+	return r.run
+}
+
+const crawlRunColumns = `id, source, status, started_at, COALESCE(finished_at, ''), found, saved, errors, message`
+
+func scanCrawlRun(row rowScanner) (CrawlRun, error) {
+	var r CrawlRun
+	err := row.Scan(&r.ID, &r.Source, &r.Status, &r.StartedAt, &r.FinishedAt, &r.Found, &r.Saved, &r.Errors, &r.Message)
+	return r, err
+}
+
+// ListCrawlRuns 依時間倒序列出執行紀錄，source 為空時列出所有來源
+func ListCrawlRuns(source string, limit int) ([]CrawlRun, error) {
+	rows, err := db.Query(`SELECT `+crawlRunColumns+` FROM crawl_runs
+		WHERE ? = '' OR source = ? ORDER BY id DESC LIMIT ?`, source, source, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	runs := []CrawlRun{}
+	for rows.Next() {
+		r, err := scanCrawlRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+func GetCrawlRun(id int64) (CrawlRun, error) {
+	r, err := scanCrawlRun(db.QueryRow(`SELECT `+crawlRunColumns+` FROM crawl_runs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return r, ErrNotFound
+	}
+	return r, err
+}
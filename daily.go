@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [每日一梗]
+// =========================================================
+
+const dateLayout = "2006-01-02"
+
+// dailyStartDate 是每日一梗的第一天。查詢過的日期會永久寫入 daily_memes，
+// 不設下限的話任何人都能用 0001-01-01 之類的日期把歷史紀錄塞滿
+const dailyStartDate = "2025-01-01"
+
+var ErrFutureDate = errors.New("還沒到那一天，不能偷看")
+var ErrDateTooEarly = errors.New("每日一梗從 " + dailyStartDate + " 開始")
+var ErrInvalidDailyMode = errors.New("mode 需為 all、image 或 text")
+
+type DailyMeme struct {
+	Date string `json:"date"`
+	Mode string `json:"mode"`
+	Meme Meme   `json:"meme"`
+}
+
+func getDailyMeme(date, mode string) (DailyMeme, error) {
+	d := DailyMeme{Date: date, Mode: mode}
+	var err error
+	d.Meme, err = scanMeme(db.QueryRow(`SELECT `+prefixedMemeColumns+` FROM daily_memes
+		JOIN memes ON memes.id = daily_memes.meme_id
+		WHERE daily_memes.date = ? AND daily_memes.mode = ? AND memes.`+visibleSQL, date, mode))
+	if err == sql.ErrNoRows {
+		return DailyMeme{}, ErrNotFound
+	}
+	return d, err
+}
+
+// GetDailyMeme 回傳指定日期的每日一梗。
+// 第一次被查詢時以「日期 + 模式」為種子抽出並寫入 daily_memes，之後即使資料增加也不會改變
+func GetDailyMeme(date time.Time, mode string) (DailyMeme, error) {
+	if !slices.Contains(v1Modes, mode) {
+		return DailyMeme{}, ErrInvalidDailyMode
+	}
+	key := date.Format(dateLayout)
+	if key > time.Now().Format(dateLayout) {
+		return DailyMeme{}, ErrFutureDate
+	}
+	if key < dailyStartDate {
+		return DailyMeme{}, ErrDateTooEarly
+	}
+
+	d, err := getDailyMeme(key, mode)
+	if !errors.Is(err, ErrNotFound) {
+		return d, err
+	}
+
+	var exists bool
+	db.QueryRow(`SELECT 1 FROM daily_memes WHERE date = ? AND mode = ?`, key, mode).Scan(&exists)
+	if exists {
+		// 當天的梗已被下架，不再重抽以免歷史紀錄改變
+		return DailyMeme{}, ErrNotFound
+	}
+
+	picked, err := GetRandomMemes(RandomOptions{Mode: mode, Count: 1, Rand: seededRand("daily|" + key + "|" + mode)})
+	if err != nil {
+		return DailyMeme{}, err
+	}
+	if len(picked) == 0 {
+		return DailyMeme{}, ErrNotFound
+	}
+
+	// 同時有兩個請求時，以先寫入的為準
+	if _, err := db.Exec(`INSERT OR IGNORE INTO daily_memes (date, mode, meme_id) VALUES (?, ?, ?)`, key, mode, picked[0].ID); err != nil {
+		return DailyMeme{}, err
+	}
+	return getDailyMeme(key, mode)
+}
+
+// ListDailyHistory 依日期倒序列出過去的每日一梗
+func ListDailyHistory(mode string, limit int) ([]DailyMeme, error) {
+	rows, err := db.Query(`SELECT `+prefixedMemeColumns+`, daily_memes.date FROM daily_memes
+		JOIN memes ON memes.id = daily_memes.meme_id
+		WHERE daily_memes.mode = ? AND memes.`+visibleSQL+`
+		ORDER BY daily_memes.date DESC LIMIT ?`, mode, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []DailyMeme{}
+	for rows.Next() {
+		d := DailyMeme{Mode: mode}
+		var err error
+		if d.Meme, err = scanMeme(rows, &d.Date); err != nil {
+			return nil, err
+		}
+		history = append(history, d)
+	}
+	return history, rows.Err()
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+func registerDailyRoutes(api *gin.RouterGroup) {
+	api.GET("/daily", func(c *gin.Context) {
+		date := time.Now()
+		if s := c.Query("date"); s != "" {
+			var err error
+			if date, err = time.ParseInLocation(dateLayout, s, time.Local); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "date 格式需為 YYYY-MM-DD"})
+				return
+			}
+		}
+		d, err := GetDailyMeme(date, c.DefaultQuery("mode", "all"))
+		if errors.Is(err, ErrFutureDate) || errors.Is(err, ErrDateTooEarly) || errors.Is(err, ErrInvalidDailyMode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, d)
+	})
+
+	api.GET("/daily/history", func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "all")
+		if !slices.Contains(v1Modes, mode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidDailyMode.Error()})
+			return
+		}
+		history, err := ListDailyHistory(mode, queryInt(c, "limit", 30, 365))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, history)
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDailyMeme(t *testing.T) {
+	var memes []ExportMeme
+	for i := 1; i <= 20; i++ {
+		memes = append(memes, ExportMeme{Title: fmt.Sprintf("梗 %d", i), URL: fmt.Sprintf("內容 %d", i), SourceURL: "https://www.ptt.cc/bbs/Joke/M." + fmt.Sprint(i)})
+	}
+	r := setupTestServer(t, memes...)
+
+	var first DailyMeme
+	w := doRequest(r, "GET", "/api/daily?date=2025-03-01")
+	if w.Code != http.StatusOK {
+		t.Fatalf("預期 200，得到 %d: %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &first)
+
+	// 新增資料後，已經決定的日期不會改變
+	InsertMeme(ExportMeme{Title: "後來才加的", URL: "新內容", SourceURL: "https://www.ptt.cc/bbs/Joke/M.new"})
+	again, _ := GetDailyMeme(time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "all")
+	if again.Meme.ID != first.Meme.ID {
+		t.Errorf("同一天的每日一梗不應改變: %d -> %d", first.Meme.ID, again.Meme.ID)
+	}
+
+	// 不同模式各自獨立
+	if _, err := GetDailyMeme(time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "text"); err != nil {
+		t.Errorf("文字模式抽取失敗: %v", err)
+	}
+	if _, err := GetDailyMeme(time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local), "image"); err != ErrNotFound {
+		t.Errorf("沒有圖片時應回傳 ErrNotFound，得到 %v", err)
+	}
+
+	if w := doRequest(r, "GET", "/api/daily?date=2999-01-01"); w.Code != http.StatusBadRequest {
+		t.Errorf("未來日期預期 400，得到 %d", w.Code)
+	}
+	if w := doRequest(r, "GET", "/api/daily?date=yesterday"); w.Code != http.StatusBadRequest {
+		t.Errorf("日期格式錯誤預期 400，得到 %d", w.Code)
+	}
+
+	// 太早的日期與未知的模式不會寫入 daily_memes
+	for _, path := range []string{"/api/daily?date=0001-01-01", "/api/daily?date=2024-12-31", "/api/daily?mode=gif", "/api/daily/history?mode=gif"} {
+		if w := doRequest(r, "GET", path); w.Code != http.StatusBadRequest {
+			t.Errorf("%s 預期 400，得到 %d", path, w.Code)
+		}
+	}
+	var stored int
+	db.QueryRow(`SELECT COUNT(*) FROM daily_memes WHERE date < ? OR mode NOT IN ('all', 'image', 'text')`, dailyStartDate).Scan(&stored)
+	if stored != 0 {
+		t.Errorf("不應寫入無效的日期或模式，得到 %d 筆", stored)
+	}
+
+	doRequest(r, "GET", "/api/daily?date=2025-03-02")
+	var history []DailyMeme
+	json.Unmarshal(doRequest(r, "GET", "/api/daily/history").Body.Bytes(), &history)
+	if len(history) != 2 || history[0].Date != "2025-03-02" || history[1].Meme.ID != first.Meme.ID {
+		t.Errorf("歷史紀錄不符: %+v", history)
+	}
+}
+
+// 不同日期的每日一梗要平均分散在所有候選資料上，不能集中在某一筆
+func TestDailyMemeSpreadsOverCandidates(t *testing.T) {
+	setupTestServer(t, sparseImageMemes(4, 96)...)
+
+	counts := map[int64]int{}
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local)
+	for i := range 120 {
+		d, err := GetDailyMeme(day.AddDate(0, 0, i), "image")
+		if err != nil {
+			t.Fatalf("抽取失敗: %v", err)
+		}
+		counts[d.Meme.ID]++
+	}
+	for id := int64(1); id <= 4; id++ {
+		if counts[id] < 10 {
+			t.Errorf("120 天裡 id %d 只出現 %d 次: %v", id, counts[id], counts)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"log"
 	"os"
-	"path/filepath"
 )
 
 const MaxScanTokenSize = 5 * 1024 * 1024
@@ -16,7 +15,7 @@ func RunDataImporter() {
 		return
 	}
 
-	filePath := filepath.Join(".", ExportFile)
+	filePath := ExportFile
 	file, err := os.Open(filePath)
 	if err != nil {
 		log.Fatalf("無法開啟 JSON 檔案 %s: %v", filePath, err)
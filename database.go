@@ -3,9 +3,12 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -15,18 +18,45 @@ import (
 // =========================================================
 
 type ExportMeme struct {
+	ID        int64  `json:"id,omitempty"`
 	Title     string `json:"title"`
 	URL       string `json:"url"`
 	Tags      string `json:"tags"`
 	SourceURL string `json:"source_url"`
+	Author    string `json:"author,omitempty"`    // 發文者 (PTT / Threads / Plurk)
+	MediaURL  string `json:"media_url,omitempty"` // 已鏡像到本地時的網址 (/media/:hash)
+
+	ThumbnailURL string `json:"thumbnail_url,omitempty"` // GIF 的靜態封面
+	PreviewURL   string `json:"preview_url,omitempty"`   // GIF 的縮小版動畫
+
+	// 爬蟲從來源頁面擷取的詮釋資料 (目前只有 GIF 詳細頁有)
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+	UploadedAt  string `json:"uploaded_at,omitempty"` // 來源網站的上傳日期 (YYYY-MM-DD)
+	SourceViews int64  `json:"source_views,omitempty"`
+
+	MatchedTerms []string `json:"matched_terms,omitempty"` // 搜尋時實際命中的關鍵字 (含同義詞)
+
+	// 搜尋時從內文 (GIF 則為描述) 擷取的摘要，Highlights 是摘要中命中關鍵字的位置
+	Snippet    string      `json:"snippet,omitempty"`
+	Highlights []Highlight `json:"highlights,omitempty"`
 }
 
 type Meme = ExportMeme
 
 var db *sql.DB
 
-const ExportFile = "memes_raw_data.json"
-const DBFile = "./memes.db"
+// ErrNotFound 查無資料時回傳，讓 API 層可以分辨 404 與其他錯誤
+var ErrNotFound = errors.New("找不到資料")
+
+// memeColumns 是所有查詢共用的欄位順序，需與 scanMeme 一致
+const memeColumns = `id, title, url, tags, source_url, author, media_hash, media_thumbnails, description, category, uploaded_at, source_views`
+
+// prefixedMemeColumns 是 JOIN 查詢用的 memeColumns
+var prefixedMemeColumns = "memes." + strings.ReplaceAll(memeColumns, ", ", ", memes.")
+
+var ExportFile = "memes_raw_data.json"
+var DBFile = "./memes.db"
 
 // =========================================================
 // [初始化與檔案操作]
@@ -95,16 +125,260 @@ func InitDB(dataSourceName string) error {
 	if err != nil {
 		return fmt.Errorf("建立表格失敗: %v", err)
 	}
+
+	// 舊版 memes.db 沒有這些欄位，啟動時自動補上
+	for _, col := range memeColumnMigrations {
+		if err := ensureColumn("memes", col.name, col.definition); err != nil {
+			return fmt.Errorf("更新表格欄位 %s 失敗: %v", col.name, err)
+		}
+	}
+
+	// 舊資料沒有發文者，Threads / Plurk 的標題就是帳號名稱
+	if _, err := db.Exec(`UPDATE memes SET author = title WHERE author = '' AND tags IN ('Threads', 'Plurk')`); err != nil {
+		return fmt.Errorf("補上發文者失敗: %v", err)
+	}
+
+	for _, stmt := range extraTablesSQL {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("建立表格失敗: %v", err)
+		}
+	}
+	// 舊版的搜尋紀錄沒有存結果，無法驗證點擊回報
+	if err := ensureColumn("search_log", "result_ids", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("更新表格欄位 result_ids 失敗: %v", err)
+	}
+
+	// 換了資料庫，已經存在的資料都不算新資料
+	latest, err := LatestMemeID()
+	if err != nil {
+		return err
+	}
+	memeEvents.Reset(latest)
 	return nil
 }
 
+// memeColumnMigrations 是建表之後陸續加入 memes 的欄位
+var memeColumnMigrations = []struct {
+	name       string
+	definition string
+}{
+	{"deleted_at", "DATETIME"},                         // 軟刪除：有值代表已被管理員下架
+	{"media_hash", "TEXT"},                             // 鏡像到本地的媒體檔 (media.hash)
+	{"media_thumbnails", "INTEGER NOT NULL DEFAULT 0"}, // 鏡像檔是 GIF 且縮圖已產生
+	{"link_status", "INTEGER"},                         // 最後一次連結檢查的 HTTP 狀態碼
+	{"link_checked_at", "DATETIME"},
+	{"link_failures", "INTEGER NOT NULL DEFAULT 0"}, // 連續檢查失敗次數
+	{"description", "TEXT NOT NULL DEFAULT ''"},
+	{"category", "TEXT NOT NULL DEFAULT ''"},
+	{"uploaded_at", "TEXT NOT NULL DEFAULT ''"},
+	{"source_views", "INTEGER NOT NULL DEFAULT 0"},
+	{"author", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// extraTablesSQL 是 memes 以外的輔助表格
+var extraTablesSQL = []string{
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		meme_id INTEGER,
+		action TEXT,
+		actor TEXT,
+		before_json TEXT,
+		after_json TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT,
+		key_hash TEXT UNIQUE,
+		prefix TEXT,
+		rate_per_min INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		revoked_at DATETIME
+	);`,
+	`CREATE TABLE IF NOT EXISTS votes (
+		meme_id INTEGER,
+		voter TEXT,
+		value INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (meme_id, voter)
+	);`,
+	`CREATE TABLE IF NOT EXISTS meme_stats (
+		meme_id INTEGER PRIMARY KEY,
+		upvotes INTEGER DEFAULT 0,
+		downvotes INTEGER DEFAULT 0,
+		views INTEGER DEFAULT 0,
+		copies INTEGER DEFAULT 0,
+		hot REAL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_meme_stats_hot ON meme_stats (hot)`,
+	`CREATE TABLE IF NOT EXISTS daily_memes (
+		date TEXT,
+		mode TEXT,
+		meme_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (date, mode)
+	);`,
+	`CREATE TABLE IF NOT EXISTS media (
+		hash TEXT PRIMARY KEY,
+		source_url TEXT,
+		mime_type TEXT,
+		size INTEGER,
+		width INTEGER,
+		height INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS media_hashes (
+		media_hash TEXT,
+		frame INTEGER,
+		dhash INTEGER,
+		PRIMARY KEY (media_hash, frame)
+	);`,
+	`CREATE TABLE IF NOT EXISTS synonyms (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		terms TEXT,
+		source TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS popular_queries (
+		query TEXT PRIMARY KEY,
+		count INTEGER NOT NULL DEFAULT 0,
+		last_searched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS search_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		query TEXT,
+		mode TEXT,
+		result_count INTEGER,
+		latency_ms INTEGER,
+		client TEXT,
+		result_ids TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_search_log_created ON search_log (created_at)`,
+	`CREATE TABLE IF NOT EXISTS search_clicks (
+		search_id INTEGER,
+		meme_id INTEGER,
+		action TEXT,
+		position INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (search_id, meme_id, action)
+	);`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE,
+		password_hash TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS sessions (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER,
+		expires_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS favorites (
+		user_id INTEGER,
+		meme_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, meme_id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS collections (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER,
+		name TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, name)
+	);`,
+	`CREATE TABLE IF NOT EXISTS collection_items (
+		collection_id INTEGER,
+		meme_id INTEGER,
+		added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (collection_id, meme_id)
+	);`,
+	`CREATE TABLE IF NOT EXISTS crawl_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source TEXT,
+		status TEXT,
+		started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		finished_at DATETIME,
+		found INTEGER DEFAULT 0,
+		saved INTEGER DEFAULT 0,
+		errors INTEGER DEFAULT 0,
+		message TEXT DEFAULT ''
+	);`,
+	`CREATE TABLE IF NOT EXISTS saved_searches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT,
+		query TEXT,
+		mode TEXT,
+		webhook_url TEXT,
+		secret TEXT,
+		active INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		saved_search_id INTEGER,
+		meme_id INTEGER,
+		payload TEXT,
+		status TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at INTEGER, -- Unix 毫秒
+		response_status INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (saved_search_id, meme_id)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at)`,
+	`CREATE TABLE IF NOT EXISTS webhook_cursor (
+		id INTEGER PRIMARY KEY CHECK (id = 1), -- 只有一列
+		last_meme_id INTEGER NOT NULL
+	);`,
+}
+
+// ensureColumn 若欄位不存在就用 ALTER TABLE 補上 (SQLite 沒有 ADD COLUMN IF NOT EXISTS)
+func ensureColumn(table, column, definition string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, definition))
+	return err
+}
+
 func InsertMeme(m ExportMeme) error {
 	if db == nil {
 		return fmt.Errorf("資料庫尚未初始化")
 	}
-	query := `INSERT OR IGNORE INTO memes (title, url, tags, source_url) VALUES (?, ?, ?, ?)`
-	_, err := db.Exec(query, m.Title, m.URL, m.Tags, m.SourceURL)
-	return err
+	query := `INSERT OR IGNORE INTO memes (title, url, tags, source_url, author, description, category, uploaded_at, source_views)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := db.Exec(query, m.Title, m.URL, m.Tags, m.SourceURL, m.Author, m.Description, m.Category, m.UploadedAt, m.SourceViews)
+	if err != nil {
+		return err
+	}
+	// 已存在的網址不算新資料
+	if n, _ := res.RowsAffected(); n == 1 {
+		m.ID, _ = res.LastInsertId()
+		memeEvents.Publish(m)
+	}
+	return nil
 }
 
 func GetMemeCount() (int, error) {
@@ -116,56 +390,326 @@ func GetMemeCount() (int, error) {
 	return count, err
 }
 
+// rowScanner 讓 scanMeme 同時支援 *sql.Row 與 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanMeme 讀取 memeColumns，extra 為查詢中接在 memeColumns 之後的其他欄位
+func scanMeme(row rowScanner, extra ...any) (Meme, error) {
+	var m Meme
+	var mediaHash sql.NullString
+	var thumbnails bool
+	dest := append([]any{&m.ID, &m.Title, &m.URL, &m.Tags, &m.SourceURL, &m.Author, &mediaHash, &thumbnails,
+		&m.Description, &m.Category, &m.UploadedAt, &m.SourceViews}, extra...)
+	err := row.Scan(dest...)
+	if mediaHash.String != "" {
+		m.MediaURL = "/media/" + mediaHash.String
+		if thumbnails {
+			m.ThumbnailURL = m.MediaURL + "/" + thumbPoster
+			m.PreviewURL = m.MediaURL + "/" + thumbPreview
+		}
+	}
+	return m, err
+}
+
+// modeFilterSQL 回傳模式過濾條件 (以 AND 開頭)，搜尋與隨機共用同一套規則
+func modeFilterSQL(mode string) string {
+	if cond := kindSQL(mode); cond != "" {
+		return ` AND ` + cond
+	}
+	return ""
+}
+
+// gifSourcePrefix 是 GIF 詳細頁網址的開頭，kindSQL 與 matchesKind 都以它判斷是否為圖片
+const gifSourcePrefix = "https://www.gif-vif.com/gifs/"
+
+// kindSQL 回傳 image / text 的判斷條件，其他值回傳空字串
+func kindSQL(kind string) string {
+	if kind == "image" {
+		return `source_url LIKE '` + gifSourcePrefix + `%'`
+	} else if kind == "text" {
+		return `source_url NOT LIKE '` + gifSourcePrefix + `%'`
+	}
+	return ""
+}
+
+// matchesKind 是 kindSQL 的 Go 版本，用來過濾已經讀出來的資料，兩者的規則需一致
+func matchesKind(m Meme, kind string) bool {
+	isImage := strings.HasPrefix(m.SourceURL, gifSourcePrefix)
+	switch kind {
+	case "image":
+		return isImage
+	case "text":
+		return !isImage
+	}
+	return true
+}
+
+// textMatchSQL 以同一個關鍵字比對所有文字欄位，需傳入 5 次參數。
+// [修正] 加入 url 支援內文搜尋；GIF 另外比對爬蟲擷取的描述與分類
+const textMatchSQL = `(title LIKE ? OR tags LIKE ? OR url LIKE ? OR description LIKE ? OR category LIKE ?)`
+
+// visibleSQL 是一般使用者看得到的資料條件 (排除已軟刪除的項目)
+const visibleSQL = `deleted_at IS NULL`
+
+// deadLinkThreshold 連結連續檢查失敗達到這個次數就視為失效
+const deadLinkThreshold = 3
+
+// aliveSQL 排除連結已失效的項目 (以 AND 開頭)。只用在搜尋、隨機與排行，直接用 id 開啟仍看得到
+var aliveSQL = fmt.Sprintf(` AND link_failures < %d`, deadLinkThreshold)
+
+// SearchOptions 是搜尋的完整參數，SearchMemes 是最常用的簡化版
+type SearchOptions struct {
+	Query  string
+	Terms  []string // 與 Query 同義的其他關鍵字 (例如翻譯)，任一個符合即可
+	Mode   string
+	Sort   string // "" 或 "new"：最新；"hot"：熱門 (隨時間衰減)；"top"：總分
+	Limit  int
+	Offset int // 分頁用，略過前面幾筆
+
+	// 只用來標示摘要的關鍵字 (例如搜尋語法中的片語)，不影響搜尋結果
+	HighlightTerms []string
+
+	// 額外的過濾條件 (以 AND 開頭)，由搜尋語法的欄位條件產生
+	FilterSQL  string
+	FilterArgs []any
+}
+
+// ErrInvalidSort 排序方式不在 new / hot / top 之內
+var ErrInvalidSort = errors.New("sort 只接受 new、hot 或 top")
+
+// 熱門度採用 Reddit 式公式：hot = sign(p)·log10(max(|p|,1)) + (建立時間 - hotEpoch) / hotDecaySeconds。
+// 新的項目基礎分數較高，所以舊項目需要累積 10 倍的人氣才能維持同樣的排名
+const hotEpoch = 1735689600 // 2025-01-01 00:00:00 UTC
+const hotDecaySeconds = 45000.0
+
+// baseHotSQL 是沒有任何互動 (人氣為 0) 時的熱門度
+var baseHotSQL = fmt.Sprintf(`(CAST(strftime('%%s', memes.created_at) AS REAL) - %d) / %g`, hotEpoch, hotDecaySeconds)
+
+// sortSQL 把排序方式轉成 ORDER BY；meme_stats 以 LEFT JOIN 帶入，沒有互動紀錄的項目視為 0 分
+func sortSQL(sort string) (string, error) {
+	switch sort {
+	case "", "new":
+		return ` ORDER BY memes.id DESC`, nil
+	case "hot":
+		return ` ORDER BY COALESCE(meme_stats.hot, ` + baseHotSQL + `) DESC, memes.id DESC`, nil
+	case "top":
+		return ` ORDER BY COALESCE(meme_stats.upvotes - meme_stats.downvotes, 0) DESC, COALESCE(meme_stats.copies, 0) DESC, memes.id DESC`, nil
+	}
+	return "", ErrInvalidSort
+}
+
 func SearchMemes(query string, mode string) ([]Meme, error) {
-	// [修正] SQL 加入 OR url LIKE ? 支援內文搜尋
-	baseSQL := `SELECT title, url, tags, source_url FROM memes WHERE (title LIKE ? OR tags LIKE ? OR url LIKE ?)`
+	return SearchMemesWithOptions(SearchOptions{Query: query, Mode: mode})
+}
 
-	filterSQL := ""
-	if mode == "image" {
-		filterSQL = ` AND source_url LIKE 'https://www.gif-vif.com/gifs%'`
-	} else if mode == "text" {
-		filterSQL = ` AND source_url NOT LIKE 'https://www.gif-vif.com/gifs/%'`
+func SearchMemesWithOptions(opts SearchOptions) ([]Meme, error) {
+	orderSQL, err := sortSQL(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Limit <= 0 || opts.Limit > 50 {
+		opts.Limit = 50
+	}
+
+	terms := append([]string{opts.Query}, opts.Terms...)
+	conditions := make([]string, len(terms))
+	var args []any
+	for i, term := range terms {
+		conditions[i] = textMatchSQL
+		likeQuery := "%" + term + "%"
+		// [修正] 每個 LIKE 條件各傳入一次 likeQuery
+		args = append(args, likeQuery, likeQuery, likeQuery, likeQuery, likeQuery)
 	}
 
-	finalSQL := baseSQL + filterSQL + ` ORDER BY id DESC LIMIT 50`
+	baseSQL := `SELECT ` + prefixedMemeColumns + ` FROM memes LEFT JOIN meme_stats ON meme_stats.meme_id = memes.id
+		WHERE ` + visibleSQL + aliveSQL + ` AND (` + strings.Join(conditions, ` OR `) + `)`
 
-	likeQuery := "%" + query + "%"
+	finalSQL := baseSQL + opts.FilterSQL + modeFilterSQL(opts.Mode) + orderSQL + ` LIMIT ? OFFSET ?`
+	args = append(args, opts.FilterArgs...)
 
-	// [修正] 傳入三次 likeQuery
-	rows, err := db.Query(finalSQL, likeQuery, likeQuery, likeQuery)
+	rows, err := db.Query(finalSQL, append(args, opts.Limit, max(opts.Offset, 0))...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	highlightTerms := append(append([]string{}, terms...), opts.HighlightTerms...)
 	memes := []Meme{}
 	for rows.Next() {
-		var m Meme
-		if err := rows.Scan(&m.Title, &m.URL, &m.Tags, &m.SourceURL); err != nil {
+		m, err := scanMeme(rows)
+		if err != nil {
 			log.Printf("讀取資料列失敗: %v", err)
 			continue
 		}
+		if len(opts.Terms) > 0 {
+			m.MatchedTerms = matchedTerms(m, terms)
+		}
+		m.Snippet, m.Highlights = BuildSnippet(snippetSource(m), highlightTerms)
 		memes = append(memes, m)
 	}
 	return memes, nil
 }
 
+// snippetSource 是要擷取摘要的文字：文字梗的內容放在 url 欄位，GIF 則使用描述
+func snippetSource(m Meme) string {
+	if isImageURL(m.URL) || isVideoURL(m.URL) {
+		return m.Description
+	}
+	return m.URL
+}
+
+// matchedTerms 回傳實際命中這筆資料的關鍵字，讓使用者知道為什麼會搜到
+func matchedTerms(m Meme, terms []string) []string {
+	haystack := strings.ToLower(strings.Join([]string{m.Title, m.Tags, m.URL, m.Description, m.Category}, "\n"))
+	var matched []string
+	for _, term := range terms {
+		if strings.Contains(haystack, strings.ToLower(term)) {
+			matched = append(matched, term)
+		}
+	}
+	return matched
+}
+
 func GetRandomMeme(mode string) (Meme, error) {
-	var m Meme
-	sqlQuery := `SELECT title, url, tags, source_url FROM memes`
+	memes, err := GetRandomMemes(RandomOptions{Mode: mode, Count: 1})
+	if err != nil {
+		return Meme{}, err
+	}
+	if len(memes) == 0 {
+		return Meme{}, ErrNotFound
+	}
+	return memes[0], nil
+}
+
+// RandomOptions 是隨機抽取的參數
+type RandomOptions struct {
+	Mode    string
+	Count   int        // 要抽幾筆 (彼此不重複)
+	Rand    *rand.Rand // 指定亂數來源可得到可重現的結果 (例如每日一梗)，nil 時使用全域亂數
+	Exclude []int64    // 不要抽到的 id (例如同一個使用者剛看過的)
+}
+
+// exactProbes 是先嘗試「剛好命中」的次數，之後才退回以筆數抽取
+const exactProbes = 3
+
+// GetRandomMemes 以 id 區間抽樣取代 ORDER BY RANDOM()，不需要每次排序整張表。
+// 先在 [MIN(id), MAX(id)] 之間取亂數，剛好命中符合條件的 id 就採用 (每筆機率相同)；
+// 落在空洞 (被刪除或不符模式的 id) 時重試幾次，仍落空就計算符合條件的筆數，以 OFFSET 取第 k 筆。
+// 不能改取「下一個存在的 id」：前面空洞越大的資料越容易被抽中，條件很稀疏時幾乎每次都是同一筆
+func GetRandomMemes(opts RandomOptions) ([]Meme, error) {
+	if db == nil {
+		return nil, fmt.Errorf("資料庫未連線")
+	}
+	if opts.Count <= 0 {
+		opts.Count = 1
+	}
+	intn := rand.Int63n
+	if opts.Rand != nil {
+		intn = opts.Rand.Int63n
+	}
+
+	var minID, maxID sql.NullInt64
+	if err := db.QueryRow(`SELECT MIN(id), MAX(id) FROM memes`).Scan(&minID, &maxID); err != nil {
+		return nil, err
+	}
+	if !minID.Valid {
+		return []Meme{}, nil
+	}
+
+	excluded := append([]int64{}, opts.Exclude...)
+	memes := []Meme{}
+	for len(memes) < opts.Count {
+		filter := ` AND ` + visibleSQL + aliveSQL + modeFilterSQL(opts.Mode) + notInSQL(len(excluded))
+		args := func(id int64) []any {
+			a := []any{id}
+			for _, e := range excluded {
+				a = append(a, e)
+			}
+			return a
+		}
 
-	whereClause := ""
-	if mode == "image" {
-		whereClause = ` WHERE source_url LIKE 'https://www.gif-vif.com/gifs%'`
-	} else if mode == "text" {
-		whereClause = ` WHERE source_url NOT LIKE 'https://www.gif-vif.com/gifs%'`
+		var m Meme
+		err := sql.ErrNoRows
+		for i := 0; i < exactProbes && err == sql.ErrNoRows; i++ {
+			target := minID.Int64 + intn(maxID.Int64-minID.Int64+1)
+			m, err = scanMeme(db.QueryRow(`SELECT `+memeColumns+` FROM memes WHERE id = ?`+filter, args(target)...))
+		}
+		if err == sql.ErrNoRows {
+			// 條件裡的 id 一律大於 0，用 id > 0 讓兩個查詢可以共用 filter 與參數
+			var n int64
+			if err := db.QueryRow(`SELECT COUNT(*) FROM memes WHERE id > ?`+filter, args(0)...).Scan(&n); err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				a := append(args(0), intn(n))
+				m, err = scanMeme(db.QueryRow(`SELECT `+memeColumns+` FROM memes WHERE id > ?`+filter+` ORDER BY id LIMIT 1 OFFSET ?`, a...))
+			}
+		}
+		if err == sql.ErrNoRows {
+			break // 符合條件的資料已經全部抽完
+		}
+		if err != nil {
+			return nil, err
+		}
+		memes = append(memes, m)
+		excluded = append(excluded, m.ID)
 	}
+	return memes, nil
+}
 
-	sqlQuery += whereClause + ` ORDER BY RANDOM() LIMIT 1`
+// notInSQL 產生 AND id NOT IN (?, ?, ...)，n 為 0 時回傳空字串
+func notInSQL(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return ` AND id NOT IN (?` + strings.Repeat(`, ?`, n-1) + `)`
+}
 
-	err := db.QueryRow(sqlQuery).Scan(&m.Title, &m.URL, &m.Tags, &m.SourceURL)
+// GetMemeByID 依 id 取得單筆資料，供詳細頁與分享連結使用
+func GetMemeByID(id int64) (Meme, error) {
+	if db == nil {
+		return Meme{}, fmt.Errorf("資料庫未連線")
+	}
+	m, err := scanMeme(db.QueryRow(`SELECT `+memeColumns+` FROM memes WHERE id = ? AND `+visibleSQL, id))
 	if err == sql.ErrNoRows {
-		return Meme{}, fmt.Errorf("找不到資料")
+		return Meme{}, ErrNotFound
 	}
 	return m, err
 }
+
+// LatestMemeID 回傳目前最大的 id，沒有資料時為 0
+func LatestMemeID() (int64, error) {
+	var id int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM memes`).Scan(&id)
+	return id, err
+}
+
+// ListMemesAfter 依寫入順序列出 id 大於 afterID 的資料 (gRPC 的 WatchNewMemes 用)
+func ListMemesAfter(afterID int64, mode string, limit int) ([]Meme, error) {
+	if db == nil {
+		return nil, fmt.Errorf("資料庫未連線")
+	}
+	return queryMemes(`SELECT `+memeColumns+` FROM memes WHERE id > ? AND `+visibleSQL+aliveSQL+modeFilterSQL(mode)+`
+		ORDER BY id LIMIT ?`, afterID, limit)
+}
+
+// queryMemes 執行回傳 memeColumns 的查詢 (欄位需加上 memes. 前綴避免 JOIN 時衝突)
+func queryMemes(query string, args ...any) ([]Meme, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memes := []Meme{}
+	for rows.Next() {
+		m, err := scanMeme(rows)
+		if err != nil {
+			return nil, err
+		}
+		memes = append(memes, m)
+	}
+	return memes, rows.Err()
+}
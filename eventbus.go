@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// =========================================================
+// [新資料事件]
+// =========================================================
+
+// MemeBus 把新寫入的資料廣播給訂閱者 (SSE 串流)。只在同一個程序內有效，
+// 爬蟲是另一個程序，伺服器端由 startMemeFeed 定期讀取資料庫補發
+type MemeBus struct {
+	mu     sync.Mutex
+	subs   map[chan Meme]struct{}
+	lastID int64 // 已發布的最大 id，同一筆資料不會發布兩次
+}
+
+func NewMemeBus() *MemeBus {
+	return &MemeBus{subs: map[chan Meme]struct{}{}}
+}
+
+// memeEvents 是 InsertMeme 發布事件的對象
+var memeEvents = NewMemeBus()
+
+// memeEdits 是已存在的資料被修改的次數 (後台編輯、下架/復原、連結失效隱藏)。
+// 新資料由 memeEvents 通知，兩者合起來讓自動完成之類的快取知道何時要重建，不必每次查詢資料庫
+var memeEdits atomic.Int64
+
+// Subscribe 回傳接收事件的 channel 與取消訂閱的函式，buffer 滿了之後的事件會被丟棄
+func (b *MemeBus) Subscribe(buffer int) (<-chan Meme, func()) {
+	ch := make(chan Meme, buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish 發布一筆新資料，id 不大於已發布過的會被忽略。不會等待處理較慢的訂閱者
+func (b *MemeBus) Publish(m Meme) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if m.ID <= b.lastID {
+		return
+	}
+	b.lastID = m.ID
+	for ch := range b.subs {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// LastID 回傳已發布的最大 id
+func (b *MemeBus) LastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Reset 把已發布的位置設回 id，爬蟲重建資料庫後 id 會從頭開始
+func (b *MemeBus) Reset(id int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID = id
+}
@@ -0,0 +1,743 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// =========================================================
+// [GraphQL：/graphql]
+// =========================================================
+// 給內部儀表板一次取得資料、標籤、來源、統計與爬蟲紀錄。
+// 搜尋和 /api/search 走同一個 runSearch，同義詞、搜尋語法與搜尋分析都一樣
+
+const graphqlMaxFirst = searchPageSize
+const graphqlMaxBody = 64 << 10
+const graphqlMaxDepth = 8
+const graphqlMaxAliases = 20 // 別名可以讓同一個欄位在一次請求裡重複執行很多次
+
+// 查詢成本：只有會打到資料庫的欄位才計算，同一個請求裡重複的查詢走快取不再計算。
+// 一次請求的總和超過 graphqlMaxCost 時，之後的欄位回傳錯誤
+const (
+	graphqlMaxCost    = 500
+	graphqlCostSearch = 20 // 搜尋與依標籤列出 (全文檢索)
+	graphqlCostList   = 10 // 隨機抽取、標籤與來源統計
+	graphqlCostLookup = 1  // 單筆查詢 (資料、統計、標籤次數、爬蟲紀錄)
+)
+
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+enum Mode { ALL IMAGE TEXT }
+enum Sort { NEW HOT TOP }
+
+type Query {
+	# 搜尋，query 支援搜尋語法 (title:、tag:、"完整片語"…) 與同義詞，first 最多 20
+	search(query: String!, mode: Mode = ALL, sort: Sort = NEW, first: Int = 20, after: String): MemeConnection!
+	# 隨機抽取，count 最多 20
+	random(mode: Mode = ALL, count: Int = 1, seed: String): [Meme!]!
+	meme(id: ID!): Meme
+	# 標籤依使用次數排序
+	tags(prefix: String = "", first: Int = 20): [Tag!]!
+	sources: [Source!]!
+	crawlRuns(source: String = "", first: Int = 20): [CrawlRun!]!
+}
+
+type Meme {
+	id: ID!
+	title: String!
+	url: String!
+	tags: [Tag!]!
+	source: Source!
+	sourceUrl: String!
+	author: String
+	mediaUrl: String
+	thumbnailUrl: String
+	previewUrl: String
+	description: String
+	category: String
+	uploadedAt: String
+	sourceViews: Int!
+	permalink: String!
+	stats: Stats!
+	# 以下只有搜尋結果才有
+	matchedTerms: [String!]!
+	snippet: String
+	highlights: [Highlight!]!
+}
+
+type Highlight {
+	start: Int!
+	end: Int!
+}
+
+type Stats {
+	upvotes: Int!
+	downvotes: Int!
+	score: Int!
+	views: Int!
+	copies: Int!
+	hot: Float!
+}
+
+type Tag {
+	name: String!
+	memeCount: Int!
+	memes(first: Int = 20): [Meme!]!
+}
+
+type Source {
+	name: String!
+	memeCount: Int!
+	lastCrawl: CrawlRun
+	crawlRuns(first: Int = 5): [CrawlRun!]!
+}
+
+type CrawlRun {
+	id: ID!
+	source: String!
+	status: String!
+	startedAt: String!
+	finishedAt: String
+	found: Int!
+	saved: Int!
+	errors: Int!
+	message: String
+}
+
+type MemeConnection {
+	edges: [MemeEdge!]!
+	pageInfo: PageInfo!
+	expansions: [String!]!
+	searchId: ID
+}
+
+type MemeEdge {
+	cursor: String!
+	node: Meme!
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+`
+
+// newGraphQLSchema 以同一份同義詞與標籤索引建立 schema
+func newGraphQLSchema(syn *synonymIndex, tags *suggestIndex) *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &graphqlResolver{syn: syn, tagIndex: tags},
+		graphql.MaxDepth(graphqlMaxDepth), graphql.MaxQueryLength(graphqlMaxBody))
+}
+
+// ginContextKey 讓 resolver 取回 gin.Context (搜尋分析需要使用者代號)
+type ginContextKey struct{}
+
+// graphqlRequestKey 讓 resolver 取回這個請求的 graphqlRequest
+type graphqlRequestKey struct{}
+
+// graphqlRequest 是單一請求內共用的成本計算與快取，
+// 避免巢狀查詢 (例如 tags{memes{tags{memes{stats}}}}) 放大成成千上萬次資料庫查詢
+type graphqlRequest struct {
+	cost atomic.Int64
+
+	mu       sync.Mutex
+	stats    map[int64]MemeStats
+	tagUsage map[string]int
+	tagMemes map[string][]Meme
+
+	sourcesOnce sync.Once
+	sources     map[string]int
+	sourcesErr  error
+}
+
+func newGraphQLRequest() *graphqlRequest {
+	return &graphqlRequest{stats: map[int64]MemeStats{}, tagUsage: map[string]int{}, tagMemes: map[string][]Meme{}}
+}
+
+// graphqlRequestFrom 取回請求狀態；直接呼叫 schema 時 (沒有經過 graphqlHandler) 每次都是新的
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	if q, ok := ctx.Value(graphqlRequestKey{}).(*graphqlRequest); ok {
+		return q
+	}
+	return newGraphQLRequest()
+}
+
+// charge 累加成本，超過上限時回傳錯誤
+func (q *graphqlRequest) charge(cost int) error {
+	if q.cost.Add(int64(cost)) > graphqlMaxCost {
+		return fmt.Errorf("查詢太複雜 (成本上限 %d)，請減少巢狀欄位或筆數", graphqlMaxCost)
+	}
+	return nil
+}
+
+// memeStats 回傳快取的統計，沒有時才查詢並計算成本
+func (q *graphqlRequest) memeStats(id int64) (MemeStats, error) {
+	q.mu.Lock()
+	s, ok := q.stats[id]
+	q.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+	if err := q.charge(graphqlCostLookup); err != nil {
+		return MemeStats{}, err
+	}
+	s, err := GetMemeStats(id)
+	if err != nil {
+		return MemeStats{}, err
+	}
+	q.mu.Lock()
+	q.stats[id] = s
+	q.mu.Unlock()
+	return s, nil
+}
+
+func (q *graphqlRequest) tagCount(tags *suggestIndex, name string) (int, error) {
+	q.mu.Lock()
+	n, ok := q.tagUsage[name]
+	q.mu.Unlock()
+	if ok {
+		return n, nil
+	}
+	if err := q.charge(graphqlCostLookup); err != nil {
+		return 0, err
+	}
+	n, err := tags.TagUsage(name)
+	if err != nil {
+		return 0, err
+	}
+	q.mu.Lock()
+	q.tagUsage[name] = n
+	q.mu.Unlock()
+	return n, nil
+}
+
+// memesByTag 列出使用某個標籤的資料，同一個請求內相同的標籤與筆數只查一次
+func (q *graphqlRequest) memesByTag(name string, first int) ([]Meme, error) {
+	key := name + "|" + strconv.Itoa(first)
+	q.mu.Lock()
+	memes, ok := q.tagMemes[key]
+	q.mu.Unlock()
+	if ok {
+		return memes, nil
+	}
+	if err := q.charge(graphqlCostSearch); err != nil {
+		return nil, err
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(name)
+	memes, err := SearchMemesWithOptions(SearchOptions{
+		Mode: "all", Limit: first, FilterSQL: tagFilterSQL, FilterArgs: []any{"%," + escaped + ",%"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	q.tagMemes[key] = memes
+	q.mu.Unlock()
+	return memes, nil
+}
+
+// sourceCounts 每個請求只做一次依來源分組的統計
+func (q *graphqlRequest) sourceCounts() (map[string]int, error) {
+	q.sourcesOnce.Do(func() {
+		if q.sourcesErr = q.charge(graphqlCostList); q.sourcesErr != nil {
+			return
+		}
+		counts, err := CountMemesBySource()
+		if err != nil {
+			q.sourcesErr = err
+			return
+		}
+		q.sources = map[string]int{}
+		for _, s := range counts {
+			q.sources[s.Name] = s.Count
+		}
+	})
+	return q.sources, q.sourcesErr
+}
+
+// countAliases 計算查詢中的別名數，也就是選擇集裡、括號外的「名稱:」(括號內是參數與變數定義)，略過字串與註解
+func countAliases(query string) int {
+	braces, parens, n := 0, 0, 0
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case '"':
+			if strings.HasPrefix(query[i:], `"""`) {
+				end := strings.Index(query[i+3:], `"""`)
+				if end < 0 {
+					return n
+				}
+				i += end + 5
+				continue
+			}
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			braces++
+		case '}':
+			braces--
+		case '(':
+			parens++
+		case ')':
+			parens--
+		case ':':
+			if braces > 0 && parens == 0 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// graphqlHandler 處理 POST {"query", "operationName", "variables"}，也接受 GET ?query=
+func graphqlHandler(schema *graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Query         string         `json:"query"`
+			OperationName string         `json:"operationName"`
+			Variables     map[string]any `json:"variables"`
+		}
+		if c.Request.Method == http.MethodGet {
+			req.Query, req.OperationName = c.Query("query"), c.Query("operationName")
+			if v := c.Query("variables"); v != "" {
+				if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+					graphqlError(c, http.StatusBadRequest, "variables 不是合法的 JSON")
+					return
+				}
+			}
+		} else {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, graphqlMaxBody)
+			if err := c.ShouldBindJSON(&req); err != nil {
+				graphqlError(c, http.StatusBadRequest, fmt.Sprintf("資料格式錯誤: %v", err))
+				return
+			}
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			graphqlError(c, http.StatusBadRequest, "缺少 query")
+			return
+		}
+		if countAliases(req.Query) > graphqlMaxAliases {
+			graphqlError(c, http.StatusBadRequest, fmt.Sprintf("別名最多 %d 個", graphqlMaxAliases))
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), ginContextKey{}, c)
+		ctx = context.WithValue(ctx, graphqlRequestKey{}, newGraphQLRequest())
+		c.JSON(http.StatusOK, schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+}
+
+// graphqlError 以 GraphQL 的格式回傳請求本身的錯誤
+func graphqlError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{"errors": []gin.H{{"message": message}}})
+}
+
+// ---------------------------------------------------------
+// Query
+// ---------------------------------------------------------
+
+type graphqlResolver struct {
+	syn      *synonymIndex
+	tagIndex *suggestIndex
+}
+
+func checkFirst(first int32) error {
+	if first < 1 || first > graphqlMaxFirst {
+		return fmt.Errorf("first 需介於 1 到 %d", graphqlMaxFirst)
+	}
+	return nil
+}
+
+// 游標只是位移量，編碼起來避免使用者把它當成穩定的 id
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	rest, ok := strings.CutPrefix(string(raw), "offset:")
+	n, convErr := strconv.Atoi(rest)
+	if err != nil || !ok || convErr != nil || n < 0 {
+		return 0, errors.New("after 游標無效")
+	}
+	return n, nil
+}
+
+func (r *graphqlResolver) Search(ctx context.Context, args struct {
+	Query string
+	Mode  string
+	Sort  string
+	First int32
+	After *string
+}) (*memeConnectionResolver, error) {
+	c, ok := ctx.Value(ginContextKey{}).(*gin.Context)
+	if !ok {
+		return nil, errors.New("缺少請求內容")
+	}
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	offset := 0
+	if args.After != nil {
+		n, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		offset = n + 1
+	}
+	if offset > v1MaxOffset {
+		return nil, fmt.Errorf("最多只能翻到第 %d 筆", v1MaxOffset)
+	}
+	if err := graphqlRequestFrom(ctx).charge(graphqlCostSearch); err != nil {
+		return nil, err
+	}
+
+	// 多查一筆，用來判斷是否還有下一頁
+	first := int(args.First)
+	res, err := runSearch(r.syn, searchRequest{
+		Query: args.Query, Mode: strings.ToLower(args.Mode), Sort: strings.ToLower(args.Sort),
+		Limit: first + 1, Offset: offset, Client: anonymousClient(c),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &memeConnectionResolver{expansions: nonNil(res.Expansions), searchID: res.SearchID}
+	memes := res.Memes
+	if len(memes) > first {
+		memes, conn.hasNext = memes[:first], true
+	}
+	for i, m := range memes {
+		conn.edges = append(conn.edges, &memeEdgeResolver{cursor: encodeCursor(offset + i), node: r.meme(m)})
+	}
+	return conn, nil
+}
+
+func (r *graphqlResolver) Random(ctx context.Context, args struct {
+	Mode  string
+	Count int32
+	Seed  *string
+}) ([]*memeResolver, error) {
+	if args.Count < 1 || args.Count > maxRandomCount {
+		return nil, fmt.Errorf("count 需介於 1 到 %d", maxRandomCount)
+	}
+	if err := graphqlRequestFrom(ctx).charge(graphqlCostList); err != nil {
+		return nil, err
+	}
+	mode := strings.ToLower(args.Mode)
+	opts := RandomOptions{Mode: mode, Count: int(args.Count)}
+	if args.Seed != nil && *args.Seed != "" {
+		opts.Rand = seededRand(*args.Seed + "|" + mode)
+	}
+	memes, err := GetRandomMemes(opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.memes(memes), nil
+}
+
+func (r *graphqlResolver) Meme(ctx context.Context, args struct{ ID graphql.ID }) (*memeResolver, error) {
+	id, err := strconv.ParseInt(string(args.ID), 10, 64)
+	if err != nil || id <= 0 {
+		return nil, errors.New("id 需為正整數")
+	}
+	if err := graphqlRequestFrom(ctx).charge(graphqlCostLookup); err != nil {
+		return nil, err
+	}
+	m, err := GetMemeByID(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.meme(m), nil
+}
+
+func (r *graphqlResolver) Tags(ctx context.Context, args struct {
+	Prefix string
+	First  int32
+}) ([]*tagResolver, error) {
+	if args.First < 1 || args.First > maxSuggestions*5 {
+		return nil, fmt.Errorf("first 需介於 1 到 %d", maxSuggestions*5)
+	}
+	if err := graphqlRequestFrom(ctx).charge(graphqlCostList); err != nil {
+		return nil, err
+	}
+	tags, err := r.tagIndex.Tags(strings.TrimSpace(args.Prefix), int(args.First))
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*tagResolver, len(tags))
+	for i, t := range tags {
+		count := t.Count
+		resolvers[i] = &tagResolver{root: r, name: t.Name, count: &count}
+	}
+	return resolvers, nil
+}
+
+func (r *graphqlResolver) Sources(ctx context.Context) ([]*sourceResolver, error) {
+	if err := graphqlRequestFrom(ctx).charge(graphqlCostList); err != nil {
+		return nil, err
+	}
+	counts, err := CountMemesBySource()
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*sourceResolver, len(counts))
+	for i, s := range counts {
+		count := s.Count
+		resolvers[i] = &sourceResolver{name: s.Name, count: &count}
+	}
+	return resolvers, nil
+}
+
+func (r *graphqlResolver) CrawlRuns(ctx context.Context, args struct {
+	Source string
+	First  int32
+}) ([]*crawlRunResolver, error) {
+	return crawlRuns(ctx, args.Source, args.First)
+}
+
+func crawlRuns(ctx context.Context, source string, first int32) ([]*crawlRunResolver, error) {
+	if first < 1 || first > 100 {
+		return nil, errors.New("first 需介於 1 到 100")
+	}
+	if err := graphqlRequestFrom(ctx).charge(graphqlCostLookup); err != nil {
+		return nil, err
+	}
+	runs, err := ListCrawlRuns(source, int(first))
+	if err != nil {
+		return nil, err
+	}
+	resolvers := make([]*crawlRunResolver, len(runs))
+	for i, run := range runs {
+		resolvers[i] = &crawlRunResolver{run}
+	}
+	return resolvers, nil
+}
+
+func (r *graphqlResolver) meme(m Meme) *memeResolver {
+	return &memeResolver{root: r, m: m}
+}
+
+func (r *graphqlResolver) memes(memes []Meme) []*memeResolver {
+	resolvers := make([]*memeResolver, len(memes))
+	for i, m := range memes {
+		resolvers[i] = r.meme(m)
+	}
+	return resolvers
+}
+
+// ---------------------------------------------------------
+// 型別
+// ---------------------------------------------------------
+
+// optionalString 讓空字串輸出成 null
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type memeResolver struct {
+	root *graphqlResolver
+	m    Meme
+}
+
+func (r *memeResolver) ID() graphql.ID        { return graphql.ID(strconv.FormatInt(r.m.ID, 10)) }
+func (r *memeResolver) Title() string         { return r.m.Title }
+func (r *memeResolver) URL() string           { return r.m.URL }
+func (r *memeResolver) SourceURL() string     { return r.m.SourceURL }
+func (r *memeResolver) Author() *string       { return optionalString(r.m.Author) }
+func (r *memeResolver) MediaURL() *string     { return optionalString(r.m.MediaURL) }
+func (r *memeResolver) ThumbnailURL() *string { return optionalString(r.m.ThumbnailURL) }
+func (r *memeResolver) PreviewURL() *string   { return optionalString(r.m.PreviewURL) }
+func (r *memeResolver) Description() *string  { return optionalString(r.m.Description) }
+func (r *memeResolver) Category() *string     { return optionalString(r.m.Category) }
+func (r *memeResolver) UploadedAt() *string   { return optionalString(r.m.UploadedAt) }
+func (r *memeResolver) SourceViews() int32    { return int32(min(r.m.SourceViews, 1<<31-1)) }
+func (r *memeResolver) Permalink() string     { return "/m/" + strconv.FormatInt(r.m.ID, 10) }
+func (r *memeResolver) MatchedTerms() []string {
+	return nonNil(r.m.MatchedTerms)
+}
+func (r *memeResolver) Snippet() *string { return optionalString(r.m.Snippet) }
+
+func (r *memeResolver) Highlights() []*highlightResolver {
+	resolvers := make([]*highlightResolver, len(r.m.Highlights))
+	for i, h := range r.m.Highlights {
+		resolvers[i] = &highlightResolver{h}
+	}
+	return resolvers
+}
+
+// Tags 拆開逗號分隔的標籤，使用次數在需要時才查
+func (r *memeResolver) Tags() []*tagResolver {
+	resolvers := []*tagResolver{}
+	for _, tag := range strings.Split(r.m.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			resolvers = append(resolvers, &tagResolver{root: r.root, name: tag})
+		}
+	}
+	return resolvers
+}
+
+func (r *memeResolver) Source() *sourceResolver {
+	return &sourceResolver{name: sourceName(r.m.SourceURL)}
+}
+
+func (r *memeResolver) Stats(ctx context.Context) (*statsResolver, error) {
+	s, err := graphqlRequestFrom(ctx).memeStats(r.m.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &statsResolver{s}, nil
+}
+
+type highlightResolver struct{ h Highlight }
+
+func (r *highlightResolver) Start() int32 { return int32(r.h.Start) }
+func (r *highlightResolver) End() int32   { return int32(r.h.End) }
+
+type statsResolver struct{ s MemeStats }
+
+func (r *statsResolver) Upvotes() int32   { return int32(r.s.Upvotes) }
+func (r *statsResolver) Downvotes() int32 { return int32(r.s.Downvotes) }
+func (r *statsResolver) Score() int32     { return int32(r.s.Score) }
+func (r *statsResolver) Views() int32     { return int32(r.s.Views) }
+func (r *statsResolver) Copies() int32    { return int32(r.s.Copies) }
+func (r *statsResolver) Hot() float64     { return r.s.Hot }
+
+type tagResolver struct {
+	root  *graphqlResolver
+	name  string
+	count *int // 已知時不必再查
+}
+
+func (r *tagResolver) Name() string { return r.name }
+
+func (r *tagResolver) MemeCount(ctx context.Context) (int32, error) {
+	if r.count != nil {
+		return int32(*r.count), nil
+	}
+	n, err := graphqlRequestFrom(ctx).tagCount(r.root.tagIndex, r.name)
+	return int32(n), err
+}
+
+// tagFilterSQL 比對完整的標籤 (逗號分隔，不分大小寫)，不會因為「貓」而找到「貓咪」
+const tagFilterSQL = ` AND (',' || REPLACE(tags, ', ', ',') || ',') LIKE ? ESCAPE '\'`
+
+func (r *tagResolver) Memes(ctx context.Context, args struct{ First int32 }) ([]*memeResolver, error) {
+	if err := checkFirst(args.First); err != nil {
+		return nil, err
+	}
+	memes, err := graphqlRequestFrom(ctx).memesByTag(r.name, int(args.First))
+	if err != nil {
+		return nil, err
+	}
+	return r.root.memes(memes), nil
+}
+
+type sourceResolver struct {
+	name  string
+	count *int
+}
+
+func (r *sourceResolver) Name() string { return r.name }
+
+func (r *sourceResolver) MemeCount(ctx context.Context) (int32, error) {
+	if r.count != nil {
+		return int32(*r.count), nil
+	}
+	counts, err := graphqlRequestFrom(ctx).sourceCounts()
+	return int32(counts[r.name]), err
+}
+
+func (r *sourceResolver) LastCrawl(ctx context.Context) (*crawlRunResolver, error) {
+	runs, err := crawlRuns(ctx, r.name, 1)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return runs[0], nil
+}
+
+func (r *sourceResolver) CrawlRuns(ctx context.Context, args struct{ First int32 }) ([]*crawlRunResolver, error) {
+	return crawlRuns(ctx, r.name, args.First)
+}
+
+type crawlRunResolver struct{ run CrawlRun }
+
+func (r *crawlRunResolver) ID() graphql.ID      { return graphql.ID(strconv.FormatInt(r.run.ID, 10)) }
+func (r *crawlRunResolver) Source() string      { return r.run.Source }
+func (r *crawlRunResolver) Status() string      { return r.run.Status }
+func (r *crawlRunResolver) StartedAt() string   { return r.run.StartedAt }
+func (r *crawlRunResolver) FinishedAt() *string { return optionalString(r.run.FinishedAt) }
+func (r *crawlRunResolver) Found() int32        { return int32(r.run.Found) }
+func (r *crawlRunResolver) Saved() int32        { return int32(r.run.Saved) }
+func (r *crawlRunResolver) Errors() int32       { return int32(r.run.Errors) }
+func (r *crawlRunResolver) Message() *string    { return optionalString(r.run.Message) }
+
+type memeConnectionResolver struct {
+	edges      []*memeEdgeResolver
+	hasNext    bool
+	expansions []string
+	searchID   int64
+}
+
+func (r *memeConnectionResolver) Edges() []*memeEdgeResolver {
+	return nonNil(r.edges)
+}
+
+func (r *memeConnectionResolver) PageInfo() *pageInfoResolver {
+	p := &pageInfoResolver{hasNext: r.hasNext}
+	if len(r.edges) > 0 {
+		p.endCursor = &r.edges[len(r.edges)-1].cursor
+	}
+	return p
+}
+
+func (r *memeConnectionResolver) Expansions() []string { return r.expansions }
+
+func (r *memeConnectionResolver) SearchID() *graphql.ID {
+	if r.searchID == 0 {
+		return nil
+	}
+	id := graphql.ID(strconv.FormatInt(r.searchID, 10))
+	return &id
+}
+
+type memeEdgeResolver struct {
+	cursor string
+	node   *memeResolver
+}
+
+func (r *memeEdgeResolver) Cursor() string      { return r.cursor }
+func (r *memeEdgeResolver) Node() *memeResolver { return r.node }
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool  { return r.hasNext }
+func (r *pageInfoResolver) EndCursor() *string { return r.endCursor }
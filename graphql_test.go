@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// graphqlResponse 是測試用的回應格式
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func doGraphQL(t *testing.T, r http.Handler, query string, variables map[string]any) graphqlResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	w := doJSON(r, "POST", "/graphql", string(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GraphQL 應回傳 200，得到 %d: %s", w.Code, w.Body.String())
+	}
+	var res graphqlResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("回應不是 JSON: %v", err)
+	}
+	return res
+}
+
+func TestGraphQLSearchAndPagination(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "貓咪跳舞", URL: "https://example.com/1.gif", Tags: "cat, 跳舞", SourceURL: "https://www.gif-vif.com/gifs/1"},
+		ExportMeme{Title: "狗狗跳舞", URL: "https://example.com/2.gif", Tags: "dog, 跳舞", SourceURL: "https://www.gif-vif.com/gifs/2"},
+		ExportMeme{Title: "跳舞複製文", URL: "今天也要跳舞", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html", Author: "someone"},
+	)
+
+	const query = `query($q: String!, $after: String) {
+		search(query: $q, first: 2, after: $after) {
+			edges { cursor node { id title author tags { name memeCount } source { name memeCount } stats { views } permalink } }
+			pageInfo { hasNextPage endCursor }
+			searchId
+		}
+	}`
+	res := doGraphQL(t, r, query, map[string]any{"q": "跳舞"})
+	if len(res.Errors) > 0 {
+		t.Fatalf("不應有錯誤: %+v", res.Errors)
+	}
+	var page struct {
+		Search struct {
+			Edges []struct {
+				Cursor string
+				Node   struct {
+					ID        string
+					Permalink string
+				}
+			}
+			PageInfo struct {
+				HasNextPage bool
+				EndCursor   string
+			}
+			SearchID *string
+		}
+	}
+	json.Unmarshal(res.Data, &page)
+	firstPage := res.Data
+	if len(page.Search.Edges) != 2 || !page.Search.PageInfo.HasNextPage || page.Search.SearchID == nil {
+		t.Fatalf("第一頁應有 2 筆且還有下一頁: %s", res.Data)
+	}
+	for _, e := range page.Search.Edges {
+		if e.Node.Permalink != "/m/"+e.Node.ID {
+			t.Errorf("永久連結錯誤: %s", e.Node.Permalink)
+		}
+	}
+
+	res = doGraphQL(t, r, query, map[string]any{"q": "跳舞", "after": page.Search.PageInfo.EndCursor})
+	var next struct {
+		Search struct {
+			Edges    []struct{ Node json.RawMessage }
+			PageInfo struct{ HasNextPage bool }
+			SearchID *string
+		}
+	}
+	json.Unmarshal(res.Data, &next)
+	if len(next.Search.Edges) != 1 || next.Search.PageInfo.HasNextPage {
+		t.Fatalf("第二頁應只有最後 1 筆: %s", res.Data)
+	}
+	if next.Search.SearchID != nil {
+		t.Errorf("翻頁不應重複記錄搜尋分析")
+	}
+
+	// 兩頁合起來檢查每一筆的標籤、來源與作者
+	nodes := []json.RawMessage{next.Search.Edges[0].Node}
+	var first struct {
+		Search struct {
+			Edges []struct{ Node json.RawMessage }
+		}
+	}
+	json.Unmarshal(firstPage, &first)
+	for _, e := range first.Search.Edges {
+		nodes = append(nodes, e.Node)
+	}
+	for _, raw := range nodes {
+		var n struct {
+			Title  string
+			Author *string
+			Tags   []struct {
+				Name      string
+				MemeCount int
+			}
+			Source struct {
+				Name      string
+				MemeCount int
+			}
+		}
+		json.Unmarshal(raw, &n)
+		if n.Source.Name == "ptt" {
+			if n.Author == nil || *n.Author != "someone" || n.Source.MemeCount != 1 {
+				t.Errorf("PTT 應有作者且來源有 1 筆: %s", raw)
+			}
+			continue
+		}
+		if n.Source.Name != "gif" || n.Source.MemeCount != 2 || n.Author != nil {
+			t.Errorf("來源應為 gif 且有 2 筆: %s", raw)
+		}
+		if len(n.Tags) != 2 || n.Tags[1].Name != "跳舞" || n.Tags[1].MemeCount != 2 {
+			t.Errorf("標籤應拆開並附上使用次數: %s", raw)
+		}
+	}
+
+	// 和 /api/search 使用同一套搜尋語法
+	res = doGraphQL(t, r, `{ search(query: "after:昨天") { edges { cursor } } }`, nil)
+	if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, "日期") {
+		t.Errorf("語法錯誤應回傳說明: %+v", res.Errors)
+	}
+	res = doGraphQL(t, r, `{ search(query: "", after: "亂寫") { edges { cursor } } }`, nil)
+	if len(res.Errors) == 0 {
+		t.Errorf("無效的游標應回傳錯誤")
+	}
+}
+
+func TestGraphQLMemeTagsAndSources(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "貓咪", URL: "https://example.com/1.gif", Tags: "cat, 貓咪", SourceURL: "https://www.gif-vif.com/gifs/1"},
+		ExportMeme{Title: "小貓", URL: "https://example.com/2.gif", Tags: "cat", SourceURL: "https://www.gif-vif.com/gifs/2"},
+		ExportMeme{Title: "catalog", URL: "https://example.com/3.gif", Tags: "catalog", SourceURL: "https://www.gif-vif.com/gifs/3"},
+		ExportMeme{Title: "複製文", URL: "一段很長的複製文", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html"},
+	)
+	doJSON(r, "POST", "/api/memes/1/vote", `{"value": 1}`)
+
+	res := doGraphQL(t, r, `{
+		meme(id: "1") { title stats { upvotes score } }
+		missing: meme(id: "999") { title }
+		tags(prefix: "cat") { name memeCount memes { title } }
+		sources { name memeCount }
+		random(mode: TEXT, count: 1) { title }
+	}`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("不應有錯誤: %+v", res.Errors)
+	}
+	var data struct {
+		Meme struct {
+			Title string
+			Stats struct{ Upvotes, Score int }
+		}
+		Missing *struct{}
+		Tags    []struct {
+			Name      string
+			MemeCount int
+			Memes     []struct{ Title string }
+		}
+		Sources []struct {
+			Name      string
+			MemeCount int
+		}
+		Random []struct{ Title string }
+	}
+	json.Unmarshal(res.Data, &data)
+
+	if data.Meme.Title != "貓咪" || data.Meme.Stats.Upvotes != 1 || data.Missing != nil {
+		t.Errorf("meme 查詢錯誤: %s", res.Data)
+	}
+	if len(data.Tags) != 2 || data.Tags[0].Name != "cat" || data.Tags[0].MemeCount != 2 {
+		t.Fatalf("標籤應依使用次數排序: %s", res.Data)
+	}
+	// 標籤比對完整的詞，不會把 catalog 算進 cat
+	if len(data.Tags[0].Memes) != 2 {
+		t.Errorf("cat 標籤應有 2 筆: %+v", data.Tags[0].Memes)
+	}
+	if len(data.Sources) != 2 || data.Sources[0].Name != "gif" || data.Sources[0].MemeCount != 3 {
+		t.Errorf("來源統計錯誤: %+v", data.Sources)
+	}
+	if len(data.Random) != 1 || data.Random[0].Title != "複製文" {
+		t.Errorf("隨機抽取應套用模式: %+v", data.Random)
+	}
+
+	// GET 也可以查詢
+	w := doRequest(r, "GET", "/graphql?query="+url.QueryEscape(`{ meme(id: "2") { title } }`))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "小貓") {
+		t.Errorf("GET 查詢失敗: %d %s", w.Code, w.Body.String())
+	}
+	if w := doJSON(r, "POST", "/graphql", `{"query": ""}`); w.Code != http.StatusBadRequest {
+		t.Errorf("缺少 query 應回傳 400，得到 %d", w.Code)
+	}
+}
+
+func TestGraphQLQueryCostLimits(t *testing.T) {
+	var memes []ExportMeme
+	for i := 0; i < 30; i++ {
+		memes = append(memes, ExportMeme{
+			Title: fmt.Sprintf("跳舞 %d", i), URL: fmt.Sprintf("https://example.com/%d.gif", i),
+			Tags: fmt.Sprintf("跳舞, t%d, u%d", i, i), SourceURL: fmt.Sprintf("https://www.gif-vif.com/gifs/%d", i),
+		})
+	}
+	r := setupTestServer(t, memes...)
+
+	// 相同的標籤與統計在同一個請求裡只查一次，正常的儀表板查詢不受影響
+	res := doGraphQL(t, r, `{ search(query: "跳舞", first: 20) { edges { node { tags { name memeCount } source { memeCount } stats { views } } } } }`, nil)
+	if len(res.Errors) > 0 {
+		t.Fatalf("不應有錯誤: %+v", res.Errors)
+	}
+
+	// 每一層都展開成更多查詢，超過成本上限
+	res = doGraphQL(t, r, `{ search(query: "跳舞", first: 20) { edges { node { tags { memes { tags { memes { id } } } } } } } }`, nil)
+	if len(res.Errors) == 0 || !strings.Contains(res.Errors[0].Message, "查詢太複雜") {
+		t.Errorf("應超過成本上限: %+v", res.Errors)
+	}
+
+	if n := countAliases(`query($q: String!) { a: meme(id: "1:2") { title } search(query: $q, after: "x:y") { pageInfo { hasNextPage } } } # b: c`); n != 1 {
+		t.Errorf("只有選擇集裡的別名要計算，得到 %d", n)
+	}
+	var many strings.Builder
+	many.WriteString("{")
+	for i := 0; i <= graphqlMaxAliases; i++ {
+		fmt.Fprintf(&many, ` m%d: meme(id: "1") { title }`, i)
+	}
+	many.WriteString("}")
+	body, _ := json.Marshal(map[string]any{"query": many.String()})
+	if w := doJSON(r, "POST", "/graphql", string(body)); w.Code != http.StatusBadRequest {
+		t.Errorf("別名過多應回傳 400，得到 %d", w.Code)
+	}
+}
+
+func TestCrawlRunsRecordedAndQueried(t *testing.T) {
+	r := setupTestServer(t)
+
+	old := StartCrawlRun("ptt")
+	old.Error(errors.New("逾時"))
+	old.Finish(errors.New("連不上"))
+
+	run := StartCrawlRun("ptt")
+	run.Found(nil)
+	run.Found(nil)
+	run.Found(errors.New("寫入失敗"))
+	run.Error(errors.New("https://www.ptt.cc/bbs/Joke/M.2.html: 404"))
+	finished := run.Finish(nil)
+	if finished.Status != CrawlOK || finished.Found != 3 || finished.Saved != 2 || finished.Errors != 2 {
+		t.Errorf("統計錯誤: %+v", finished)
+	}
+	if got, err := GetCrawlRun(finished.ID); err != nil || got.FinishedAt == "" || got.Saved != 2 {
+		t.Errorf("紀錄應寫入資料庫: %+v (%v)", got, err)
+	}
+
+	res := doGraphQL(t, r, `{
+		crawlRuns(source: "ptt") { status found saved errors message finishedAt }
+		sources { name lastCrawl { status } }
+	}`, nil)
+	var data struct {
+		CrawlRuns []struct {
+			Status     string
+			Found      int
+			Saved      int
+			Errors     int
+			Message    *string
+			FinishedAt *string
+		}
+	}
+	json.Unmarshal(res.Data, &data)
+	if len(data.CrawlRuns) != 2 {
+		t.Fatalf("應有 2 筆紀錄: %s", res.Data)
+	}
+	latest, first := data.CrawlRuns[0], data.CrawlRuns[1]
+	if latest.Status != "ok" || latest.Saved != 2 || latest.FinishedAt == nil {
+		t.Errorf("最新的紀錄應排在前面: %+v", latest)
+	}
+	if first.Status != "failed" || first.Message == nil || *first.Message != "連不上" {
+		t.Errorf("失敗的紀錄應保留原因: %+v", first)
+	}
+}
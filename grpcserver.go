@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gofinal/memepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// =========================================================
+// [gRPC 服務]
+// =========================================================
+
+// 與 HTTP 伺服器分開的連接埠，GRPC_ADDR=off 時不啟動
+const defaultGRPCAddr = ":9090"
+
+// watchBatchSize 是 WatchNewMemes 每次從資料庫取出的筆數上限
+const watchBatchSize = 100
+
+// memeService 實作 memepb.MemeServiceServer，和 HTTP API 共用同一套搜尋與資料存取
+type memeService struct {
+	memepb.UnimplementedMemeServiceServer
+	syn        *synonymIndex
+	maxStreams int64 // WatchNewMemes 同時連線數的上限 (GRPC_MAX_STREAMS，預設 100)
+	streams    atomic.Int64
+}
+
+// newGRPCServer 建立掛好 API key 驗證與限流的 gRPC 伺服器。
+// syn 與 limiter 需與 HTTP 共用：管理員修改同義詞才會同時生效，限流器的清理也由 main 統一啟動
+func newGRPCServer(syn *synonymIndex, limiter *RateLimiter) *grpc.Server {
+	auth := grpcAuth{cfg: rateLimitConfigFromEnv(), limiter: limiter}
+
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.unary),
+		grpc.StreamInterceptor(auth.stream),
+	)
+	memepb.RegisterMemeServiceServer(s, &memeService{syn: syn, maxStreams: int64(envInt("GRPC_MAX_STREAMS", 100))})
+	return s
+}
+
+// serveGRPC 在背景啟動 gRPC 伺服器，addr 為空時使用預設埠
+func serveGRPC(addr string, syn *synonymIndex, limiter *RateLimiter) {
+	if addr == "off" {
+		return
+	}
+	if addr == "" {
+		addr = defaultGRPCAddr
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("❌ gRPC 無法監聽 %s: %v", addr, err)
+	}
+	go func() {
+		if err := newGRPCServer(syn, limiter).Serve(lis); err != nil {
+			log.Printf("[gRPC] 伺服器停止: %v", err)
+		}
+	}()
+	log.Printf("🚀 gRPC 運行中: %s", lis.Addr())
+}
+
+// ---------------------------------------------------------
+// 驗證與限流 (規則同 apiKeyAuth，金鑰放在 x-api-key metadata)
+// ---------------------------------------------------------
+
+type grpcAuth struct {
+	cfg     RateLimitConfig
+	limiter *RateLimiter
+}
+
+// grpcClientKey 存放呼叫端的識別 (key:1 或 ip:...)，搜尋分析用
+type grpcClientKey struct{}
+
+// check 驗證金鑰並套用限流，回傳呼叫端的識別
+func (a grpcAuth) check(ctx context.Context) (string, error) {
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-api-key"); len(v) > 0 {
+			key = v[0]
+		}
+	}
+
+	if key == "" {
+		if a.cfg.RequireKey {
+			return "", status.Error(codes.Unauthenticated, "缺少 API key")
+		}
+		client := "ip:" + peerIPHash(ctx)
+		if ok, wait := a.limiter.Allow(client, a.cfg.IPPerMinute, a.cfg.IPBurst); !ok {
+			return "", rateLimited(wait)
+		}
+		return client, nil
+	}
+
+	k, err := LookupAPIKey(key)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	rate := k.RatePerMin
+	if rate == 0 {
+		rate = a.cfg.KeyPerMinute
+	}
+	client := "key:" + strconv.FormatInt(k.ID, 10)
+	if ok, wait := a.limiter.Allow(client, rate, 0); !ok {
+		return "", rateLimited(wait)
+	}
+	return client, nil
+}
+
+func (a grpcAuth) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	client, err := a.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, grpcClientKey{}, client), req)
+}
+
+// stream 只在建立串流時檢查一次，之後推送的資料不計入限流
+func (a grpcAuth) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, err := a.check(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// peerIPHash 和 voterID 一樣只保留 IP 的雜湊
+func peerIPHash(ctx context.Context) string {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:8])
+}
+
+func rateLimited(wait time.Duration) error {
+	return status.Errorf(codes.ResourceExhausted, "請求過於頻繁，請 %d 秒後再試", int(wait.Seconds())+1)
+}
+
+// grpcError 把資料層的錯誤轉成對應的狀態碼
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case isSearchInputError(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// ---------------------------------------------------------
+// RPC
+// ---------------------------------------------------------
+
+func (s *memeService) Search(ctx context.Context, req *memepb.SearchRequest) (*memepb.SearchResponse, error) {
+	mode, err := protoMode(req.GetMode())
+	if err != nil {
+		return nil, err
+	}
+	sort, err := protoSort(req.GetSort())
+	if err != nil {
+		return nil, err
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = v1DefaultLimit
+	}
+	if limit < 0 || limit > v1MaxLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit 需為 1 到 %d 的整數", v1MaxLimit)
+	}
+	offset := int(req.GetOffset())
+	if offset < 0 || offset > v1MaxOffset {
+		return nil, status.Errorf(codes.InvalidArgument, "offset 需為 0 到 %d 的整數", v1MaxOffset)
+	}
+
+	client, _ := ctx.Value(grpcClientKey{}).(string)
+	res, err := runSearch(s.syn, searchRequest{
+		Query: req.GetQuery(), Mode: mode, Sort: sort, Limit: limit, Offset: offset, Client: anonymousID(client),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &memepb.SearchResponse{Expansions: res.Expansions, SearchId: res.SearchID}
+	for _, m := range res.Memes {
+		resp.Memes = append(resp.Memes, toProtoMeme(m))
+	}
+	if len(res.Memes) == limit && offset+limit <= v1MaxOffset {
+		resp.NextOffset = int32(offset + limit)
+	}
+	return resp, nil
+}
+
+func (s *memeService) Random(_ context.Context, req *memepb.RandomRequest) (*memepb.RandomResponse, error) {
+	mode, err := protoMode(req.GetMode())
+	if err != nil {
+		return nil, err
+	}
+	count := int(req.GetCount())
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 || count > maxRandomCount {
+		return nil, status.Errorf(codes.InvalidArgument, "count 需為 1 到 %d 的整數", maxRandomCount)
+	}
+
+	opts := RandomOptions{Mode: mode, Count: count}
+	if seed := req.GetSeed(); seed != "" {
+		opts.Rand = seededRand(seed + "|" + mode)
+	}
+	memes, err := GetRandomMemes(opts)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if len(memes) == 0 {
+		return nil, grpcError(ErrNotFound)
+	}
+
+	resp := &memepb.RandomResponse{}
+	for _, m := range memes {
+		recordView(m.ID)
+		resp.Memes = append(resp.Memes, toProtoMeme(m))
+	}
+	return resp, nil
+}
+
+func (s *memeService) Get(_ context.Context, req *memepb.GetRequest) (*memepb.Meme, error) {
+	if req.GetId() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "id 需為正整數")
+	}
+	m, err := GetMemeByID(req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	recordView(m.ID)
+	return toProtoMeme(m), nil
+}
+
+// WatchNewMemes 推送新寫入的資料。和 /api/stream 一樣訂閱 memeEvents
+// (爬蟲另一個程序寫入的資料由 startMemeFeed 統一讀取後發布)，不會每條串流各自輪詢資料庫
+func (s *memeService) WatchNewMemes(req *memepb.WatchRequest, stream grpc.ServerStreamingServer[memepb.Meme]) error {
+	mode, err := protoMode(req.GetMode())
+	if err != nil {
+		return err
+	}
+
+	if s.streams.Add(1) > s.maxStreams {
+		s.streams.Add(-1)
+		return status.Error(codes.ResourceExhausted, "串流數已達上限，請稍後再試")
+	}
+	defer s.streams.Add(-1)
+
+	// 先訂閱再補送，補送期間寫入的資料才不會漏掉
+	events, unsubscribe := memeEvents.Subscribe(streamBuffer)
+	defer unsubscribe()
+
+	last := req.GetAfterId()
+	if last <= 0 {
+		if last, err = LatestMemeID(); err != nil {
+			return grpcError(err)
+		}
+	} else {
+		for {
+			memes, err := ListMemesAfter(last, mode, watchBatchSize)
+			if err != nil {
+				return grpcError(err)
+			}
+			for _, m := range memes {
+				if err := stream.Send(toProtoMeme(m)); err != nil {
+					return err
+				}
+				last = m.ID
+			}
+			if len(memes) < watchBatchSize {
+				break
+			}
+		}
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case m := <-events:
+			// 補送時已送過的資料也會出現在事件裡
+			if m.ID <= last || !matchesKind(m, mode) {
+				continue
+			}
+			if err := stream.Send(toProtoMeme(m)); err != nil {
+				return err
+			}
+			last = m.ID
+		}
+	}
+}
+
+// ---------------------------------------------------------
+// 轉換
+// ---------------------------------------------------------
+
+func protoMode(m memepb.Mode) (string, error) {
+	switch m {
+	case memepb.Mode_MODE_ALL:
+		return "all", nil
+	case memepb.Mode_MODE_IMAGE:
+		return "image", nil
+	case memepb.Mode_MODE_TEXT:
+		return "text", nil
+	}
+	return "", status.Errorf(codes.InvalidArgument, "未知的 mode: %d", m)
+}
+
+func protoSort(s memepb.Sort) (string, error) {
+	switch s {
+	case memepb.Sort_SORT_NEW:
+		return "new", nil
+	case memepb.Sort_SORT_HOT:
+		return "hot", nil
+	case memepb.Sort_SORT_TOP:
+		return "top", nil
+	}
+	return "", status.Errorf(codes.InvalidArgument, "未知的 sort: %d", s)
+}
+
+func toProtoMeme(m Meme) *memepb.Meme {
+	pm := &memepb.Meme{
+		Id:           m.ID,
+		Title:        m.Title,
+		Url:          m.URL,
+		Source:       sourceName(m.SourceURL),
+		SourceUrl:    m.SourceURL,
+		Author:       m.Author,
+		MediaUrl:     m.MediaURL,
+		ThumbnailUrl: m.ThumbnailURL,
+		PreviewUrl:   m.PreviewURL,
+		Description:  m.Description,
+		Category:     m.Category,
+		UploadedAt:   m.UploadedAt,
+		SourceViews:  m.SourceViews,
+		Permalink:    "/m/" + strconv.FormatInt(m.ID, 10),
+		MatchedTerms: m.MatchedTerms,
+		Snippet:      m.Snippet,
+	}
+	for _, tag := range strings.Split(m.Tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			pm.Tags = append(pm.Tags, tag)
+		}
+	}
+	for _, h := range m.Highlights {
+		pm.Highlights = append(pm.Highlights, &memepb.Highlight{Start: int32(h.Start), End: int32(h.End)})
+	}
+	return pm
+}
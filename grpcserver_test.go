@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"gofinal/memepb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// setupTestGRPC 在記憶體中的連線上啟動 gRPC 伺服器 (資料庫由 setupTestServer 準備)
+func setupTestGRPC(t *testing.T) memepb.MemeServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := newGRPCServer(newSynonymIndex(), NewRateLimiter())
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("建立連線失敗: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return memepb.NewMemeServiceClient(conn)
+}
+
+func TestGRPCSearchRandomGet(t *testing.T) {
+	setupTestServer(t,
+		ExportMeme{Title: "貓咪跳舞", URL: "https://example.com/1.gif", Tags: "cat, 跳舞", SourceURL: "https://www.gif-vif.com/gifs/1"},
+		ExportMeme{Title: "狗狗跳舞", URL: "https://example.com/2.gif", Tags: "dog", SourceURL: "https://www.gif-vif.com/gifs/2"},
+		ExportMeme{Title: "跳舞複製文", URL: "今天也要跳舞", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html", Author: "someone"},
+	)
+	client := setupTestGRPC(t)
+	ctx := context.Background()
+
+	res, err := client.Search(ctx, &memepb.SearchRequest{Query: "跳舞", Mode: memepb.Mode_MODE_IMAGE, Limit: 1})
+	if err != nil {
+		t.Fatalf("搜尋失敗: %v", err)
+	}
+	if len(res.Memes) != 1 || res.NextOffset != 1 || res.SearchId == 0 {
+		t.Fatalf("應回傳 1 筆 GIF 與下一頁位置: %v", res)
+	}
+	m := res.Memes[0]
+	if m.Source != "gif" || m.Permalink == "" || len(m.Tags) == 0 {
+		t.Errorf("欄位轉換錯誤: %v", m)
+	}
+
+	res, err = client.Search(ctx, &memepb.SearchRequest{Query: "跳舞", Mode: memepb.Mode_MODE_TEXT})
+	if err != nil || len(res.Memes) != 1 || res.Memes[0].Author != "someone" || res.NextOffset != 0 {
+		t.Errorf("複製文搜尋錯誤: %v (%v)", res, err)
+	}
+
+	// 輸入錯誤回傳 INVALID_ARGUMENT
+	for _, req := range []*memepb.SearchRequest{
+		{Query: "after:昨天"},
+		{Limit: 99},
+		{Offset: -1},
+		{Mode: memepb.Mode(9)},
+	} {
+		if _, err := client.Search(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%v 應回傳 InvalidArgument，得到 %v", req, err)
+		}
+	}
+
+	a, err := client.Random(ctx, &memepb.RandomRequest{Count: 2, Seed: "abc"})
+	if err != nil || len(a.Memes) != 2 {
+		t.Fatalf("隨機抽取失敗: %v (%v)", a, err)
+	}
+	b, _ := client.Random(ctx, &memepb.RandomRequest{Count: 2, Seed: "abc"})
+	if a.Memes[0].Id != b.Memes[0].Id || a.Memes[1].Id != b.Memes[1].Id {
+		t.Errorf("相同 seed 應抽到相同結果")
+	}
+	if _, err := client.Random(ctx, &memepb.RandomRequest{Count: 21}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("count 超過上限應回傳 InvalidArgument，得到 %v", err)
+	}
+
+	got, err := client.Get(ctx, &memepb.GetRequest{Id: 3})
+	if err != nil || got.Title != "跳舞複製文" || got.Source != "ptt" {
+		t.Errorf("取得單筆失敗: %v (%v)", got, err)
+	}
+	if _, err := client.Get(ctx, &memepb.GetRequest{Id: 999}); status.Code(err) != codes.NotFound {
+		t.Errorf("不存在的 id 應回傳 NotFound，得到 %v", err)
+	}
+	SetMemeDeleted(3, true, "test")
+	if _, err := client.Random(ctx, &memepb.RandomRequest{Mode: memepb.Mode_MODE_TEXT}); status.Code(err) != codes.NotFound {
+		t.Errorf("沒有資料時應回傳 NotFound，得到 %v", err)
+	}
+}
+
+func TestGRPCWatchNewMemes(t *testing.T) {
+	setupTestServer(t,
+		ExportMeme{Title: "舊的", URL: "https://example.com/1.gif", Tags: "old", SourceURL: "https://www.gif-vif.com/gifs/1"},
+	)
+	client := setupTestGRPC(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// after_id = 0 只收之後新增的，且只收符合模式的
+	stream, err := client.WatchNewMemes(ctx, &memepb.WatchRequest{Mode: memepb.Mode_MODE_TEXT})
+	if err != nil {
+		t.Fatalf("建立串流失敗: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	InsertMeme(ExportMeme{Title: "新的 GIF", URL: "https://example.com/2.gif", Tags: "new", SourceURL: "https://www.gif-vif.com/gifs/2"})
+	InsertMeme(ExportMeme{Title: "新的複製文", URL: "剛剛爬到的", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/Joke/M.2.html"})
+
+	m, err := stream.Recv()
+	if err != nil || m.Title != "新的複製文" {
+		t.Fatalf("應收到新的複製文: %v (%v)", m, err)
+	}
+
+	// 指定 after_id 會先補送之前的資料
+	stream, err = client.WatchNewMemes(ctx, &memepb.WatchRequest{AfterId: 1})
+	if err != nil {
+		t.Fatalf("建立串流失敗: %v", err)
+	}
+	for _, want := range []string{"新的 GIF", "新的複製文"} {
+		if m, err := stream.Recv(); err != nil || m.Title != want {
+			t.Errorf("應依序補送 %s: %v (%v)", want, m, err)
+		}
+	}
+}
+
+func TestGRPCWatchStreamLimit(t *testing.T) {
+	t.Setenv("GRPC_MAX_STREAMS", "1")
+	setupTestServer(t,
+		ExportMeme{Title: "一", URL: "https://example.com/1.gif", Tags: "a", SourceURL: "https://www.gif-vif.com/gifs/1"},
+		ExportMeme{Title: "二", URL: "https://example.com/2.gif", Tags: "b", SourceURL: "https://www.gif-vif.com/gifs/2"},
+	)
+	client := setupTestGRPC(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 收到補送的資料代表第一條串流已經佔用名額
+	first, cancelFirst := context.WithCancel(ctx)
+	stream, err := client.WatchNewMemes(first, &memepb.WatchRequest{AfterId: 1})
+	if err != nil {
+		t.Fatalf("建立串流失敗: %v", err)
+	}
+	if m, err := stream.Recv(); err != nil || m.Title != "二" {
+		t.Fatalf("應收到補送的資料: %v (%v)", m, err)
+	}
+
+	stream, _ = client.WatchNewMemes(ctx, &memepb.WatchRequest{})
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("超過串流上限應回傳 ResourceExhausted，得到 %v", err)
+	}
+
+	// 第一條關閉後名額會釋放
+	cancelFirst()
+	deadline := time.Now().Add(time.Second)
+	for {
+		stream, _ = client.WatchNewMemes(ctx, &memepb.WatchRequest{AfterId: 1})
+		_, err := stream.Recv()
+		if err == nil {
+			break
+		}
+		if status.Code(err) != codes.ResourceExhausted || time.Now().After(deadline) {
+			t.Fatalf("名額釋放後應可再建立串流: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGRPCRequiresAPIKey(t *testing.T) {
+	t.Setenv("REQUIRE_API_KEY", "1")
+	setupTestServer(t, ExportMeme{Title: "貓咪", URL: "https://example.com/1.gif", Tags: "cat", SourceURL: "https://www.gif-vif.com/gifs/1"})
+	client := setupTestGRPC(t)
+
+	if _, err := client.Get(context.Background(), &memepb.GetRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("缺少金鑰應回傳 Unauthenticated，得到 %v", err)
+	}
+	bad := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "mk_nope")
+	if _, err := client.Get(bad, &memepb.GetRequest{Id: 1}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("無效的金鑰應回傳 Unauthenticated，得到 %v", err)
+	}
+
+	key, _, err := IssueAPIKey("bot", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	if _, err := client.Get(ctx, &memepb.GetRequest{Id: 1}); err != nil {
+		t.Errorf("有效的金鑰應可呼叫: %v", err)
+	}
+	if _, err := client.Get(ctx, &memepb.GetRequest{Id: 1}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("超過金鑰的限流應回傳 ResourceExhausted，得到 %v", err)
+	}
+	stream, _ := client.WatchNewMemes(ctx, &memepb.WatchRequest{})
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("串流也要套用限流，得到 %v", err)
+	}
+}
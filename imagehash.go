@@ -0,0 +1,273 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"log"
+	"math/bits"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// =========================================================
+// [以圖搜圖：dHash 感知雜湊]
+// =========================================================
+
+// GIF 最多取幾格計算雜湊 (第一格、中間與最後)，截圖通常只是其中一格
+const hashFramesPerGIF = 3
+
+const defaultMaxDistance = 12 // 64 bit 中最多幾個 bit 不同仍算相似
+const maxUploadSize = 10 << 20
+const maxImageResults = 50 // 以圖搜圖一次最多回傳幾筆
+
+// dHash 把圖縮成 9x8 灰階，比較每一列相鄰像素的亮度，得到 64 bit 的指紋。
+// 對縮放、壓縮與輕微調色不敏感，相似的圖片漢明距離會很小
+func dHash(img image.Image) uint64 {
+	b := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok || b.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	}
+
+	var gray [8][9]uint32
+	w, h := rgba.Bounds().Dx(), rgba.Bounds().Dy()
+	for y := 0; y < 8; y++ {
+		y0, y1 := y*h/8, max((y+1)*h/8, y*h/8+1)
+		for x := 0; x < 9; x++ {
+			x0, x1 := x*w/9, max((x+1)*w/9, x*w/9+1)
+			var sum, n uint32
+			for sy := y0; sy < y1 && sy < h; sy++ {
+				for sx := x0; sx < x1 && sx < w; sx++ {
+					i := rgba.PixOffset(sx, sy)
+					// ITU-R 601 亮度，透明部分當成白色
+					a := uint32(rgba.Pix[i+3])
+					lum := (299*uint32(rgba.Pix[i]) + 587*uint32(rgba.Pix[i+1]) + 114*uint32(rgba.Pix[i+2])) / 1000
+					sum += (lum*a + 255*(255-a)) / 255
+					n++
+				}
+			}
+			if n > 0 {
+				gray[y][x] = sum / n
+			}
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// imageHashes 計算一張圖 (或 GIF 的幾個影格) 的 dHash，解碼前先檢查尺寸與影格數
+func imageHashes(r io.ReadSeeker) ([]uint64, error) {
+	format, err := checkImageSize(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "gif" {
+		g, err := gif.DecodeAll(r)
+		if err != nil || len(g.Image) == 0 {
+			return nil, ErrUnsupportedImage
+		}
+		n := len(g.Image)
+		picks := []int{0, n / 2, n - 1}[:min(hashFramesPerGIF, n)]
+		frames := renderGIFFrames(g, func(i int) bool { return slices.Contains(picks, i) })
+		var hashes []uint64
+		seen := map[uint64]bool{}
+		for _, i := range picks {
+			h := dHash(frames[i])
+			if !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+		}
+		return hashes, nil
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	return []uint64{dHash(img)}, nil
+}
+
+// IndexMediaHashes 為一個鏡像檔建立雜湊索引，已建立過的略過
+func IndexMediaHashes(hash string) error {
+	var exists bool
+	db.QueryRow(`SELECT 1 FROM media_hashes WHERE media_hash = ? LIMIT 1`, hash).Scan(&exists)
+	if exists {
+		return nil
+	}
+
+	f, err := os.Open(mediaPath(hash))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hashes, err := imageHashes(f)
+	if err != nil {
+		return err
+	}
+	for i, h := range hashes {
+		// SQLite 只有有號整數，以相同的 64 bit 存取即可
+		if _, err := db.Exec(`INSERT OR IGNORE INTO media_hashes (media_hash, frame, dhash) VALUES (?, ?, ?)`, hash, i, int64(h)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexPendingMediaHashes 補建舊鏡像檔 (加入以圖搜圖之前鏡像的) 的雜湊，回傳成功筆數
+func IndexPendingMediaHashes() (int, error) {
+	rows, err := db.Query(`SELECT hash FROM media WHERE mime_type LIKE 'image/%'
+		AND hash NOT IN (SELECT media_hash FROM media_hashes)`)
+	if err != nil {
+		return 0, err
+	}
+	var pending []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, h := range pending {
+		if err := IndexMediaHashes(h); err != nil {
+			log.Printf("[ImageHash] 建立索引失敗 %s: %v", h, err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ---------------------------------------------------------
+// 記憶體索引
+// ---------------------------------------------------------
+
+type hashEntry struct {
+	mediaHash string
+	dhash     uint64
+}
+
+// imageIndex 把 media_hashes 整張表放在記憶體中做線性比對 (每筆只要一次 XOR + popcount)。
+// 雜湊在鏡像時就已建立 (MirrorMedia)，爬蟲在另一個程序寫入時筆數會改變，查詢前比對筆數，有變化才重新載入
+type imageIndex struct {
+	mu      sync.Mutex
+	entries []hashEntry
+	rows    int
+}
+
+var sharedImageIndex = &imageIndex{rows: -1}
+
+func (idx *imageIndex) snapshot() ([]hashEntry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM media_hashes`).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count == idx.rows {
+		return idx.entries, nil
+	}
+
+	rows, err := db.Query(`SELECT media_hash, dhash FROM media_hashes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []hashEntry{}
+	for rows.Next() {
+		var e hashEntry
+		var h int64
+		if err := rows.Scan(&e.mediaHash, &h); err != nil {
+			return nil, err
+		}
+		e.dhash = uint64(h)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	idx.entries, idx.rows = entries, count
+	return entries, nil
+}
+
+// ImageMatch 是以圖搜圖的一筆結果，Distance 越小越像 (0 代表指紋完全相同)
+type ImageMatch struct {
+	Meme     Meme `json:"meme"`
+	Distance int  `json:"distance"`
+}
+
+// SearchByImage 找出與上傳圖片最相近的資料，最多 limit 筆 (小於 1 時當作 1)
+func SearchByImage(query []uint64, maxDistance, limit int) ([]ImageMatch, error) {
+	limit = max(limit, 1)
+	entries, err := sharedImageIndex.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	best := map[string]int{}
+	for _, e := range entries {
+		for _, q := range query {
+			d := bits.OnesCount64(e.dhash ^ q)
+			if d > maxDistance {
+				continue
+			}
+			if old, ok := best[e.mediaHash]; !ok || d < old {
+				best[e.mediaHash] = d
+			}
+		}
+	}
+
+	type candidate struct {
+		hash     string
+		distance int
+	}
+	candidates := make([]candidate, 0, len(best))
+	for h, d := range best {
+		candidates = append(candidates, candidate{h, d})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].hash < candidates[j].hash
+	})
+
+	matches := []ImageMatch{}
+	for _, cand := range candidates {
+		// 同一個檔案可能被多筆資料使用 (不同網址、相同內容)
+		memes, err := queryMemes(`SELECT `+memeColumns+` FROM memes WHERE media_hash = ? AND `+visibleSQL+aliveSQL+` ORDER BY id`, cand.hash)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range memes {
+			matches = append(matches, ImageMatch{Meme: m, Distance: cand.distance})
+			if len(matches) >= limit {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gradientGIF 產生兩格的漸層動畫，flip 為 true 時方向相反
+func gradientGIF(flip bool) []byte {
+	pal := color.Palette{}
+	for i := 0; i < 256; i++ {
+		pal = append(pal, color.Gray{uint8(i)})
+	}
+	anim := &gif.GIF{}
+	for f := 0; f < 2; f++ {
+		img := image.NewPaletted(image.Rect(0, 0, 90, 80), pal)
+		for y := 0; y < 80; y++ {
+			for x := 0; x < 90; x++ {
+				v := x * 255 / 89
+				if flip {
+					v = 255 - v
+				}
+				img.SetColorIndex(x, y, uint8((v+y*f)%256))
+			}
+		}
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, anim)
+	return buf.Bytes()
+}
+
+func uploadImage(r http.Handler, path string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreateFormFile("image", "upload")
+	part.Write(data)
+	mw.Close()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestReverseImageSearch(t *testing.T) {
+	files := map[string][]byte{"/right.gif": gradientGIF(false), "/left.gif": gradientGIF(true)}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(files[r.URL.Path])
+	}))
+	defer origin.Close()
+
+	MediaDir = t.TempDir()
+	sharedImageIndex = &imageIndex{rows: -1}
+	right := ExportMeme{Title: "往右", URL: origin.URL + "/right.gif", SourceURL: "https://www.gif-vif.com/gifs/right"}
+	left := ExportMeme{Title: "往左", URL: origin.URL + "/left.gif", SourceURL: "https://www.gif-vif.com/gifs/left"}
+	r := setupTestServer(t, right, left)
+	for _, m := range []ExportMeme{right, left} {
+		if _, err := MirrorMemeMedia(m); err != nil {
+			t.Fatalf("鏡像失敗: %v", err)
+		}
+	}
+	// 雜湊在鏡像時就建立好，搜尋時不需要再解碼檔案
+	var indexed int
+	db.QueryRow(`SELECT COUNT(DISTINCT media_hash) FROM media_hashes`).Scan(&indexed)
+	if indexed != 2 {
+		t.Fatalf("鏡像後應已建立 2 個檔案的雜湊，得到 %d", indexed)
+	}
+
+	// 把第一格放大兩倍、稍微調暗後存成 PNG，模擬使用者的截圖
+	g, _ := gif.DecodeAll(bytes.NewReader(files["/right.gif"]))
+	shot := image.NewRGBA(image.Rect(0, 0, 180, 160))
+	for y := 0; y < 160; y++ {
+		for x := 0; x < 180; x++ {
+			c := color.GrayModel.Convert(g.Image[0].At(x/2, y/2)).(color.Gray)
+			v := uint8(int(c.Y) * 9 / 10)
+			shot.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	var screenshot bytes.Buffer
+	png.Encode(&screenshot, shot)
+
+	w := uploadImage(r, "/api/search/image", screenshot.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("預期 200，得到 %d: %s", w.Code, w.Body.String())
+	}
+	var matches []ImageMatch
+	json.Unmarshal(w.Body.Bytes(), &matches)
+	if len(matches) != 1 || matches[0].Meme.Title != "往右" || matches[0].Distance > 4 {
+		t.Fatalf("應只找到往右的漸層: %+v", matches)
+	}
+
+	// 放寬門檻後方向相反的也會出現，但排在後面
+	json.Unmarshal(uploadImage(r, "/api/search/image?max_distance=64", screenshot.Bytes()).Body.Bytes(), &matches)
+	if len(matches) != 2 || matches[1].Meme.Title != "往左" || matches[1].Distance <= matches[0].Distance {
+		t.Errorf("排序不符: %+v", matches)
+	}
+
+	matches = nil
+	json.Unmarshal(uploadImage(r, "/api/search/image?max_distance=64&limit=1", screenshot.Bytes()).Body.Bytes(), &matches)
+	if len(matches) != 1 || matches[0].Meme.Title != "往右" {
+		t.Errorf("limit=1 應只回傳最相近的一筆: %+v", matches)
+	}
+	for _, limit := range []string{"0", "-1", "abc"} {
+		if w := uploadImage(r, "/api/search/image?limit="+limit, screenshot.Bytes()); w.Code != http.StatusBadRequest {
+			t.Errorf("limit=%s 預期 400，得到 %d", limit, w.Code)
+		}
+	}
+	if hashes, err := imageHashes(bytes.NewReader(screenshot.Bytes())); err != nil {
+		t.Fatalf("計算雜湊失敗: %v", err)
+	} else if got, _ := SearchByImage(hashes, 64, 0); len(got) != 1 {
+		t.Errorf("limit 小於 1 時應當作 1，得到 %d 筆", len(got))
+	}
+
+	if w := uploadImage(r, "/api/search/image", []byte("不是圖片")); w.Code != http.StatusBadRequest {
+		t.Errorf("無法解析的檔案預期 400，得到 %d", w.Code)
+	}
+	if w := doRequest(r, "POST", "/api/search/image"); w.Code != http.StatusBadRequest {
+		t.Errorf("沒有上傳檔案預期 400，得到 %d", w.Code)
+	}
+}
+
+func TestImageSearchRejectsOversizedImages(t *testing.T) {
+	r := setupTestServer(t)
+
+	if n, err := countGIFFrames(bytes.NewReader(gradientGIF(false)), 10); err != nil || n != 2 {
+		t.Fatalf("應數到 2 格，得到 %d (%v)", n, err)
+	}
+
+	// 檔案很小，但檔頭宣告的畫面是 65535x65535
+	huge := gradientGIF(false)
+	huge[6], huge[7], huge[8], huge[9] = 0xff, 0xff, 0xff, 0xff
+	if w := uploadImage(r, "/api/search/image", huge); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("尺寸過大預期 413，得到 %d", w.Code)
+	}
+
+	// 每格只有 1 像素，但影格數超過上限
+	anim := &gif.GIF{}
+	for i := 0; i <= maxGIFFrames; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black, color.White}))
+		anim.Delay = append(anim.Delay, 1)
+	}
+	var many bytes.Buffer
+	gif.EncodeAll(&many, anim)
+	if w := uploadImage(r, "/api/search/image", many.Bytes()); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("影格過多預期 413，得到 %d", w.Code)
+	}
+}
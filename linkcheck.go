@@ -0,0 +1,315 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [失效連結檢查]
+// =========================================================
+
+// LinkCheckConfig 由環境變數設定，Interval 為 0 時不啟動背景檢查
+type LinkCheckConfig struct {
+	Interval    time.Duration // 每隔多久跑一輪
+	BatchSize   int           // 每輪最多檢查幾筆
+	RecheckAge  time.Duration // 上次檢查超過這麼久才會再檢查
+	HostPerMin  int           // 對同一個網站每分鐘最多送出的請求數
+	Concurrency int
+}
+
+func linkCheckConfigFromEnv() LinkCheckConfig {
+	return LinkCheckConfig{
+		Interval:    time.Duration(envInt("LINK_CHECK_INTERVAL_MIN", 0)) * time.Minute,
+		BatchSize:   envInt("LINK_CHECK_BATCH", 200),
+		RecheckAge:  time.Duration(envInt("LINK_CHECK_RECHECK_HOURS", 24)) * time.Hour,
+		HostPerMin:  envInt("LINK_CHECK_HOST_PER_MIN", 30),
+		Concurrency: 4,
+	}
+}
+
+// LinkReport 是後台看到的連結狀態
+type LinkReport struct {
+	Meme
+	LinkStatus    int     `json:"link_status"` // 最後一次檢查的 HTTP 狀態碼，0 代表連線失敗
+	LinkFailures  int     `json:"link_failures"`
+	LinkCheckedAt *string `json:"link_checked_at"`
+	Hidden        bool    `json:"hidden"`
+}
+
+var linkClient = &http.Client{Timeout: 15 * time.Second}
+
+// LinkChecker 依網站各自限流，避免短時間對同一個網站送出大量請求
+type LinkChecker struct {
+	cfg     LinkCheckConfig
+	limiter *RateLimiter
+}
+
+func NewLinkChecker(cfg LinkCheckConfig) *LinkChecker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	return &LinkChecker{cfg: cfg, limiter: NewRateLimiter()}
+}
+
+// checkURL 回傳狀態碼與是否確定失效。
+// 401/403/429 多半是擋爬蟲，不算失效也不算正常；不支援 HEAD 的網站改用只取 1 byte 的 GET
+func (lc *LinkChecker) checkURL(rawURL string) (status int, dead bool, conclusive bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return 0, true, true
+	}
+	for {
+		ok, wait := lc.limiter.Allow(u.Host, lc.cfg.HostPerMin, 1)
+		if ok {
+			break
+		}
+		time.Sleep(wait)
+	}
+
+	status, err = doLinkRequest("HEAD", rawURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = doLinkRequest("GET", rawURL)
+	}
+	switch {
+	case err != nil:
+		return 0, true, true
+	case status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusTooManyRequests:
+		return status, false, false
+	default:
+		return status, status >= 400, true
+	}
+}
+
+func doLinkRequest(method, rawURL string) (int, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; meme-link-checker)")
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := linkClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+type linkTarget struct {
+	id        int64
+	url       string
+	sourceURL string
+	mirrored  bool
+}
+
+type linkResult struct {
+	id         int64
+	status     int
+	dead       bool
+	conclusive bool
+}
+
+// probe 檢查一筆資料的所有連結。已鏡像到本地的圖片不檢查原始網址，任一連結失效就算失效
+func (lc *LinkChecker) probe(t linkTarget) linkResult {
+	var urls []string
+	if (isImageURL(t.url) || isVideoURL(t.url)) && !t.mirrored {
+		urls = append(urls, t.url)
+	}
+	if t.sourceURL != "" {
+		urls = append(urls, t.sourceURL)
+	}
+
+	res := linkResult{id: t.id, status: http.StatusOK, conclusive: true}
+	for _, u := range urls {
+		status, dead, conclusive := lc.checkURL(u)
+		if dead || !conclusive {
+			res.status = status
+		}
+		res.dead = res.dead || dead
+		res.conclusive = res.conclusive && conclusive
+	}
+	return res
+}
+
+// saveLinkResult 寫回檢查結果：失效就累加失敗次數，確定正常則歸零，無法判斷時維持原狀
+func saveLinkResult(res linkResult) error {
+	failures := `link_failures`
+	switch {
+	case res.dead:
+		failures = `link_failures + 1`
+	case res.conclusive:
+		failures = `0`
+	}
+	_, err := db.Exec(`UPDATE memes SET link_status = ?, link_checked_at = CURRENT_TIMESTAMP, link_failures = `+failures+` WHERE id = ?`, res.status, res.id)
+	if err == nil && (res.dead || res.conclusive) {
+		memeEdits.Add(1) // 失敗次數變動可能讓資料被隱藏或重新顯示
+	}
+	return err
+}
+
+// CheckMeme 立即檢查單筆資料
+func (lc *LinkChecker) CheckMeme(t linkTarget) error {
+	return saveLinkResult(lc.probe(t))
+}
+
+// RunOnce 檢查一批最久沒檢查的資料，回傳檢查筆數。
+// 連線檢查平行進行，寫入資料庫則集中在同一個 goroutine，避免 SQLite 寫入衝突
+func (lc *LinkChecker) RunOnce() (int, error) {
+	rows, err := db.Query(`SELECT id, url, source_url, media_hash IS NOT NULL FROM memes
+		WHERE `+visibleSQL+` AND (link_checked_at IS NULL OR link_checked_at <= datetime('now', ?))
+		ORDER BY link_checked_at IS NOT NULL, link_checked_at LIMIT ?`,
+		fmt.Sprintf("-%d seconds", int(lc.cfg.RecheckAge.Seconds())), lc.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var targets []linkTarget
+	for rows.Next() {
+		var t linkTarget
+		if err := rows.Scan(&t.id, &t.url, &t.sourceURL, &t.mirrored); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+
+	jobs := make(chan linkTarget)
+	results := make(chan linkResult)
+	var wg sync.WaitGroup
+	for i := 0; i < lc.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				results <- lc.probe(t)
+			}
+		}()
+	}
+	go func() {
+		for _, t := range targets {
+			jobs <- t
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for res := range results {
+		if err := saveLinkResult(res); err != nil {
+			log.Printf("[LinkCheck] 寫入 #%d 結果失敗: %v", res.id, err)
+		}
+	}
+	return len(targets), nil
+}
+
+// Start 在背景定期檢查
+func (lc *LinkChecker) Start() {
+	if lc.cfg.Interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			n, err := lc.RunOnce()
+			if err != nil {
+				log.Printf("[LinkCheck] 檢查失敗: %v", err)
+			} else if n > 0 {
+				log.Printf("[LinkCheck] 已檢查 %d 筆", n)
+			}
+			time.Sleep(lc.cfg.Interval)
+		}
+	}()
+}
+
+const linkReportColumns = memeColumns + `, link_status, link_failures, link_checked_at`
+
+func scanLinkReport(row rowScanner) (LinkReport, error) {
+	var r LinkReport
+	var status *int
+	var err error
+	if r.Meme, err = scanMeme(row, &status, &r.LinkFailures, &r.LinkCheckedAt); err != nil {
+		return LinkReport{}, err
+	}
+	if status != nil {
+		r.LinkStatus = *status
+	}
+	r.Hidden = r.LinkFailures >= deadLinkThreshold
+	return r, nil
+}
+
+func getLinkReport(id int64) (LinkReport, error) {
+	r, err := scanLinkReport(db.QueryRow(`SELECT `+linkReportColumns+` FROM memes WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return LinkReport{}, ErrNotFound
+	}
+	return r, err
+}
+
+// ListLinkReports 列出失敗次數至少 minFailures 的資料，失敗最多的排前面
+func ListLinkReports(minFailures, limit int) ([]LinkReport, error) {
+	rows, err := db.Query(`SELECT `+linkReportColumns+` FROM memes
+		WHERE `+visibleSQL+` AND link_failures >= ? ORDER BY link_failures DESC, link_checked_at DESC LIMIT ?`, minFailures, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []LinkReport{}
+	for rows.Next() {
+		r, err := scanLinkReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+// ---------------------------------------------------------
+// HTTP 處理 (掛在管理員 API 底下)
+// ---------------------------------------------------------
+
+func registerLinkCheckRoutes(admin *gin.RouterGroup, lc *LinkChecker) {
+	admin.GET("/links", func(c *gin.Context) {
+		reports, err := ListLinkReports(queryInt(c, "min_failures", 1, 0), queryInt(c, "limit", 50, 500))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, reports)
+	})
+
+	// 手動重新檢查單筆 (例如來源網站恢復後)
+	admin.POST("/links/:id/check", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		var t linkTarget
+		err := db.QueryRow(`SELECT id, url, source_url, media_hash IS NOT NULL FROM memes WHERE id = ?`, id).
+			Scan(&t.id, &t.url, &t.sourceURL, &t.mirrored)
+		if err == sql.ErrNoRows {
+			err = ErrNotFound
+		}
+		if err == nil {
+			err = lc.CheckMeme(t)
+		}
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		report, err := getLinkReport(id)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	})
+}
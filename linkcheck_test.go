@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLinkChecker(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	t.Setenv("LINK_CHECK_HOST_PER_MIN", "6000") // 測試伺服器都在同一個 host
+
+	alive := true
+	heads := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gone.gif":
+			if !alive {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case "/no-head":
+			// 不支援 HEAD 的網站要改用 GET
+			if r.Method == "HEAD" {
+				heads++
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/blocked":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer origin.Close()
+
+	r := setupTestServer(t,
+		ExportMeme{Title: "會消失的圖", URL: origin.URL + "/gone.gif", SourceURL: origin.URL + "/page"},
+		ExportMeme{Title: "文字", URL: "好笑的文", SourceURL: origin.URL + "/no-head"},
+		ExportMeme{Title: "擋爬蟲", URL: "另一則", SourceURL: origin.URL + "/blocked"},
+	)
+	lc := NewLinkChecker(LinkCheckConfig{BatchSize: 10, RecheckAge: time.Hour, HostPerMin: 6000, Concurrency: 2})
+
+	if n, err := lc.RunOnce(); err != nil || n != 3 {
+		t.Fatalf("第一輪預期檢查 3 筆，得到 %d (%v)", n, err)
+	}
+	if n, _ := lc.RunOnce(); n != 0 {
+		t.Errorf("剛檢查過的不應重複檢查，得到 %d 筆", n)
+	}
+	if heads != 1 {
+		t.Errorf("HEAD 被拒時應改用 GET")
+	}
+
+	// 連續失敗達門檻才隱藏
+	alive = false
+	for i := 1; i <= deadLinkThreshold; i++ {
+		if res, _ := SearchMemes("會消失", "all"); len(res) != 1 {
+			t.Fatalf("失敗 %d 次前不應隱藏", i-1)
+		}
+		lc.CheckMeme(linkTarget{id: 1, url: origin.URL + "/gone.gif", sourceURL: origin.URL + "/page"})
+	}
+	if res, _ := SearchMemes("會消失", "all"); len(res) != 0 {
+		t.Errorf("失效項目不應出現在搜尋結果")
+	}
+	for i := 0; i < 10; i++ {
+		if m, _ := GetRandomMeme("image"); m.ID == 1 {
+			t.Fatalf("失效項目不應被隨機抽到")
+		}
+	}
+	if _, err := GetMemeByID(1); err != nil {
+		t.Errorf("直接以 id 開啟仍應看得到: %v", err)
+	}
+
+	var reports []LinkReport
+	json.Unmarshal(adminRequest(r, "GET", "/api/admin/links", nil).Body.Bytes(), &reports)
+	if len(reports) != 1 || reports[0].ID != 1 || reports[0].LinkStatus != http.StatusNotFound || !reports[0].Hidden {
+		t.Fatalf("失效報表不符: %+v", reports)
+	}
+
+	// 403 無法判斷，不累加失敗次數
+	if m, _ := getLinkReport(3); m.LinkFailures != 0 || m.LinkStatus != http.StatusForbidden {
+		t.Errorf("被擋的連結不應算失效: %+v", m)
+	}
+
+	// 網站恢復後手動重新檢查，失敗次數歸零
+	alive = true
+	w := adminRequest(r, "POST", "/api/admin/links/1/check", nil)
+	var report LinkReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.LinkFailures != 0 || report.Hidden {
+		t.Errorf("恢復後應歸零: %d %+v", w.Code, report)
+	}
+	if res, _ := SearchMemes("會消失", "all"); len(res) != 1 {
+		t.Errorf("恢復後應重新出現在搜尋結果")
+	}
+}
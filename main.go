@@ -1,51 +1,201 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // 抽出 setupRouter 方便測試
 func setupRouter() *gin.Engine {
+	return newRouter(newSynonymIndex(), NewRateLimiter())
+}
+
+// newRouter 建立 HTTP 路由，syn 與 limiter 與 gRPC 服務共用。
+// 這裡不啟動 limiter 的定期清理，由 main 負責 (測試每次建立路由才不會多留一個 goroutine)
+func newRouter(syn *synonymIndex, limiter *RateLimiter) *gin.Engine {
 	r := gin.Default()
-	r.LoadHTMLFiles("index.html")
+	loadTemplates(r)
+
+	// 預設不信任任何代理的 X-Forwarded-For，避免有人偽造 IP 繞過限流
+	var proxies []string
+	if env := os.Getenv("TRUSTED_PROXIES"); env != "" {
+		proxies = strings.Split(env, ",")
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Printf("[警告] TRUSTED_PROXIES 設定錯誤: %v", err)
+	}
 
 	r.GET("/", func(c *gin.Context) {
 		c.HTML(http.StatusOK, "index.html", nil)
 	})
 
-	r.GET("/api/search", func(c *gin.Context) {
-		query := c.Query("q")
-		mode := c.DefaultQuery("mode", "all")
-		results, err := SearchMemes(query, mode)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// 公開 API：驗證 API key 並依金鑰或 IP 限流，同時讀取登入狀態
+	rateCfg := rateLimitConfigFromEnv()
+	apiAuth := apiKeyAuth(rateCfg, limiter)
+	api := r.Group("/api", apiAuth, loadSession())
+
+	// 搜尋 (支援搜尋語法與同義詞，見 search.go)
+	api.GET("/search", searchHandler(syn))
+
+	// 搜尋結果的點擊與複製回報 (搜尋分析用)
+	registerSearchClickRoutes(api)
+
+	// 自動完成 (標題、標籤與熱門搜尋)
+	suggest := newSuggestIndex()
+	registerSuggestRoutes(api, suggest)
+
+	// 以圖搜圖 (上傳圖片找最相近的 GIF)
+	registerImageSearchRoutes(api)
+
+	// 即時推送新寫入的資料 (SSE，見 stream.go)
+	api.GET("/stream", streamHandler)
+
+	// 隨機抽取 (count / seed / no_repeat / weighted 可任意組合，見 random.go)
+	api.GET("/random", randomHandler(newRecentHistory()))
+
+	api.GET("/memes/:id", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		meme, err := GetMemeByID(id)
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "找不到資料"})
 			return
 		}
-		c.JSON(http.StatusOK, results)
-	})
-
-	r.GET("/api/random", func(c *gin.Context) {
-		mode := c.DefaultQuery("mode", "all")
-		meme, err := GetRandomMeme(mode)
 		if err != nil {
-			c.JSON(http.StatusOK, gin.H{"error": "找不到資料"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		recordView(meme.ID)
 		c.JSON(http.StatusOK, meme)
 	})
 
+	// 投票、複製次數與熱門排行
+	registerVoteRoutes(api)
+
+	// 每日一梗
+	registerDailyRoutes(api)
+
+	// 帳號、我的最愛與收藏集
+	registerUserRoutes(api)
+
+	// 版本化 API (固定的回應格式與 OpenAPI 規格，見 apiv1.go)
+	registerV1Routes(api, syn)
+	r.NoRoute(v1NoRoute)
+
+	// GraphQL (給內部儀表板一次取得資料、標籤、來源與爬蟲紀錄，與公開 API 共用驗證與限流)
+	gql := graphqlHandler(newGraphQLSchema(syn, suggest))
+	r.GET("/graphql", apiAuth, loadSession(), gql)
+	r.POST("/graphql", apiAuth, loadSession(), gql)
+
+	// 伺服器端渲染的頁面每次都會查資料庫，同樣依 IP 限流
+	pageLimit := ipRateLimit(rateCfg, limiter)
+
+	// 永久連結頁 (伺服器端渲染，含 Open Graph 預覽)
+	r.GET("/m/:id", pageLimit, memePageHandler)
+
+	// 不需要 JavaScript 的搜尋與隨機頁面 (伺服器端渲染，搜尋引擎也能收錄)
+	r.GET("/search", pageLimit, searchPageHandler(syn))
+	r.GET("/random", pageLimit, randomPageHandler)
+
+	// 鏡像到本地的圖片/影片 (內容定址，可長期快取；一頁會載入很多張，所以不限流)
+	r.GET("/media/:hash", mediaHandler)
+	r.GET("/media/:hash/:kind", thumbnailHandler)
+
+	// Slack / Discord 的 /meme 指令 (以平台簽章驗證，見 chatbot.go)
+	registerChatRoutes(r, syn)
+
+	// 管理員後台與 API (需設定 ADMIN_TOKEN)
+	admin := registerAdminRoutes(r)
+
+	// 失效連結檢查 (LINK_CHECK_INTERVAL_MIN 大於 0 才會在背景執行)
+	linkChecker := NewLinkChecker(linkCheckConfigFromEnv())
+	linkChecker.Start()
+	registerLinkCheckRoutes(admin, linkChecker)
+	registerSynonymRoutes(admin, syn)
+	registerAnalyticsRoutes(admin)
+	registerWebhookRoutes(admin)
+
 	return r
 }
 
 func main() {
+	// 共用參數 (--data-dir / --dev) 寫在子指令前面，例如 go run ... --data-dir /var/lib/meme mirror
+	cfg, args, err := ParseConfig(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := cfg.Apply(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	// 管理 API key 的子指令：go run ... apikey issue|revoke|list
+	if len(args) > 0 && args[0] == "apikey" {
+		if err := InitDB(DBFile); err != nil {
+			log.Fatalf("❌ 資料庫連線失敗: %v", err)
+		}
+		if err := runAPIKeyCommand(args[1:]); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	// 補抓尚未鏡像的圖片/影片：go run ... mirror [筆數]
+	if len(args) > 0 && args[0] == "mirror" {
+		if err := InitDB(DBFile); err != nil {
+			log.Fatalf("❌ 資料庫連線失敗: %v", err)
+		}
+		limit := 500
+		if len(args) > 1 {
+			if n, err := strconv.Atoi(args[1]); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		n, err := MirrorPendingMedia(limit)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ 鏡像完成 %d 筆", n)
+		// 新鏡像的檔案已建立雜湊，這裡補上加入以圖搜圖之前鏡像的舊檔案
+		n, err = IndexPendingMediaHashes()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ 補建圖片雜湊 %d 筆", n)
+		n, err = GeneratePendingThumbnails()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ 補產生縮圖 %d 個", n)
+		return
+	}
+
+	// 立即檢查一批連結：go run ... linkcheck
+	if len(args) > 0 && args[0] == "linkcheck" {
+		if err := InitDB(DBFile); err != nil {
+			log.Fatalf("❌ 資料庫連線失敗: %v", err)
+		}
+		n, err := NewLinkChecker(linkCheckConfigFromEnv()).RunOnce()
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✅ 已檢查 %d 筆", n)
+		return
+	}
+
 	log.Println("=== 正在啟動伺服器 ===")
+	log.Printf("📁 資料目錄: %s", cfg.DataDir)
 
-	// 1. 初始化資料庫 (使用常數 DBFile)
-	err := InitDB(DBFile)
-	if err != nil {
+	// 1. 初始化資料庫 (DBFile 由 --data-dir 決定)
+	if err := InitDB(DBFile); err != nil {
 		log.Fatalf("❌ 資料庫連線失敗: %v", err)
 	}
 	log.Println("✅ 資料庫連線成功")
@@ -53,12 +203,58 @@ func main() {
 	// 2. 啟動時自動匯入 JSON 資料
 	RunDataImporter()
 
-	// 3. 檢查資料量
+	// 3. 第一次啟動時匯入內建的中英對照表
+	if n, err := SeedSynonyms(assetFS(), SynonymSeedFile); err != nil {
+		log.Printf("[Synonyms] 匯入對照表失敗: %v", err)
+	} else if n > 0 {
+		log.Printf("📚 已匯入 %d 組同義詞", n)
+	}
+
+	// 4. 檢查資料量
 	count, _ := GetMemeCount()
 	log.Printf("📊 目前資料庫共有 %d 筆資料", count)
 
-	// 4. 啟動 Web Server
-	r := setupRouter()
+	// 5. 在背景補建舊鏡像檔的雜湊與縮圖，再載入以圖搜圖的索引 (請求本身不會解碼圖片)
+	go func() {
+		if n, err := GeneratePendingThumbnails(); err != nil {
+			log.Printf("[Thumbnail] 補產生縮圖失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("🖼️ 已補產生 %d 個縮圖", n)
+		}
+		if n, err := IndexPendingMediaHashes(); err != nil {
+			log.Printf("[ImageHash] 補建雜湊失敗: %v", err)
+		} else if n > 0 {
+			log.Printf("🖼️ 已補建 %d 個檔案的圖片雜湊", n)
+		}
+		if _, err := sharedImageIndex.snapshot(); err != nil {
+			log.Printf("[ImageHash] 載入索引失敗: %v", err)
+		}
+	}()
+
+	// 6. 爬蟲在另一個程序寫入資料，定期讀取後推送給 /api/stream 的連線
+	startMemeFeed()
+
+	// 7. 啟動 gRPC 服務 (GRPC_ADDR，預設 :9090，設為 off 關閉)，同義詞與限流器都與 HTTP 共用，
+	// 限流器只有一個，清理也只啟動一次
+	syn := newSynonymIndex()
+	limiter := NewRateLimiter()
+	stopCleanup := limiter.StartCleanup(10 * time.Minute)
+	defer stopCleanup()
+	serveGRPC(os.Getenv("GRPC_ADDR"), syn, limiter)
+
+	// 8. 新資料符合已儲存的搜尋時送出 webhook 通知 (未送出的會在重新啟動後繼續重試)
+	webhooks := NewWebhookDispatcher(syn, webhookConfigFromEnv())
+	webhooks.Start()
+
+	// 9. 瀏覽次數先累積在記憶體，每隔 STATS_FLUSH_INTERVAL_SEC 秒 (預設 10) 批次寫入
+	stopViews := startViewFlusher(time.Duration(max(envInt("STATS_FLUSH_INTERVAL_SEC", 10), 1)) * time.Second)
+	defer stopViews()
+
+	// 10. 搜尋紀錄保留 SEARCH_LOG_RETENTION_DAYS 天 (預設 90，設為 0 永久保留)
+	startSearchLogCleanup(envInt("SEARCH_LOG_RETENTION_DAYS", 90))
+
+	// 11. 啟動 Web Server
+	r := newRouter(syn, limiter)
 	log.Println("🚀 伺服器運行中: http://localhost:8080")
 	r.Run(":8080")
 }
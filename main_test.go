@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupTestServer 建立暫存資料庫並塞入測試資料，回傳 router
+func setupTestServer(t *testing.T, memes ...ExportMeme) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	// 測試都從同一個 IP 發出，預設放寬匿名限流 (限流測試會自行設定)
+	if os.Getenv("RATE_LIMIT_IP_BURST") == "" {
+		t.Setenv("RATE_LIMIT_IP_BURST", "10000")
+	}
+
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("初始化測試資料庫失敗: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	resetStatsCaches()
+
+	for _, m := range memes {
+		if err := InsertMeme(m); err != nil {
+			t.Fatalf("插入資料失敗 (%s): %v", m.Title, err)
+		}
+	}
+	return setupRouter()
+}
+
+func doRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, nil)
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// doJSON 送出帶 JSON body 的請求
+func doJSON(r http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMemeDetailAndPermalink(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "Those damn tourists", URL: "https://www.gif-vif.com/gmedia/test.gif", Tags: "Those, damn, tourists", SourceURL: "https://www.gif-vif.com/gifs/test"},
+	)
+
+	// 搜尋結果必須帶 id
+	w := doRequest(r, "GET", "/api/search?q=tourists")
+	var results []Meme
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil || len(results) != 1 {
+		t.Fatalf("搜尋結果錯誤: %s", w.Body.String())
+	}
+	id := results[0].ID
+	if id == 0 {
+		t.Fatal("搜尋結果缺少 id")
+	}
+
+	w = doRequest(r, "GET", fmt.Sprintf("/api/memes/%d", id))
+	if w.Code != http.StatusOK {
+		t.Fatalf("預期 200，得到 %d", w.Code)
+	}
+	var meme Meme
+	json.Unmarshal(w.Body.Bytes(), &meme)
+	if meme.ID != id || meme.Title != "Those damn tourists" {
+		t.Errorf("詳細資料不符: %+v", meme)
+	}
+
+	if w := doRequest(r, "GET", "/api/memes/9999"); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的 id 預期 404，得到 %d", w.Code)
+	}
+	if w := doRequest(r, "GET", "/api/memes/abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("錯誤的 id 預期 400，得到 %d", w.Code)
+	}
+
+	w = doRequest(r, "GET", fmt.Sprintf("/m/%d", id))
+	body := w.Body.String()
+	if w.Code != http.StatusOK {
+		t.Fatalf("永久連結頁預期 200，得到 %d", w.Code)
+	}
+	if !strings.Contains(body, `property="og:image" content="https://www.gif-vif.com/gmedia/test.gif"`) {
+		t.Errorf("永久連結頁缺少 og:image: %s", body)
+	}
+	if !strings.Contains(body, fmt.Sprintf(`property="og:url" content="http://example.com/m/%d"`, id)) {
+		t.Error("永久連結頁缺少 og:url")
+	}
+}
+
+func TestBaseURLForwardedProto(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	base := func(proto string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/m/1", nil)
+		if proto != "" {
+			c.Request.Header.Set("X-Forwarded-Proto", proto)
+		}
+		return baseURL(c)
+	}
+
+	// 沒有設定 TRUSTED_PROXIES 時不相信標頭
+	t.Setenv("TRUSTED_PROXIES", "")
+	if got := base("https"); got != "http://example.com" {
+		t.Errorf("未信任代理時應忽略 X-Forwarded-Proto，得到 %s", got)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "127.0.0.1")
+	for proto, want := range map[string]string{
+		"https":           "https://example.com",
+		"http":            "http://example.com",
+		"":                "http://example.com",
+		"javascript":      "http://example.com",
+		`https"><script>`: "http://example.com",
+	} {
+		if got := base(proto); got != want {
+			t.Errorf("X-Forwarded-Proto=%q 預期 %s，得到 %s", proto, want, got)
+		}
+	}
+}
+
+func TestSearchGifMetadata(t *testing.T) {
+	r := setupTestServer(t, ExportMeme{
+		Title: "Cat claimed his hooman", URL: "https://www.gif-vif.com/gmedia/cat.gif", Tags: "cat, hooman",
+		SourceURL: "https://www.gif-vif.com/gifs/cat", Description: "A very sleepy kitten", Category: "animals",
+		UploadedAt: "2025-12-03", SourceViews: 30,
+	})
+
+	// 描述與分類也能被搜尋到
+	for _, q := range []string{"sleepy", "animals"} {
+		var results []Meme
+		json.Unmarshal(doRequest(r, "GET", "/api/search?q="+q).Body.Bytes(), &results)
+		if len(results) != 1 || results[0].UploadedAt != "2025-12-03" || results[0].SourceViews != 30 {
+			t.Errorf("搜尋 %q 結果不符: %+v", q, results)
+		}
+	}
+}
+
+func TestServerRenderedSearchPages(t *testing.T) {
+	memes := []ExportMeme{
+		{Title: "<script>alert(1)</script>", URL: strings.Repeat("前情提要。", 40) + "結果老闆說 <b>好笑</b>", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.0.A.html"},
+	}
+	for i := 1; i <= 25; i++ {
+		memes = append(memes, ExportMeme{Title: fmt.Sprintf("好笑的圖 %d", i), URL: fmt.Sprintf("https://example.com/%d.gif", i), SourceURL: fmt.Sprintf("https://www.gif-vif.com/gifs/%d", i)})
+	}
+	r := setupTestServer(t, memes...)
+
+	w := doRequest(r, "GET", "/search?q=%E5%A5%BD%E7%AC%91")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || strings.Count(body, `class="meme-card"`) != 20 {
+		t.Fatalf("第一頁預期 20 筆，得到 %d: %d", strings.Count(body, `class="meme-card"`), w.Code)
+	}
+	if !strings.Contains(body, `href="/search?page=2&amp;q=%E5%A5%BD%E7%AC%91"`) || strings.Contains(body, "上一頁") {
+		t.Errorf("第一頁應只有下一頁連結")
+	}
+	if strings.Contains(body, "<script>") || strings.Contains(body, "<b>") {
+		t.Errorf("標題與內文必須跳脫")
+	}
+	if !strings.Contains(body, `value="好笑"`) {
+		t.Errorf("搜尋框應保留關鍵字")
+	}
+
+	w = doRequest(r, "GET", "/search?q=%E5%A5%BD%E7%AC%91&page=2")
+	body = w.Body.String()
+	if strings.Count(body, `class="meme-card"`) != 6 || !strings.Contains(body, "上一頁") || strings.Contains(body, "下一頁") {
+		t.Errorf("第二頁預期 6 筆且只有上一頁")
+	}
+	// 長文顯示摘要，命中的部分以 <mark> 標示 (內容仍然跳脫)
+	if !strings.Contains(body, "結果老闆說 &lt;b&gt;<mark>好笑</mark>&lt;/b&gt;") || !strings.Contains(body, "顯示全文") {
+		t.Errorf("摘要標示不符: %s", body)
+	}
+
+	if w := doRequest(r, "GET", "/search?q=kind:video"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "image 或 text") {
+		t.Errorf("語法錯誤應回傳 400 並顯示原因，得到 %d", w.Code)
+	}
+	if w := doRequest(r, "GET", "/search?q=%E4%B8%8D%E5%AD%98%E5%9C%A8"); !strings.Contains(w.Body.String(), "找不到結果") {
+		t.Errorf("沒有結果時應顯示提示")
+	}
+	if w := doRequest(r, "GET", "/search"); w.Code != http.StatusOK || strings.Contains(w.Body.String(), `class="meme-card"`) {
+		t.Errorf("沒有 q 時只顯示表單")
+	}
+
+	w = doRequest(r, "GET", "/random?mode=text")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "&lt;script&gt;") || w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("隨機頁應抽到唯一的文字資料: %d", w.Code)
+	}
+}
+
+// kindSQL 與 matchesKind 對同一筆資料要有相同的判斷
+func TestKindSQLMatchesGo(t *testing.T) {
+	setupTestServer(t,
+		ExportMeme{Title: "GIF", URL: "https://example.com/1.gif", SourceURL: "https://www.gif-vif.com/gifs/1"},
+		ExportMeme{Title: "列表頁", URL: "https://example.com/list", SourceURL: "https://www.gif-vif.com/gifsearch?q=cat"},
+		ExportMeme{Title: "複製文", URL: "內容", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html"},
+	)
+	for _, kind := range []string{"image", "text"} {
+		results, err := SearchMemesWithOptions(SearchOptions{Mode: kind})
+		if err != nil {
+			t.Fatalf("搜尋失敗: %v", err)
+		}
+		all, _ := SearchMemesWithOptions(SearchOptions{})
+		var want int
+		for _, m := range all {
+			if matchesKind(m, kind) {
+				want++
+			}
+		}
+		if len(results) != want {
+			t.Errorf("%s 的 SQL 條件找到 %d 筆，Go 判斷為 %d 筆", kind, len(results), want)
+		}
+	}
+	if m, _ := GetMemeByID(2); matchesKind(m, "image") {
+		t.Error("不是 GIF 詳細頁的網址不應視為圖片")
+	}
+}
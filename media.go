@@ -0,0 +1,288 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [媒體鏡像：把圖片/影片存到本地，不再依賴來源網站]
+// =========================================================
+
+// MediaDir 是鏡像檔案的根目錄，檔案以 SHA-256 內容雜湊命名 (media/ab/abcdef...)
+var MediaDir = "./media"
+
+const maxMediaSize = 50 << 20 // 50 MB
+
+var ErrNotMedia = errors.New("不是圖片或影片網址")
+var ErrMediaTooLarge = errors.New("檔案超過大小上限")
+var ErrUnsupportedMedia = errors.New("只接受圖片或影片 (不含 SVG)")
+
+var mediaClient = &http.Client{Timeout: 60 * time.Second}
+
+var mediaHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// 判斷規則與 index.html 的 renderMeme 保持一致
+var imageExts = []string{".gif", ".jpg", ".jpeg", ".png", ".webp"}
+var videoExts = []string{".mp4", ".webm"}
+
+func isMediaURL(url string, exts []string) bool {
+	lower := strings.ToLower(url)
+	if !strings.HasPrefix(lower, "http") {
+		return false
+	}
+	for _, ext := range exts {
+		if strings.Contains(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isImageURL(url string) bool { return isMediaURL(url, imageExts) }
+func isVideoURL(url string) bool { return isMediaURL(url, videoExts) }
+
+// safeMediaType 回傳去掉參數的小寫 mime，不是圖片或影片時回傳空字串。
+// 鏡像檔案和網站同源，HTML 或 SVG (可以內嵌 script) 一旦被存下來就是儲存型 XSS
+func safeMediaType(mimeType string) string {
+	base, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return ""
+	}
+	if base == "image/svg+xml" || !(strings.HasPrefix(base, "image/") || strings.HasPrefix(base, "video/")) {
+		return ""
+	}
+	return base
+}
+
+type MediaAsset struct {
+	Hash      string `json:"hash"`
+	SourceURL string `json:"source_url"`
+	MimeType  string `json:"mime_type"`
+	Size      int64  `json:"size"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	CreatedAt string `json:"created_at"`
+}
+
+func mediaPath(hash string) string {
+	return filepath.Join(MediaDir, hash[:2], hash)
+}
+
+func GetMediaAsset(hash string) (MediaAsset, error) {
+	var a MediaAsset
+	err := db.QueryRow(`SELECT hash, source_url, mime_type, size, width, height, created_at FROM media WHERE hash = ?`, hash).
+		Scan(&a.Hash, &a.SourceURL, &a.MimeType, &a.Size, &a.Width, &a.Height, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return MediaAsset{}, ErrNotFound
+	}
+	return a, err
+}
+
+// MirrorMedia 下載檔案並以內容雜湊存檔，相同內容只會存一份。
+// referer 會帶在請求中，部分網站沒有 Referer 會拒絕下載
+func MirrorMedia(url, referer string) (MediaAsset, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return MediaAsset{}, err
+	}
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	resp, err := mediaClient.Do(req)
+	if err != nil {
+		return MediaAsset{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return MediaAsset{}, fmt.Errorf("下載失敗: HTTP %d", resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(MediaDir, 0755); err != nil {
+		return MediaAsset{}, err
+	}
+	tmp, err := os.CreateTemp(MediaDir, "download-*")
+	if err != nil {
+		return MediaAsset{}, err
+	}
+	defer os.Remove(tmp.Name()) // 成功時已被改名，這裡只會清掉失敗的暫存檔
+	defer tmp.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(resp.Body, maxMediaSize+1))
+	if err != nil {
+		return MediaAsset{}, err
+	}
+	if size > maxMediaSize {
+		return MediaAsset{}, ErrMediaTooLarge
+	}
+
+	asset := MediaAsset{Hash: hex.EncodeToString(h.Sum(nil)), SourceURL: url, Size: size}
+
+	// 依檔案內容判斷格式，判斷不出來才相信伺服器給的 Content-Type
+	head := make([]byte, 512)
+	n, _ := tmp.ReadAt(head, 0)
+	asset.MimeType = http.DetectContentType(head[:n])
+	if asset.MimeType == "application/octet-stream" && resp.Header.Get("Content-Type") != "" {
+		asset.MimeType = resp.Header.Get("Content-Type")
+	}
+	if asset.MimeType = safeMediaType(asset.MimeType); asset.MimeType == "" {
+		return MediaAsset{}, ErrUnsupportedMedia
+	}
+	if strings.HasPrefix(asset.MimeType, "image/") {
+		tmp.Seek(0, io.SeekStart)
+		if cfg, _, err := image.DecodeConfig(tmp); err == nil {
+			asset.Width, asset.Height = cfg.Width, cfg.Height
+		}
+	}
+	tmp.Close()
+
+	path := mediaPath(asset.Hash)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return MediaAsset{}, err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return MediaAsset{}, err
+		}
+	}
+
+	_, err = db.Exec(`INSERT OR IGNORE INTO media (hash, source_url, mime_type, size, width, height) VALUES (?, ?, ?, ?, ?, ?)`,
+		asset.Hash, asset.SourceURL, asset.MimeType, asset.Size, asset.Width, asset.Height)
+	if err != nil {
+		return MediaAsset{}, err
+	}
+	if asset.MimeType == "image/gif" {
+		// 縮圖失敗不影響鏡像，之後由 GeneratePendingThumbnails 再試
+		if err := GenerateThumbnails(asset.Hash); err != nil {
+			log.Printf("[Media] 產生縮圖失敗 %s: %v", asset.Hash, err)
+		}
+	}
+	if strings.HasPrefix(asset.MimeType, "image/") {
+		// 以圖搜圖的雜湊在這裡建立，搜尋時只需要讀取
+		if err := IndexMediaHashes(asset.Hash); err != nil {
+			log.Printf("[Media] 建立圖片雜湊失敗 %s: %v", asset.Hash, err)
+		}
+	}
+	return GetMediaAsset(asset.Hash)
+}
+
+// MirrorMemeMedia 鏡像一筆資料的圖片/影片並記錄在 memes.media_hash，已鏡像過的不會重複下載
+func MirrorMemeMedia(m ExportMeme) (MediaAsset, error) {
+	if !isImageURL(m.URL) && !isVideoURL(m.URL) {
+		return MediaAsset{}, ErrNotMedia
+	}
+
+	var existing sql.NullString
+	db.QueryRow(`SELECT media_hash FROM memes WHERE url = ?`, m.URL).Scan(&existing)
+	if existing.String != "" {
+		return GetMediaAsset(existing.String)
+	}
+
+	asset, err := MirrorMedia(m.URL, m.SourceURL)
+	if err != nil {
+		return MediaAsset{}, err
+	}
+	_, err = db.Exec(`UPDATE memes SET media_hash = ?, media_thumbnails = ? WHERE url = ?`, asset.Hash, thumbnailsReady(asset), m.URL)
+	return asset, err
+}
+
+// MirrorPendingMedia 補抓還沒鏡像的資料 (例如舊版爬下來的)，回傳成功筆數
+func MirrorPendingMedia(limit int) (int, error) {
+	memes, err := queryMemes(`SELECT `+memeColumns+` FROM memes
+		WHERE media_hash IS NULL AND url LIKE 'http%' AND `+visibleSQL+` ORDER BY id LIMIT ?`, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range memes {
+		if _, err := MirrorMemeMedia(m); err != nil {
+			if !errors.Is(err, ErrNotMedia) {
+				log.Printf("[Media] 鏡像失敗 #%d %s: %v", m.ID, m.URL, err)
+			}
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+// mediaHandler 提供 /media/:hash。內容以雜湊命名永遠不會變，可以放心長期快取；
+// http.ServeContent 會處理 Range (影片拖拉) 與 If-None-Match
+func mediaHandler(c *gin.Context) {
+	hash := c.Param("hash")
+	if !mediaHashPattern.MatchString(hash) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	asset, err := GetMediaAsset(hash)
+	// 修正前鏡像的檔案可能是 HTML 之類的格式，一律不提供
+	if err != nil || safeMediaType(asset.MimeType) == "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	serveMediaFile(c, mediaPath(hash), asset.MimeType, `"`+hash+`"`)
+}
+
+// thumbnailHandler 提供 /media/:hash/poster 與 /media/:hash/preview。
+// 縮圖在鏡像時產生，這裡只讀檔案，還沒有縮圖時回傳 404 (不在請求中解碼整個 GIF)
+func thumbnailHandler(c *gin.Context) {
+	hash, kind := c.Param("hash"), c.Param("kind")
+	if !mediaHashPattern.MatchString(hash) || (kind != thumbPoster && kind != thumbPreview) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if _, err := GetMediaAsset(hash); err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	path := thumbnailPath(hash, kind)
+	mimeType := "image/gif"
+	if kind == thumbPoster {
+		mimeType = "image/jpeg"
+	}
+	serveMediaFile(c, path, mimeType, `"`+hash+"-"+kind+`"`)
+}
+
+func serveMediaFile(c *gin.Context, path, mimeType, etag string) {
+	f, err := os.Open(path)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Content-Type", mimeType)
+	c.Header("X-Content-Type-Options", "nosniff") // 不讓瀏覽器自行猜測格式
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+	http.ServeContent(c.Writer, c.Request, "", info.ModTime(), f)
+}
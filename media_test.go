@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMirrorMedia(t *testing.T) {
+	var buf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 3, 2), color.Palette{color.Black, color.White})
+	gif.Encode(&buf, img, nil)
+	payload := buf.Bytes()
+
+	var gotReferer string
+	downloads := 0
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotReferer = r.Referer()
+		downloads++
+		w.Write(payload)
+	}))
+	defer origin.Close()
+
+	MediaDir = t.TempDir()
+	meme := ExportMeme{Title: "會動的貓", URL: origin.URL + "/cat.gif", SourceURL: "https://www.gif-vif.com/gifs/cat"}
+	r := setupTestServer(t, meme, ExportMeme{Title: "純文字", URL: "沒有圖", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1"})
+
+	asset, err := MirrorMemeMedia(meme)
+	if err != nil {
+		t.Fatalf("鏡像失敗: %v", err)
+	}
+	if asset.MimeType != "image/gif" || asset.Width != 3 || asset.Height != 2 || asset.Size != int64(len(payload)) {
+		t.Errorf("媒體資訊不符: %+v", asset)
+	}
+	if gotReferer != meme.SourceURL {
+		t.Errorf("下載時應帶上來源頁面作為 Referer，得到 %q", gotReferer)
+	}
+
+	// 已鏡像過的不會重新下載，純文字不處理
+	if n, _ := MirrorPendingMedia(10); n != 0 || downloads != 1 {
+		t.Errorf("不應重複下載: 成功 %d 筆，下載 %d 次", n, downloads)
+	}
+
+	var got Meme
+	json.Unmarshal(doRequest(r, "GET", "/api/memes/1").Body.Bytes(), &got)
+	if got.MediaURL != "/media/"+asset.Hash {
+		t.Fatalf("media_url 不符: %q", got.MediaURL)
+	}
+
+	w := doRequest(r, "GET", got.MediaURL)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), payload) {
+		t.Fatalf("讀取鏡像失敗: %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "image/gif" || w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" ||
+		w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("標頭不符: %v", w.Header())
+	}
+
+	// Range 請求 (影片拖拉時會用到)
+	req := httptest.NewRequest("GET", got.MediaURL, nil)
+	req.Header.Set("Range", "bytes=0-5")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "GIF89a" {
+		t.Errorf("Range 請求預期 206 與 GIF89a，得到 %d %q", w.Code, w.Body.String())
+	}
+
+	// ETag 沒變就不用重傳
+	req = httptest.NewRequest("GET", got.MediaURL, nil)
+	req.Header.Set("If-None-Match", `"`+asset.Hash+`"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match 預期 304，得到 %d", w.Code)
+	}
+
+	if w := doRequest(r, "GET", "/media/../../etc/passwd"); w.Code != http.StatusNotFound {
+		t.Errorf("非法雜湊預期 404，得到 %d", w.Code)
+	}
+}
+
+func TestMirrorMediaRejectsNonMedia(t *testing.T) {
+	bodies := map[string]struct{ contentType, body string }{
+		"/page.gif":  {"text/html", "<html><script>alert(1)</script></html>"},
+		"/blob.gif":  {"text/html; charset=utf-8", "\x00\x01\x02 不是圖片"},
+		"/image.gif": {"image/svg+xml", "\x00\x01\x02<svg onload=alert(1)>"},
+	}
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := bodies[r.URL.Path]
+		w.Header().Set("Content-Type", b.contentType)
+		w.Write([]byte(b.body))
+	}))
+	defer origin.Close()
+
+	MediaDir = t.TempDir()
+	setupTestServer(t)
+	for path := range bodies {
+		if _, err := MirrorMedia(origin.URL+path, ""); !errors.Is(err, ErrUnsupportedMedia) {
+			t.Errorf("%s 預期 ErrUnsupportedMedia，得到 %v", path, err)
+		}
+	}
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM media`).Scan(&n)
+	if n != 0 {
+		t.Errorf("不應記錄任何檔案，得到 %d 筆", n)
+	}
+
+	for mimeType, want := range map[string]string{
+		"image/gif": "image/gif", "Video/MP4": "video/mp4", "image/png; x=1": "image/png",
+		"image/svg+xml": "", "text/html": "", "application/octet-stream": "", "": "",
+	} {
+		if got := safeMediaType(mimeType); got != want {
+			t.Errorf("safeMediaType(%q) = %q，預期 %q", mimeType, got, want)
+		}
+	}
+}
@@ -0,0 +1,854 @@
+// 梗圖搜尋的 gRPC 服務，給聊天機器人、Slack 整合等後端服務使用。
+// 修改後以 protoc-gen-go 與 protoc-gen-go-grpc 重新產生 meme.pb.go / meme_grpc.pb.go：
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative memepb/meme.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: memepb/meme.proto
+
+package memepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Mode 對應 HTTP API 的 mode=all / image / text
+type Mode int32
+
+const (
+	Mode_MODE_ALL   Mode = 0
+	Mode_MODE_IMAGE Mode = 1 // 只看 GIF
+	Mode_MODE_TEXT  Mode = 2 // 只看複製文
+)
+
+// Enum value maps for Mode.
+var (
+	Mode_name = map[int32]string{
+		0: "MODE_ALL",
+		1: "MODE_IMAGE",
+		2: "MODE_TEXT",
+	}
+	Mode_value = map[string]int32{
+		"MODE_ALL":   0,
+		"MODE_IMAGE": 1,
+		"MODE_TEXT":  2,
+	}
+)
+
+func (x Mode) Enum() *Mode {
+	p := new(Mode)
+	*p = x
+	return p
+}
+
+func (x Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_memepb_meme_proto_enumTypes[0].Descriptor()
+}
+
+func (Mode) Type() protoreflect.EnumType {
+	return &file_memepb_meme_proto_enumTypes[0]
+}
+
+func (x Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Mode.Descriptor instead.
+func (Mode) EnumDescriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{0}
+}
+
+// Sort 對應 HTTP API 的 sort=new / hot / top
+type Sort int32
+
+const (
+	Sort_SORT_NEW Sort = 0
+	Sort_SORT_HOT Sort = 1
+	Sort_SORT_TOP Sort = 2
+)
+
+// Enum value maps for Sort.
+var (
+	Sort_name = map[int32]string{
+		0: "SORT_NEW",
+		1: "SORT_HOT",
+		2: "SORT_TOP",
+	}
+	Sort_value = map[string]int32{
+		"SORT_NEW": 0,
+		"SORT_HOT": 1,
+		"SORT_TOP": 2,
+	}
+)
+
+func (x Sort) Enum() *Sort {
+	p := new(Sort)
+	*p = x
+	return p
+}
+
+func (x Sort) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Sort) Descriptor() protoreflect.EnumDescriptor {
+	return file_memepb_meme_proto_enumTypes[1].Descriptor()
+}
+
+func (Sort) Type() protoreflect.EnumType {
+	return &file_memepb_meme_proto_enumTypes[1]
+}
+
+func (x Sort) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Sort.Descriptor instead.
+func (Sort) EnumDescriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{1}
+}
+
+// Highlight 是摘要中命中關鍵字的範圍，以 Unicode 字元 (rune) 計算
+type Highlight struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int32                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"` // 不含
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Highlight) Reset() {
+	*x = Highlight{}
+	mi := &file_memepb_meme_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Highlight) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Highlight) ProtoMessage() {}
+
+func (x *Highlight) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Highlight.ProtoReflect.Descriptor instead.
+func (*Highlight) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Highlight) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Highlight) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+type Meme struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title        string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Url          string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"` // 圖片網址或複製文內文
+	Tags         []string               `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Source       string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"` // gif / ptt / threads / plurk / other
+	SourceUrl    string                 `protobuf:"bytes,6,opt,name=source_url,json=sourceUrl,proto3" json:"source_url,omitempty"`
+	Author       string                 `protobuf:"bytes,7,opt,name=author,proto3" json:"author,omitempty"`
+	MediaUrl     string                 `protobuf:"bytes,8,opt,name=media_url,json=mediaUrl,proto3" json:"media_url,omitempty"` // 鏡像到本站的網址 (/media/:hash)
+	ThumbnailUrl string                 `protobuf:"bytes,9,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	PreviewUrl   string                 `protobuf:"bytes,10,opt,name=preview_url,json=previewUrl,proto3" json:"preview_url,omitempty"`
+	Description  string                 `protobuf:"bytes,11,opt,name=description,proto3" json:"description,omitempty"`
+	Category     string                 `protobuf:"bytes,12,opt,name=category,proto3" json:"category,omitempty"`
+	UploadedAt   string                 `protobuf:"bytes,13,opt,name=uploaded_at,json=uploadedAt,proto3" json:"uploaded_at,omitempty"` // YYYY-MM-DD
+	SourceViews  int64                  `protobuf:"varint,14,opt,name=source_views,json=sourceViews,proto3" json:"source_views,omitempty"`
+	Permalink    string                 `protobuf:"bytes,15,opt,name=permalink,proto3" json:"permalink,omitempty"` // /m/:id
+	// 以下只有搜尋結果才有
+	MatchedTerms  []string     `protobuf:"bytes,16,rep,name=matched_terms,json=matchedTerms,proto3" json:"matched_terms,omitempty"`
+	Snippet       string       `protobuf:"bytes,17,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	Highlights    []*Highlight `protobuf:"bytes,18,rep,name=highlights,proto3" json:"highlights,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Meme) Reset() {
+	*x = Meme{}
+	mi := &file_memepb_meme_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Meme) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Meme) ProtoMessage() {}
+
+func (x *Meme) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Meme.ProtoReflect.Descriptor instead.
+func (*Meme) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Meme) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Meme) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Meme) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Meme) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Meme) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Meme) GetSourceUrl() string {
+	if x != nil {
+		return x.SourceUrl
+	}
+	return ""
+}
+
+func (x *Meme) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Meme) GetMediaUrl() string {
+	if x != nil {
+		return x.MediaUrl
+	}
+	return ""
+}
+
+func (x *Meme) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *Meme) GetPreviewUrl() string {
+	if x != nil {
+		return x.PreviewUrl
+	}
+	return ""
+}
+
+func (x *Meme) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Meme) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Meme) GetUploadedAt() string {
+	if x != nil {
+		return x.UploadedAt
+	}
+	return ""
+}
+
+func (x *Meme) GetSourceViews() int64 {
+	if x != nil {
+		return x.SourceViews
+	}
+	return 0
+}
+
+func (x *Meme) GetPermalink() string {
+	if x != nil {
+		return x.Permalink
+	}
+	return ""
+}
+
+func (x *Meme) GetMatchedTerms() []string {
+	if x != nil {
+		return x.MatchedTerms
+	}
+	return nil
+}
+
+func (x *Meme) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+func (x *Meme) GetHighlights() []*Highlight {
+	if x != nil {
+		return x.Highlights
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"` // 支援搜尋語法 (title:、tag:、"完整片語"、-排除…)，空字串列出全部
+	Mode          Mode                   `protobuf:"varint,2,opt,name=mode,proto3,enum=meme.v1.Mode" json:"mode,omitempty"`
+	Sort          Sort                   `protobuf:"varint,3,opt,name=sort,proto3,enum=meme.v1.Sort" json:"sort,omitempty"`
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`   // 1–50，0 表示預設 20
+	Offset        int32                  `protobuf:"varint,5,opt,name=offset,proto3" json:"offset,omitempty"` // 最多 1000
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_memepb_meme_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_ALL
+}
+
+func (x *SearchRequest) GetSort() Sort {
+	if x != nil {
+		return x.Sort
+	}
+	return Sort_SORT_NEW
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type SearchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Memes         []*Meme                `protobuf:"bytes,1,rep,name=memes,proto3" json:"memes,omitempty"`
+	Expansions    []string               `protobuf:"bytes,2,rep,name=expansions,proto3" json:"expansions,omitempty"`                    // 同義詞展開的關鍵字
+	NextOffset    int32                  `protobuf:"varint,3,opt,name=next_offset,json=nextOffset,proto3" json:"next_offset,omitempty"` // 本頁筆數等於 limit 時為下一頁的 offset，否則為 0
+	SearchId      int64                  `protobuf:"varint,4,opt,name=search_id,json=searchId,proto3" json:"search_id,omitempty"`       // 搜尋分析的紀錄 id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_memepb_meme_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchResponse) GetMemes() []*Meme {
+	if x != nil {
+		return x.Memes
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetExpansions() []string {
+	if x != nil {
+		return x.Expansions
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetNextOffset() int32 {
+	if x != nil {
+		return x.NextOffset
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetSearchId() int64 {
+	if x != nil {
+		return x.SearchId
+	}
+	return 0
+}
+
+type RandomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mode          Mode                   `protobuf:"varint,1,opt,name=mode,proto3,enum=meme.v1.Mode" json:"mode,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"` // 1–20，0 表示 1
+	Seed          string                 `protobuf:"bytes,3,opt,name=seed,proto3" json:"seed,omitempty"`    // 相同的 seed 在資料不變時抽到相同結果
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RandomRequest) Reset() {
+	*x = RandomRequest{}
+	mi := &file_memepb_meme_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RandomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RandomRequest) ProtoMessage() {}
+
+func (x *RandomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RandomRequest.ProtoReflect.Descriptor instead.
+func (*RandomRequest) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RandomRequest) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_ALL
+}
+
+func (x *RandomRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *RandomRequest) GetSeed() string {
+	if x != nil {
+		return x.Seed
+	}
+	return ""
+}
+
+type RandomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Memes         []*Meme                `protobuf:"bytes,1,rep,name=memes,proto3" json:"memes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RandomResponse) Reset() {
+	*x = RandomResponse{}
+	mi := &file_memepb_meme_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RandomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RandomResponse) ProtoMessage() {}
+
+func (x *RandomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RandomResponse.ProtoReflect.Descriptor instead.
+func (*RandomResponse) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RandomResponse) GetMemes() []*Meme {
+	if x != nil {
+		return x.Memes
+	}
+	return nil
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_memepb_meme_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mode          Mode                   `protobuf:"varint,1,opt,name=mode,proto3,enum=meme.v1.Mode" json:"mode,omitempty"`
+	AfterId       int64                  `protobuf:"varint,2,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"` // 先補送 id 大於此值的資料；0 表示只接收之後新增的
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_memepb_meme_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_memepb_meme_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_memepb_meme_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchRequest) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_ALL
+}
+
+func (x *WatchRequest) GetAfterId() int64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+var File_memepb_meme_proto protoreflect.FileDescriptor
+
+const file_memepb_meme_proto_rawDesc = "" +
+	"\n" +
+	"\x11memepb/meme.proto\x12\ameme.v1\"3\n" +
+	"\tHighlight\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x05R\x03end\"\x97\x04\n" +
+	"\x04Meme\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12\x12\n" +
+	"\x04tags\x18\x04 \x03(\tR\x04tags\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12\x1d\n" +
+	"\n" +
+	"source_url\x18\x06 \x01(\tR\tsourceUrl\x12\x16\n" +
+	"\x06author\x18\a \x01(\tR\x06author\x12\x1b\n" +
+	"\tmedia_url\x18\b \x01(\tR\bmediaUrl\x12#\n" +
+	"\rthumbnail_url\x18\t \x01(\tR\fthumbnailUrl\x12\x1f\n" +
+	"\vpreview_url\x18\n" +
+	" \x01(\tR\n" +
+	"previewUrl\x12 \n" +
+	"\vdescription\x18\v \x01(\tR\vdescription\x12\x1a\n" +
+	"\bcategory\x18\f \x01(\tR\bcategory\x12\x1f\n" +
+	"\vuploaded_at\x18\r \x01(\tR\n" +
+	"uploadedAt\x12!\n" +
+	"\fsource_views\x18\x0e \x01(\x03R\vsourceViews\x12\x1c\n" +
+	"\tpermalink\x18\x0f \x01(\tR\tpermalink\x12#\n" +
+	"\rmatched_terms\x18\x10 \x03(\tR\fmatchedTerms\x12\x18\n" +
+	"\asnippet\x18\x11 \x01(\tR\asnippet\x122\n" +
+	"\n" +
+	"highlights\x18\x12 \x03(\v2\x12.meme.v1.HighlightR\n" +
+	"highlights\"\x99\x01\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12!\n" +
+	"\x04mode\x18\x02 \x01(\x0e2\r.meme.v1.ModeR\x04mode\x12!\n" +
+	"\x04sort\x18\x03 \x01(\x0e2\r.meme.v1.SortR\x04sort\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x05 \x01(\x05R\x06offset\"\x93\x01\n" +
+	"\x0eSearchResponse\x12#\n" +
+	"\x05memes\x18\x01 \x03(\v2\r.meme.v1.MemeR\x05memes\x12\x1e\n" +
+	"\n" +
+	"expansions\x18\x02 \x03(\tR\n" +
+	"expansions\x12\x1f\n" +
+	"\vnext_offset\x18\x03 \x01(\x05R\n" +
+	"nextOffset\x12\x1b\n" +
+	"\tsearch_id\x18\x04 \x01(\x03R\bsearchId\"\\\n" +
+	"\rRandomRequest\x12!\n" +
+	"\x04mode\x18\x01 \x01(\x0e2\r.meme.v1.ModeR\x04mode\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x12\x12\n" +
+	"\x04seed\x18\x03 \x01(\tR\x04seed\"5\n" +
+	"\x0eRandomResponse\x12#\n" +
+	"\x05memes\x18\x01 \x03(\v2\r.meme.v1.MemeR\x05memes\"\x1c\n" +
+	"\n" +
+	"GetRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"L\n" +
+	"\fWatchRequest\x12!\n" +
+	"\x04mode\x18\x01 \x01(\x0e2\r.meme.v1.ModeR\x04mode\x12\x19\n" +
+	"\bafter_id\x18\x02 \x01(\x03R\aafterId*3\n" +
+	"\x04Mode\x12\f\n" +
+	"\bMODE_ALL\x10\x00\x12\x0e\n" +
+	"\n" +
+	"MODE_IMAGE\x10\x01\x12\r\n" +
+	"\tMODE_TEXT\x10\x02*0\n" +
+	"\x04Sort\x12\f\n" +
+	"\bSORT_NEW\x10\x00\x12\f\n" +
+	"\bSORT_HOT\x10\x01\x12\f\n" +
+	"\bSORT_TOP\x10\x022\xe7\x01\n" +
+	"\vMemeService\x129\n" +
+	"\x06Search\x12\x16.meme.v1.SearchRequest\x1a\x17.meme.v1.SearchResponse\x129\n" +
+	"\x06Random\x12\x16.meme.v1.RandomRequest\x1a\x17.meme.v1.RandomResponse\x12)\n" +
+	"\x03Get\x12\x13.meme.v1.GetRequest\x1a\r.meme.v1.Meme\x127\n" +
+	"\rWatchNewMemes\x12\x15.meme.v1.WatchRequest\x1a\r.meme.v1.Meme0\x01B\x10Z\x0egofinal/memepbb\x06proto3"
+
+var (
+	file_memepb_meme_proto_rawDescOnce sync.Once
+	file_memepb_meme_proto_rawDescData []byte
+)
+
+func file_memepb_meme_proto_rawDescGZIP() []byte {
+	file_memepb_meme_proto_rawDescOnce.Do(func() {
+		file_memepb_meme_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_memepb_meme_proto_rawDesc), len(file_memepb_meme_proto_rawDesc)))
+	})
+	return file_memepb_meme_proto_rawDescData
+}
+
+var file_memepb_meme_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_memepb_meme_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_memepb_meme_proto_goTypes = []any{
+	(Mode)(0),              // 0: meme.v1.Mode
+	(Sort)(0),              // 1: meme.v1.Sort
+	(*Highlight)(nil),      // 2: meme.v1.Highlight
+	(*Meme)(nil),           // 3: meme.v1.Meme
+	(*SearchRequest)(nil),  // 4: meme.v1.SearchRequest
+	(*SearchResponse)(nil), // 5: meme.v1.SearchResponse
+	(*RandomRequest)(nil),  // 6: meme.v1.RandomRequest
+	(*RandomResponse)(nil), // 7: meme.v1.RandomResponse
+	(*GetRequest)(nil),     // 8: meme.v1.GetRequest
+	(*WatchRequest)(nil),   // 9: meme.v1.WatchRequest
+}
+var file_memepb_meme_proto_depIdxs = []int32{
+	2,  // 0: meme.v1.Meme.highlights:type_name -> meme.v1.Highlight
+	0,  // 1: meme.v1.SearchRequest.mode:type_name -> meme.v1.Mode
+	1,  // 2: meme.v1.SearchRequest.sort:type_name -> meme.v1.Sort
+	3,  // 3: meme.v1.SearchResponse.memes:type_name -> meme.v1.Meme
+	0,  // 4: meme.v1.RandomRequest.mode:type_name -> meme.v1.Mode
+	3,  // 5: meme.v1.RandomResponse.memes:type_name -> meme.v1.Meme
+	0,  // 6: meme.v1.WatchRequest.mode:type_name -> meme.v1.Mode
+	4,  // 7: meme.v1.MemeService.Search:input_type -> meme.v1.SearchRequest
+	6,  // 8: meme.v1.MemeService.Random:input_type -> meme.v1.RandomRequest
+	8,  // 9: meme.v1.MemeService.Get:input_type -> meme.v1.GetRequest
+	9,  // 10: meme.v1.MemeService.WatchNewMemes:input_type -> meme.v1.WatchRequest
+	5,  // 11: meme.v1.MemeService.Search:output_type -> meme.v1.SearchResponse
+	7,  // 12: meme.v1.MemeService.Random:output_type -> meme.v1.RandomResponse
+	3,  // 13: meme.v1.MemeService.Get:output_type -> meme.v1.Meme
+	3,  // 14: meme.v1.MemeService.WatchNewMemes:output_type -> meme.v1.Meme
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_memepb_meme_proto_init() }
+func file_memepb_meme_proto_init() {
+	if File_memepb_meme_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_memepb_meme_proto_rawDesc), len(file_memepb_meme_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_memepb_meme_proto_goTypes,
+		DependencyIndexes: file_memepb_meme_proto_depIdxs,
+		EnumInfos:         file_memepb_meme_proto_enumTypes,
+		MessageInfos:      file_memepb_meme_proto_msgTypes,
+	}.Build()
+	File_memepb_meme_proto = out.File
+	file_memepb_meme_proto_goTypes = nil
+	file_memepb_meme_proto_depIdxs = nil
+}
@@ -0,0 +1,249 @@
+// 梗圖搜尋的 gRPC 服務，給聊天機器人、Slack 整合等後端服務使用。
+// 修改後以 protoc-gen-go 與 protoc-gen-go-grpc 重新產生 meme.pb.go / meme_grpc.pb.go：
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative memepb/meme.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: memepb/meme.proto
+
+package memepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MemeService_Search_FullMethodName        = "/meme.v1.MemeService/Search"
+	MemeService_Random_FullMethodName        = "/meme.v1.MemeService/Random"
+	MemeService_Get_FullMethodName           = "/meme.v1.MemeService/Get"
+	MemeService_WatchNewMemes_FullMethodName = "/meme.v1.MemeService/WatchNewMemes"
+)
+
+// MemeServiceClient is the client API for MemeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MemeServiceClient interface {
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// 找不到資料時回傳 NOT_FOUND
+	Random(ctx context.Context, in *RandomRequest, opts ...grpc.CallOption) (*RandomResponse, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Meme, error)
+	// 持續推送新寫入的資料 (例如爬蟲剛抓到的)，直到客戶端取消
+	WatchNewMemes(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Meme], error)
+}
+
+type memeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMemeServiceClient(cc grpc.ClientConnInterface) MemeServiceClient {
+	return &memeServiceClient{cc}
+}
+
+func (c *memeServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, MemeService_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memeServiceClient) Random(ctx context.Context, in *RandomRequest, opts ...grpc.CallOption) (*RandomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RandomResponse)
+	err := c.cc.Invoke(ctx, MemeService_Random_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memeServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Meme, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Meme)
+	err := c.cc.Invoke(ctx, MemeService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *memeServiceClient) WatchNewMemes(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Meme], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MemeService_ServiceDesc.Streams[0], MemeService_WatchNewMemes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, Meme]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MemeService_WatchNewMemesClient = grpc.ServerStreamingClient[Meme]
+
+// MemeServiceServer is the server API for MemeService service.
+// All implementations must embed UnimplementedMemeServiceServer
+// for forward compatibility.
+type MemeServiceServer interface {
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// 找不到資料時回傳 NOT_FOUND
+	Random(context.Context, *RandomRequest) (*RandomResponse, error)
+	Get(context.Context, *GetRequest) (*Meme, error)
+	// 持續推送新寫入的資料 (例如爬蟲剛抓到的)，直到客戶端取消
+	WatchNewMemes(*WatchRequest, grpc.ServerStreamingServer[Meme]) error
+	mustEmbedUnimplementedMemeServiceServer()
+}
+
+// UnimplementedMemeServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMemeServiceServer struct{}
+
+func (UnimplementedMemeServiceServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedMemeServiceServer) Random(context.Context, *RandomRequest) (*RandomResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Random not implemented")
+}
+func (UnimplementedMemeServiceServer) Get(context.Context, *GetRequest) (*Meme, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedMemeServiceServer) WatchNewMemes(*WatchRequest, grpc.ServerStreamingServer[Meme]) error {
+	return status.Error(codes.Unimplemented, "method WatchNewMemes not implemented")
+}
+func (UnimplementedMemeServiceServer) mustEmbedUnimplementedMemeServiceServer() {}
+func (UnimplementedMemeServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeMemeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MemeServiceServer will
+// result in compilation errors.
+type UnsafeMemeServiceServer interface {
+	mustEmbedUnimplementedMemeServiceServer()
+}
+
+func RegisterMemeServiceServer(s grpc.ServiceRegistrar, srv MemeServiceServer) {
+	// If the following call panics, it indicates UnimplementedMemeServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MemeService_ServiceDesc, srv)
+}
+
+func _MemeService_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemeServiceServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MemeService_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemeServiceServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MemeService_Random_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RandomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemeServiceServer).Random(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MemeService_Random_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemeServiceServer).Random(ctx, req.(*RandomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MemeService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MemeServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MemeService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MemeServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MemeService_WatchNewMemes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MemeServiceServer).WatchNewMemes(m, &grpc.GenericServerStream[WatchRequest, Meme]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MemeService_WatchNewMemesServer = grpc.ServerStreamingServer[Meme]
+
+// MemeService_ServiceDesc is the grpc.ServiceDesc for MemeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MemeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "meme.v1.MemeService",
+	HandlerType: (*MemeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _MemeService_Search_Handler,
+		},
+		{
+			MethodName: "Random",
+			Handler:    _MemeService_Random_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _MemeService_Get_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchNewMemes",
+			Handler:       _MemeService_WatchNewMemes_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "memepb/meme.proto",
+}
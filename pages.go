@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [伺服器端渲染頁面]
+// =========================================================
+
+// truncateRunes 以 rune 為單位截斷，避免把中文字切成亂碼
+func truncateRunes(s string, n int) string {
+	r := []rune(strings.TrimSpace(s))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
+}
+
+// baseURL 組出對外網址，分享預覽 (Open Graph) 需要絕對路徑
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	// 只有設定了 TRUSTED_PROXIES (前面確定有反向代理) 才採用代理轉送的協定，且只接受 http / https
+	if os.Getenv("TRUSTED_PROXIES") != "" {
+		if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// parseMemeID 解析路徑上的 :id (資料 id)，格式錯誤時直接回應 400
+func parseMemeID(c *gin.Context) (int64, bool) {
+	return parseIDParam(c, "id")
+}
+
+// parseIDParam 把路徑參數 name 解析成正整數 id (同義詞、已儲存的搜尋等其他資源)，格式錯誤時直接回應 400
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " 格式錯誤"})
+		return 0, false
+	}
+	return id, true
+}
+
+// memePageHandler 渲染 /m/:id 永久連結頁，附帶 Open Graph 標籤讓聊天軟體能產生預覽
+func memePageHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.String(http.StatusNotFound, "找不到資料")
+		return
+	}
+	meme, err := GetMemeByID(id)
+	if errors.Is(err, ErrNotFound) {
+		c.String(http.StatusNotFound, "找不到資料")
+		return
+	}
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	recordView(meme.ID)
+
+	description := meme.Tags
+	if !isImageURL(meme.URL) && !isVideoURL(meme.URL) {
+		description = truncateRunes(meme.URL, 120)
+	}
+
+	// 預覽圖優先使用本地鏡像 (需為絕對網址)，來源網站常擋外部引用
+	mediaURL := meme.URL
+	if meme.MediaURL != "" {
+		mediaURL = baseURL(c) + meme.MediaURL
+	}
+
+	c.HTML(http.StatusOK, "meme.html", gin.H{
+		"Meme":        meme,
+		"MediaURL":    mediaURL,
+		"IsImage":     isImageURL(meme.URL),
+		"IsVideo":     isVideoURL(meme.URL),
+		"Description": description,
+		"PageURL":     baseURL(c) + "/m/" + strconv.FormatInt(meme.ID, 10),
+	})
+}
+
+// ---------------------------------------------------------
+// 搜尋與隨機頁面 (不需要 JavaScript)
+// ---------------------------------------------------------
+
+const searchPageSize = 20
+const maxSearchPage = 50
+
+// memeCard 是 meme_card.html 一張卡片需要的資料
+type memeCard struct {
+	Meme
+	IsImage     bool
+	IsVideo     bool
+	MediaSrc    string        // 圖片優先用縮小版動畫，沒有鏡像時用原始網址
+	SnippetHTML template.HTML // 已跳脫並以 <mark> 標示關鍵字的摘要
+	Truncated   bool          // 摘要只是內文的一部分
+}
+
+func newMemeCard(m Meme) memeCard {
+	card := memeCard{Meme: m, IsImage: isImageURL(m.URL), IsVideo: isVideoURL(m.URL), MediaSrc: m.URL}
+	switch {
+	case m.PreviewURL != "":
+		card.MediaSrc = m.PreviewURL
+	case m.MediaURL != "":
+		card.MediaSrc = m.MediaURL
+	}
+	if m.Snippet != "" {
+		card.SnippetHTML = highlightHTML(m.Snippet, m.Highlights)
+		card.Truncated = m.Snippet != strings.TrimSpace(snippetSource(m))
+	}
+	return card
+}
+
+// highlightHTML 把摘要轉成 HTML：每一段文字都先跳脫，命中的部分包上 <mark>
+func highlightHTML(snippet string, highlights []Highlight) template.HTML {
+	runes := []rune(snippet)
+	var b strings.Builder
+	last := 0
+	for _, h := range highlights {
+		if h.Start < last || h.End > len(runes) {
+			continue
+		}
+		b.WriteString(template.HTMLEscapeString(string(runes[last:h.Start])))
+		b.WriteString("<mark>" + template.HTMLEscapeString(string(runes[h.Start:h.End])) + "</mark>")
+		last = h.End
+	}
+	b.WriteString(template.HTMLEscapeString(string(runes[last:])))
+	return template.HTML(b.String())
+}
+
+// searchPageURL 組出保留目前條件的搜尋頁網址
+func searchPageURL(q, mode, sort string, page int) string {
+	v := url.Values{}
+	v.Set("q", q)
+	if mode != "" && mode != "all" {
+		v.Set("mode", mode)
+	}
+	if sort != "" && sort != "new" {
+		v.Set("sort", sort)
+	}
+	if page > 1 {
+		v.Set("page", strconv.Itoa(page))
+	}
+	return "/search?" + v.Encode()
+}
+
+// searchPageHandler 渲染 /search?q=&mode=&sort=&page=，和 /api/search 使用同一套搜尋
+func searchPageHandler(syn *synonymIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q, mode, sort := c.Query("q"), c.DefaultQuery("mode", "all"), c.Query("sort")
+		page := min(max(queryInt(c, "page", 1, maxSearchPage), 1), maxSearchPage)
+		searched := c.Request.URL.Query().Has("q")
+		data := gin.H{"Query": q, "Mode": mode, "Sort": sort, "Page": page, "Searched": searched}
+
+		// 沒有帶 q 時只顯示搜尋表單
+		if !searched {
+			c.HTML(http.StatusOK, "search.html", data)
+			return
+		}
+
+		// 多查一筆，用來判斷是否還有下一頁
+		res, err := runSearch(syn, searchRequest{
+			Query: q, Mode: mode, Sort: sort, Limit: searchPageSize + 1, Offset: (page - 1) * searchPageSize,
+			Client: anonymousClient(c),
+		})
+		if err != nil {
+			status := http.StatusInternalServerError
+			if isSearchInputError(err) {
+				status = http.StatusBadRequest
+			}
+			data["Error"] = err.Error()
+			c.HTML(status, "search.html", data)
+			return
+		}
+
+		memes := res.Memes
+		if len(memes) > searchPageSize {
+			memes = memes[:searchPageSize]
+			if page < maxSearchPage {
+				data["NextURL"] = searchPageURL(q, mode, sort, page+1)
+			}
+		}
+		if page > 1 {
+			data["PrevURL"] = searchPageURL(q, mode, sort, page-1)
+		}
+		cards := make([]memeCard, len(memes))
+		for i, m := range memes {
+			cards[i] = newMemeCard(m)
+		}
+		data["Cards"] = cards
+		data["Expansions"] = strings.Join(res.Expansions, "、")
+		c.HTML(http.StatusOK, "search.html", data)
+	}
+}
+
+// randomPageHandler 渲染 /random?mode=，每次重新整理都抽一則新的
+func randomPageHandler(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "all")
+	data := gin.H{"Query": "", "Mode": mode, "Sort": "", "Random": true}
+	c.Header("Cache-Control", "no-store")
+
+	meme, err := GetRandomMeme(mode)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		data["Error"] = "資料庫裡找不到符合的資料 🥲"
+		c.HTML(http.StatusNotFound, "search.html", data)
+		return
+	case err != nil:
+		data["Error"] = err.Error()
+		c.HTML(http.StatusInternalServerError, "search.html", data)
+		return
+	}
+	recordView(meme.ID)
+	data["Cards"] = []memeCard{newMemeCard(meme)}
+	c.HTML(http.StatusOK, "search.html", data)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [隨機抽取：多筆、固定種子與不重複模式]
+// =========================================================
+
+const maxRandomCount = 20
+const randomSessionCookie = "random_sid"
+
+// 每個 session 記住最近看過的幾筆，閒置太久就丟掉
+const historySize = 50
+const historyTTL = time.Hour
+
+type recentEntry struct {
+	ids  []int64
+	last time.Time
+}
+
+// recentHistory 記錄每個 session 最近抽過的 id，讓 no_repeat 模式不會連續出現同一則
+type recentHistory struct {
+	mu      sync.Mutex
+	entries map[string]*recentEntry
+	writes  int
+}
+
+func newRecentHistory() *recentHistory {
+	return &recentHistory{entries: make(map[string]*recentEntry)}
+}
+
+func (h *recentHistory) Recent(sid string) []int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if e, ok := h.entries[sid]; ok {
+		return append([]int64{}, e.ids...)
+	}
+	return nil
+}
+
+func (h *recentHistory) Remember(sid string, ids ...int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	e, ok := h.entries[sid]
+	if !ok {
+		e = &recentEntry{}
+		h.entries[sid] = e
+	}
+	e.ids = append(e.ids, ids...)
+	if len(e.ids) > historySize {
+		e.ids = e.ids[len(e.ids)-historySize:]
+	}
+	e.last = now
+
+	// 每寫入一百次順便清掉閒置的 session
+	h.writes++
+	if h.writes%100 == 0 {
+		for key, entry := range h.entries {
+			if now.Sub(entry.last) > historyTTL {
+				delete(h.entries, key)
+			}
+		}
+	}
+}
+
+func (h *recentHistory) Reset(sid string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.entries, sid)
+}
+
+// seededRand 把任意字串 (例如日期) 轉成固定的亂數來源
+func seededRand(seed string) *mathrand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	return mathrand.New(mathrand.NewSource(int64(h.Sum64())))
+}
+
+// randomSessionID 優先使用 session 參數，其次是 cookie，都沒有就發一個新的
+func randomSessionID(c *gin.Context) string {
+	if sid := c.Query("session"); sid != "" {
+		return sid
+	}
+	if sid, err := c.Cookie(randomSessionCookie); err == nil && sid != "" {
+		return sid
+	}
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	sid := hex.EncodeToString(buf)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(randomSessionCookie, sid, int(historyTTL.Seconds()), "/", "", c.Request.TLS != nil, true)
+	return sid
+}
+
+// randomHandler 處理 /api/random：
+// count=N 回傳 N 筆不重複的陣列；seed 固定抽取結果；no_repeat=1 避免同一個 session 重複看到；weighted=1 依人氣加權。
+// 這些參數可以任意組合，例如 weighted=1&count=5&seed=x 會以固定的亂數依人氣抽出 5 筆不重複的資料
+func randomHandler(history *recentHistory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mode := c.DefaultQuery("mode", "all")
+
+		// 依人氣加權時熱門的梗比較容易被抽中
+		pick := GetRandomMemes
+		if c.Query("weighted") == "1" {
+			pick = GetWeightedRandomMemes
+		}
+
+		opts := RandomOptions{Mode: mode, Count: 1}
+		_, wantList := c.GetQuery("count")
+		if wantList {
+			n, err := strconv.Atoi(c.Query("count"))
+			if err != nil || n < 1 || n > maxRandomCount {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "count 需介於 1 到 " + strconv.Itoa(maxRandomCount)})
+				return
+			}
+			opts.Count = n
+		}
+		if seed := c.Query("seed"); seed != "" {
+			opts.Rand = seededRand(seed + "|" + mode)
+		}
+
+		noRepeat := c.Query("no_repeat") == "1"
+		var sid string
+		if noRepeat {
+			sid = randomSessionID(c)
+			opts.Exclude = history.Recent(sid)
+		}
+
+		memes, err := pick(opts)
+		if err == nil && noRepeat && len(memes) < opts.Count && len(opts.Exclude) > 0 {
+			// 全部都看過了，清空紀錄重新開始
+			history.Reset(sid)
+			opts.Exclude = nil
+			memes, err = pick(opts)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ids := make([]int64, len(memes))
+		for i, m := range memes {
+			ids[i] = m.ID
+			recordView(m.ID)
+		}
+		if noRepeat {
+			history.Remember(sid, ids...)
+		}
+
+		if wantList {
+			c.JSON(http.StatusOK, memes)
+			return
+		}
+		if len(memes) == 0 {
+			c.JSON(http.StatusOK, gin.H{"error": "找不到資料"})
+			return
+		}
+		c.JSON(http.StatusOK, memes[0])
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestRandomSampling(t *testing.T) {
+	var memes []ExportMeme
+	for i := 1; i <= 10; i++ {
+		source := "https://www.ptt.cc/bbs/Joke/M." + fmt.Sprint(i)
+		if i%2 == 0 {
+			source = "https://www.gif-vif.com/gifs/" + fmt.Sprint(i)
+		}
+		memes = append(memes, ExportMeme{Title: fmt.Sprintf("梗 %d", i), URL: fmt.Sprintf("內容 %d", i), SourceURL: source})
+	}
+	r := setupTestServer(t, memes...)
+
+	// 製造 id 空洞：下架 3 ~ 6
+	for id := int64(3); id <= 6; id++ {
+		SetMemeDeleted(id, true, "test")
+	}
+
+	// 多筆抽取不重複，且不會抽到已下架或不符模式的資料
+	picked, err := GetRandomMemes(RandomOptions{Mode: "image", Count: 10})
+	if err != nil {
+		t.Fatalf("隨機抽取失敗: %v", err)
+	}
+	seen := map[int64]bool{}
+	for _, m := range picked {
+		if seen[m.ID] || m.ID%2 != 0 || (m.ID >= 3 && m.ID <= 6) {
+			t.Errorf("抽到不該出現的資料: %d", m.ID)
+		}
+		seen[m.ID] = true
+	}
+	if len(picked) != 3 { // 2, 8, 10
+		t.Errorf("圖片模式預期抽完 3 筆，得到 %d 筆", len(picked))
+	}
+
+	// 相同 seed 結果相同
+	var a, b []Meme
+	json.Unmarshal(doRequest(r, "GET", "/api/random?count=3&seed=2025-01-01").Body.Bytes(), &a)
+	json.Unmarshal(doRequest(r, "GET", "/api/random?count=3&seed=2025-01-01").Body.Bytes(), &b)
+	if len(a) != 3 || fmt.Sprint(a) != fmt.Sprint(b) {
+		t.Errorf("相同 seed 應得到相同結果: %v vs %v", a, b)
+	}
+
+	if w := doRequest(r, "GET", "/api/random?count=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("count=0 預期 400，得到 %d", w.Code)
+	}
+
+	// no_repeat：同一個 session 在看完全部 6 筆之前不會重複
+	var cookie *http.Cookie
+	seen = map[int64]bool{}
+	for i := 0; i < 6; i++ {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/random?no_repeat=1", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		r.ServeHTTP(w, req)
+		for _, c := range w.Result().Cookies() {
+			if c.Name == randomSessionCookie {
+				cookie = c
+			}
+		}
+
+		var m Meme
+		json.Unmarshal(w.Body.Bytes(), &m)
+		if seen[m.ID] {
+			t.Fatalf("第 %d 次抽到重複的 #%d", i+1, m.ID)
+		}
+		seen[m.ID] = true
+	}
+}
+
+func TestWeightedRandomCombinesOptions(t *testing.T) {
+	var memes []ExportMeme
+	for i := 1; i <= 6; i++ {
+		memes = append(memes, ExportMeme{Title: fmt.Sprintf("梗 %d", i), URL: fmt.Sprintf("https://example.com/%d.gif", i), SourceURL: fmt.Sprintf("https://www.gif-vif.com/gifs/%d", i)})
+	}
+	r := setupTestServer(t, memes...)
+	for i := range 50 {
+		Vote(6, fmt.Sprintf("user:%d", i), 1)
+	}
+
+	ids := func(path string) []int64 {
+		t.Helper()
+		w := doRequest(r, "GET", path)
+		var got []Meme
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s 預期回傳陣列: %d %s", path, w.Code, w.Body.String())
+		}
+		var out []int64
+		for _, m := range got {
+			out = append(out, m.ID)
+		}
+		return out
+	}
+
+	// count 會抽出多筆不重複的資料 (抽後不放回)，全部抽完也不會重複
+	all := ids("/api/random?weighted=1&count=20")
+	seen := map[int64]bool{}
+	for _, id := range all {
+		if seen[id] {
+			t.Errorf("加權抽取不應重複: %v", all)
+		}
+		seen[id] = true
+	}
+	if len(all) != 6 {
+		t.Errorf("應抽完全部 6 筆，得到 %v", all)
+	}
+
+	// seed 固定結果
+	a := ids("/api/random?weighted=1&count=3&seed=abc")
+	b := ids("/api/random?weighted=1&count=3&seed=abc")
+	if fmt.Sprint(a) != fmt.Sprint(b) || len(a) != 3 {
+		t.Errorf("相同 seed 應抽到相同結果: %v / %v", a, b)
+	}
+
+	// no_repeat 在同一個 session 內不重複
+	first := ids("/api/random?weighted=1&count=3&no_repeat=1&session=s1")
+	second := ids("/api/random?weighted=1&count=3&no_repeat=1&session=s1")
+	for _, id := range second {
+		if slices.Contains(first, id) {
+			t.Errorf("no_repeat 不應重複: %v / %v", first, second)
+		}
+	}
+
+	// 被排除的資料佔了大部分權重時仍能抽到剩下的
+	p, _ := loadWeightedPool("all")
+	if got := p.sample(5, []int64{6}, func() float64 { return 0.99 }); len(got) != 5 || slices.Contains(got, 6) {
+		t.Errorf("應從剩下的 5 筆抽出: %v", got)
+	}
+}
+
+// 符合條件的資料很稀疏時 (前面 4 筆圖片、後面 96 筆文字)，每筆圖片被抽中的機率仍要相同
+func TestRandomSamplingIsUniformOnSparseFilter(t *testing.T) {
+	setupTestServer(t, sparseImageMemes(4, 96)...)
+
+	rng := seededRand("uniform")
+	counts := map[int64]int{}
+	const draws = 2000
+	for range draws {
+		picked, err := GetRandomMemes(RandomOptions{Mode: "image", Rand: rng})
+		if err != nil || len(picked) != 1 {
+			t.Fatalf("抽取失敗: %v %v", picked, err)
+		}
+		counts[picked[0].ID]++
+	}
+	for id := int64(1); id <= 4; id++ {
+		if n := counts[id]; n < draws/4*7/10 || n > draws/4*13/10 {
+			t.Errorf("id %d 被抽中 %d 次，應接近 %d 次: %v", id, n, draws/4, counts)
+		}
+	}
+}
+
+// sparseImageMemes 產生 images 筆圖片後接 texts 筆文字
+func sparseImageMemes(images, texts int) []ExportMeme {
+	var memes []ExportMeme
+	for i := 1; i <= images+texts; i++ {
+		source := "https://www.ptt.cc/bbs/Joke/M." + fmt.Sprint(i)
+		if i <= images {
+			source = "https://www.gif-vif.com/gifs/" + fmt.Sprint(i)
+		}
+		memes = append(memes, ExportMeme{Title: fmt.Sprintf("梗 %d", i), URL: fmt.Sprintf("內容 %d", i), SourceURL: source})
+	}
+	return memes
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// =========================================================
+// [Token Bucket 限流器]
+// =========================================================
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time
+	rate     float64 // 每秒補充的 token 數
+	capacity float64
+}
+
+// RateLimiter 依 key (API key 或 IP) 各自維護一個 token bucket
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time // 測試時可替換
+}
+
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+// Allow 嘗試從 key 的桶子取出一個 token。
+// perMinute 為每分鐘補充量，burst 為桶子容量；被拒絕時回傳需要等待的時間
+func (l *RateLimiter) Allow(key string, perMinute, burst int) (bool, time.Duration) {
+	if perMinute <= 0 {
+		return true, 0
+	}
+	if burst <= 0 {
+		burst = perMinute
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.rate = float64(perMinute) / 60
+	b.capacity = float64(burst)
+
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	return false, wait
+}
+
+// Cleanup 移除已經補滿 (閒置) 的桶子，避免大量 IP 讓 map 無限成長
+func (l *RateLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// StartCleanup 定期清理閒置的桶子，回傳的函式會停止清理 (可重複呼叫)
+func (l *RateLimiter) StartCleanup(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.Cleanup()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [搜尋：JSON API 與伺服器端渲染頁面共用]
+// =========================================================
+
+// searchRequest 是一次搜尋的輸入 (q 為原始字串，可包含搜尋語法)
+type searchRequest struct {
+	Query  string
+	Mode   string
+	Sort   string
+	Limit  int
+	Offset int
+	Client string // 搜尋分析用的匿名代號 (anonymousClient)
+}
+
+// searchResult 是 runSearch 的結果
+type searchResult struct {
+	Parsed     ParsedQuery
+	Expansions []string // 同義詞展開的關鍵字
+	Memes      []Meme
+	SearchID   int64 // 搜尋分析的紀錄 id，沒有記錄時為 0
+}
+
+// isSearchInputError 判斷是否為使用者輸入錯誤 (回應 400)
+func isSearchInputError(err error) bool {
+	var qe *QueryError
+	return errors.As(err, &qe) || errors.Is(err, ErrInvalidSort)
+}
+
+// runSearch 解析搜尋語法、套用同義詞後查詢，並記錄熱門搜尋與搜尋分析。
+// 只有第一頁會記錄，翻頁不重複計算。HTTP、GraphQL 與 gRPC 共用
+func runSearch(syn *synonymIndex, req searchRequest) (searchResult, error) {
+	start := time.Now()
+	pq, err := ParseSearchQuery(req.Query)
+	if err != nil {
+		return searchResult{}, err
+	}
+	res := searchResult{Parsed: pq, Expansions: syn.Expand(pq.Text)}
+	res.Memes, err = SearchMemesWithOptions(SearchOptions{
+		Query: pq.Text, Terms: res.Expansions, Mode: req.Mode, Sort: req.Sort, Limit: req.Limit, Offset: req.Offset,
+		HighlightTerms: pq.Phrases, FilterSQL: pq.FilterSQL, FilterArgs: pq.FilterArgs,
+	})
+	if err != nil || req.Offset > 0 {
+		return res, err
+	}
+
+	if len(res.Memes) > 0 {
+		if err := RecordPopularQuery(req.Query); err != nil {
+			log.Printf("[Suggest] 記錄熱門搜尋失敗: %v", err)
+		}
+	}
+	// 空白查詢是瀏覽全部，不列入分析
+	if strings.TrimSpace(req.Query) != "" {
+		ids := make([]int64, len(res.Memes))
+		for i, m := range res.Memes {
+			ids[i] = m.ID
+		}
+		res.SearchID, err = LogSearch(SearchLogEntry{
+			Query: req.Query, Mode: req.Mode, ResultCount: len(res.Memes),
+			Latency: time.Since(start), Client: req.Client, ResultIDs: ids,
+		})
+		if err != nil {
+			log.Printf("[Analytics] 記錄搜尋失敗: %v", err)
+		}
+	}
+	return res, nil
+}
+
+// searchHandler 處理 GET /api/search
+func searchHandler(syn *synonymIndex) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := runSearch(syn, searchRequest{
+			Query: c.Query("q"), Mode: c.DefaultQuery("mode", "all"), Sort: c.Query("sort"), Client: anonymousClient(c),
+		})
+		if isSearchInputError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(res.Expansions) > 0 {
+			escaped := make([]string, len(res.Expansions))
+			for i, t := range res.Expansions {
+				escaped[i] = url.QueryEscape(t)
+			}
+			c.Header("X-Search-Expansions", strings.Join(escaped, ","))
+		}
+		if res.SearchID != 0 {
+			c.Header("X-Search-ID", strconv.FormatInt(res.SearchID, 10))
+		}
+		c.JSON(http.StatusOK, res.Memes)
+	}
+}
+
+// ---------------------------------------------------------
+// 以圖搜圖 (比對邏輯見 imagehash.go)
+// ---------------------------------------------------------
+
+func registerImageSearchRoutes(api *gin.RouterGroup) {
+	// POST /api/search/image (multipart，欄位名稱 image)
+	api.POST("/search/image", func(c *gin.Context) {
+		// limit 預設 10、最多 50；0 或負數沒有意義，直接拒絕 (先檢查，不必讀完上傳的圖片)
+		limit := 10
+		if s, ok := c.GetQuery("limit"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit 需為正整數 (最多 " + strconv.Itoa(maxImageResults) + ")"})
+				return
+			}
+			limit = min(n, maxImageResults)
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize+1<<20)
+		file, err := c.FormFile("image")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "請以 multipart 欄位 image 上傳圖片 (上限 10 MB)"})
+			return
+		}
+		if file.Size > maxUploadSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "圖片超過 10 MB"})
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+
+		query, err := imageHashes(f)
+		if errors.Is(err, ErrImageTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		maxDistance := defaultMaxDistance
+		if s := c.Query("max_distance"); s != "" {
+			if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 64 {
+				maxDistance = n
+			}
+		}
+		matches, err := SearchByImage(query, maxDistance, limit)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, matches)
+	})
+}
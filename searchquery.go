@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// =========================================================
+// [搜尋語法：欄位條件、排除與完整片語]
+// =========================================================
+//
+// 例如 `title:trump source:ptt after:2025-01-01 -tag:nsfw "exact phrase"`：
+//   - 沒有欄位的文字合併成一段關鍵字，和原本的搜尋相同 (也會套用同義詞)
+//   - "..." 是必須出現的完整片語，欄位值也可以加引號 (title:"a b")
+//   - 開頭加 - 代表排除
+//   - 條件之間都是 AND
+
+// QueryError 是搜尋語法錯誤，訊息會直接回給使用者
+type QueryError struct {
+	Msg string
+}
+
+func (e *QueryError) Error() string { return "搜尋語法錯誤：" + e.Msg }
+
+func queryErrorf(format string, args ...any) error {
+	return &QueryError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// searchFields 是可用的欄位，對應到資料表欄位的比對條件 (value 已經過驗證)
+var searchFields = map[string]func(value string) (string, []any, error){
+	"title":  likeField("title"),
+	"tag":    likeField("tags"),
+	"tags":   likeField("tags"),
+	"source": likeField("source_url"), // source:ptt、source:threads、source:plurk、source:gif-vif
+	"author": likeField("author"),
+	"kind": func(v string) (string, []any, error) {
+		cond := kindSQL(strings.ToLower(v))
+		if cond == "" {
+			return "", nil, queryErrorf("kind 只接受 image 或 text，得到「%s」", v)
+		}
+		return cond, nil, nil
+	},
+	"after":   dateField(`memes.created_at >= ?`),
+	"before":  dateField(`memes.created_at < ?`),
+	"created": dateField(`date(memes.created_at) = ?`),
+}
+
+func likeField(column string) func(string) (string, []any, error) {
+	return func(v string) (string, []any, error) {
+		return column + ` LIKE ?`, []any{"%" + v + "%"}, nil
+	}
+}
+
+func dateField(cond string) func(string) (string, []any, error) {
+	return func(v string) (string, []any, error) {
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			return "", nil, queryErrorf("日期「%s」格式錯誤，請用 YYYY-MM-DD", v)
+		}
+		return cond, []any{v}, nil
+	}
+}
+
+// ParsedQuery 是解析後的搜尋條件
+type ParsedQuery struct {
+	Text       string   // 沒有欄位的關鍵字
+	Phrases    []string // 必須出現的片語
+	Excluded   []string // 不得出現的關鍵字或片語
+	FilterSQL  string   // 欄位條件 (以 AND 開頭)，包含 Phrases 與 Excluded
+	FilterArgs []any
+}
+
+// queryToken 是切開後的一個詞，field 為空代表不是欄位條件
+type queryToken struct {
+	negated bool
+	quoted  bool
+	field   string
+	value   string
+}
+
+// tokenizeQuery 以空白切詞，引號內的空白不切
+func tokenizeQuery(q string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var tok queryToken
+		if runes[i] == '-' {
+			tok.negated = true
+			i++
+		}
+		// 引號之前的部分，用來判斷是不是「欄位:」
+		var prefix, value strings.Builder
+		for i < len(runes) && !unicode.IsSpace(runes[i]) {
+			if runes[i] != '"' {
+				if !tok.quoted {
+					prefix.WriteRune(runes[i])
+				}
+				value.WriteRune(runes[i])
+				i++
+				continue
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, queryErrorf("引號沒有成對")
+			}
+			tok.quoted = true
+			value.WriteString(string(runes[i+1 : end]))
+			i = end + 1
+		}
+
+		tok.value = value.String()
+		// 只有已知的欄位才當成條件，其他像「Re: 笑話」、「note:xxx」都是一般文字
+		if name, rest, ok := strings.Cut(prefix.String(), ":"); ok && isSearchField(name) && !strings.HasPrefix(rest, "//") {
+			tok.field = strings.ToLower(name)
+			tok.value = strings.TrimPrefix(tok.value, name+":")
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+func isSearchField(name string) bool {
+	_, ok := searchFields[strings.ToLower(name)]
+	return ok
+}
+
+// ParseSearchQuery 把搜尋字串解析成 SQL 條件，語法錯誤時回傳 *QueryError
+func ParseSearchQuery(q string) (ParsedQuery, error) {
+	tokens, err := tokenizeQuery(q)
+	if err != nil {
+		return ParsedQuery{}, err
+	}
+
+	var pq ParsedQuery
+	var words []string
+	var conds []string
+	for _, tok := range tokens {
+		value := strings.TrimSpace(tok.value)
+		switch {
+		case tok.field != "":
+			build := searchFields[tok.field]
+			if value == "" {
+				return ParsedQuery{}, queryErrorf("「%s:」後面缺少內容", tok.field)
+			}
+			cond, args, err := build(value)
+			if err != nil {
+				return ParsedQuery{}, err
+			}
+			if tok.negated {
+				cond = `NOT (` + cond + `)`
+			}
+			conds = append(conds, cond)
+			pq.FilterArgs = append(pq.FilterArgs, args...)
+		case value == "":
+			if tok.negated {
+				return ParsedQuery{}, queryErrorf("「-」後面缺少要排除的關鍵字")
+			}
+			if tok.quoted {
+				return ParsedQuery{}, queryErrorf("引號內沒有內容")
+			}
+		case tok.negated:
+			pq.Excluded = append(pq.Excluded, value)
+			conds = append(conds, `NOT `+textMatchSQL)
+			pq.FilterArgs = appendLike(pq.FilterArgs, value)
+		case tok.quoted:
+			pq.Phrases = append(pq.Phrases, value)
+			conds = append(conds, textMatchSQL)
+			pq.FilterArgs = appendLike(pq.FilterArgs, value)
+		default:
+			words = append(words, value)
+		}
+	}
+
+	pq.Text = strings.Join(words, " ")
+	for _, cond := range conds {
+		pq.FilterSQL += ` AND ` + cond
+	}
+	return pq, nil
+}
+
+// appendLike 加入 textMatchSQL 需要的 5 個參數
+func appendLike(args []any, value string) []any {
+	like := "%" + value + "%"
+	return append(args, like, like, like, like, like)
+}
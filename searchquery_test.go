@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	pq, err := ParseSearchQuery(`好笑 title:"川普 演講" -tag:nsfw "exact phrase" -難笑 https://example.com/a 笑死:D`)
+	if err != nil {
+		t.Fatalf("解析失敗: %v", err)
+	}
+	if pq.Text != "好笑 https://example.com/a 笑死:D" {
+		t.Errorf("關鍵字不符: %q", pq.Text)
+	}
+	if len(pq.Phrases) != 1 || pq.Phrases[0] != "exact phrase" || len(pq.Excluded) != 1 || pq.Excluded[0] != "難笑" {
+		t.Errorf("片語或排除不符: %+v", pq)
+	}
+	if pq.FilterArgs[0] != "%川普 演講%" || !strings.Contains(pq.FilterSQL, "NOT (tags LIKE ?)") {
+		t.Errorf("欄位條件不符: %s %v", pq.FilterSQL, pq.FilterArgs)
+	}
+
+	for q, want := range map[string]string{
+		`title:`:            "缺少內容",
+		`"沒有結尾`:             "引號沒有成對",
+		`after:2025/01/01`:  "YYYY-MM-DD",
+		`kind:video`:        "image 或 text",
+		`好笑 -`:              "缺少要排除",
+		`before:2025-13-01`: "YYYY-MM-DD",
+	} {
+		_, err := ParseSearchQuery(q)
+		if _, ok := err.(*QueryError); !ok || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q 預期錯誤包含「%s」，得到 %v", q, want, err)
+		}
+	}
+}
+
+func TestSearchQueryUnknownFieldIsText(t *testing.T) {
+	// 不是 searchFields 的「名稱:」一律當成一般文字，不會變成條件或語法錯誤
+	for q, want := range map[string]string{
+		`Re: 笑話`:         "Re: 笑話",
+		`note:xxx`:       "note:xxx",
+		`foo:bar 好笑`:     "foo:bar 好笑",
+		`Title:川普 Re:回覆`: "Re:回覆",
+	} {
+		pq, err := ParseSearchQuery(q)
+		if err != nil {
+			t.Errorf("%q 不應該有錯誤: %v", q, err)
+			continue
+		}
+		if pq.Text != want {
+			t.Errorf("%q 關鍵字預期 %q，得到 %q", q, want, pq.Text)
+		}
+	}
+	if pq, _ := ParseSearchQuery(`Re: 笑話`); pq.FilterSQL != "" {
+		t.Errorf("「Re:」不應產生欄位條件: %s", pq.FilterSQL)
+	}
+}
+
+func TestSearchQueryLanguage(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "川普演講", URL: "川普又說了好笑的話", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.A.html", Author: "joker"},
+		ExportMeme{Title: "川普跳舞", URL: "https://example.com/dance.gif", Tags: "nsfw", SourceURL: "https://www.gif-vif.com/gifs/dance"},
+		ExportMeme{Title: "老梗", URL: "川普的 exact phrase 笑話", Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.2.A.html", Author: "oldman"},
+		ExportMeme{Title: "alice", URL: "今天好累", Tags: "Threads", SourceURL: "https://www.threads.net/@alice", Author: "alice"},
+	)
+	db.Exec(`UPDATE memes SET created_at = '2024-12-31 23:00:00' WHERE id = 3`)
+
+	search := func(q string) []Meme {
+		t.Helper()
+		w := doRequest(r, "GET", "/api/search?q="+url.QueryEscape(q))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q 預期 200，得到 %d: %s", q, w.Code, w.Body.String())
+		}
+		var results []Meme
+		json.Unmarshal(w.Body.Bytes(), &results)
+		return results
+	}
+	titles := func(ms []Meme) string {
+		var out []string
+		for _, m := range ms {
+			out = append(out, m.Title)
+		}
+		return strings.Join(out, ",")
+	}
+
+	cases := map[string]string{
+		`title:川普`:                      "川普跳舞,川普演講",
+		`title:川普 source:ptt`:           "川普演講",
+		`川普 -tag:nsfw after:2025-01-01`: "川普演講",
+		`川普 before:2025-01-01`:          "老梗",
+		`"exact phrase"`:                "老梗",
+		`kind:image`:                    "川普跳舞",
+		`author:joker`:                  "川普演講",
+		`author:alice`:                  "alice",
+		`川普 -跳舞`:                        "老梗,川普演講",
+		`created:2024-12-31`:            "老梗",
+		`title:"川普演講" -author:oldman kind:text`: "川普演講",
+	}
+	for q, want := range cases {
+		if got := titles(search(q)); got != want {
+			t.Errorf("%q 預期 %s，得到 %s", q, want, got)
+		}
+	}
+
+	if got := titles(search("foo:bar")); got != "" {
+		t.Errorf("未知欄位應當成一般文字，得到 %s", got)
+	}
+	w := doRequest(r, "GET", "/api/search?q="+url.QueryEscape("after:2025/01/01"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "YYYY-MM-DD") {
+		t.Errorf("語法錯誤預期 400 並說明原因，得到 %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// =========================================================
+// [搜尋結果摘要與關鍵字標示]
+// =========================================================
+
+const snippetWindow = 120 // 摘要長度 (字元數)
+const snippetContext = 20 // 第一個命中位置前保留的字數
+const maxHighlights = 50
+
+// Highlight 是命中的範圍，以 rune (Unicode 字元) 計算，不是 byte，也不是 JavaScript 的 UTF-16 長度
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"` // 不含
+}
+
+// findHighlights 找出所有關鍵字在 text 中的位置 (不分大小寫)，重疊或相鄰的合併成一段
+func findHighlights(text []rune, terms []string) []Highlight {
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		// unicode.ToLower 一個字元只對應一個字元，位置不會跑掉 (strings.ToLower 則可能改變長度)
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var hits []Highlight
+	for _, term := range terms {
+		needle := []rune(strings.ToLower(strings.TrimSpace(term)))
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower) && len(hits) < maxHighlights*4; i++ {
+			if runesHavePrefix(lower[i:], needle) {
+				hits = append(hits, Highlight{Start: i, End: i + len(needle)})
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Start < hits[j].Start })
+	merged := []Highlight{hits[0]}
+	for _, h := range hits[1:] {
+		last := &merged[len(merged)-1]
+		if h.Start <= last.End {
+			last.End = max(last.End, h.End)
+			continue
+		}
+		merged = append(merged, h)
+	}
+	return merged
+}
+
+func runesHavePrefix(s, prefix []rune) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildSnippet 從 text 擷取包含最多命中的一段，回傳摘要與摘要內的標示位置。
+// 被截斷的一端會加上「…」；沒有命中時回傳開頭的一段
+func BuildSnippet(text string, terms []string) (string, []Highlight) {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) == 0 {
+		return "", nil
+	}
+	hits := findHighlights(runes, terms)
+
+	start := 0
+	if len(hits) > 0 && len(runes) > snippetWindow {
+		// 以每個命中位置當作起點試一次，選出涵蓋最多命中的區段
+		best, bestCount := 0, -1
+		for i, h := range hits {
+			s := max(0, h.Start-snippetContext)
+			count := 0
+			for _, other := range hits[i:] {
+				if other.End > s+snippetWindow {
+					break
+				}
+				count++
+			}
+			if count > bestCount {
+				best, bestCount = s, count
+			}
+		}
+		start = min(best, len(runes)-snippetWindow)
+		start = snapToBoundary(runes, start)
+	}
+	end := min(len(runes), start+snippetWindow)
+
+	var b strings.Builder
+	offset := -start
+	if start > 0 {
+		b.WriteRune('…')
+		offset++
+	}
+	b.WriteString(string(runes[start:end]))
+	if end < len(runes) {
+		b.WriteRune('…')
+	}
+
+	var highlights []Highlight
+	for _, h := range hits {
+		// 只保留在摘要內的部分，跨過邊界的截掉
+		s, e := max(h.Start, start), min(h.End, end)
+		if s >= e {
+			continue
+		}
+		highlights = append(highlights, Highlight{Start: s + offset, End: e + offset})
+		if len(highlights) >= maxHighlights {
+			break
+		}
+	}
+	return b.String(), highlights
+}
+
+// snapToBoundary 起點往前找最近的空白或標點 (最多 10 個字)，避免把英文單字切成兩半。
+// 中日韓文字本身就能斷開，遇到時直接停在原位
+func snapToBoundary(runes []rune, start int) int {
+	for i := start; i > 0 && i > start-10; i-- {
+		prev := runes[i-1]
+		if unicode.IsSpace(prev) || unicode.IsPunct(prev) {
+			return i
+		}
+		if unicode.Is(unicode.Han, prev) || unicode.Is(unicode.Hiragana, prev) || unicode.Is(unicode.Katakana, prev) || unicode.Is(unicode.Hangul, prev) {
+			return start
+		}
+	}
+	return start
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// marked 依標示位置把摘要中的命中部分加上 [ ]，方便比對
+func marked(snippet string, hs []Highlight) string {
+	runes := []rune(snippet)
+	var b strings.Builder
+	last := 0
+	for _, h := range hs {
+		b.WriteString(string(runes[last:h.Start]) + "[" + string(runes[h.Start:h.End]) + "]")
+		last = h.End
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
+
+func TestBuildSnippet(t *testing.T) {
+	// 短文整段回傳，中文以字元計算位置，重疊的關鍵字合併
+	s, hs := BuildSnippet("今天的川普演講好好笑", []string{"川普", "普演講", "好笑"})
+	if got := marked(s, hs); got != "今天的[川普演講]好[好笑]" {
+		t.Errorf("短文標示不符: %s", got)
+	}
+
+	// 長文擷取命中最集中的一段，前後加上省略號
+	long := strings.Repeat("無關的文字。", 40) + "這裡提到貓，然後又是貓咪。" + strings.Repeat("後面的文字。", 40)
+	s, hs = BuildSnippet(long, []string{"貓"})
+	if !strings.HasPrefix(s, "…") || !strings.HasSuffix(s, "…") || len(hs) != 2 {
+		t.Fatalf("長文摘要不符: %s %+v", s, hs)
+	}
+	if got := marked(s, hs); !strings.Contains(got, "這裡提到[貓]，然後又是[貓]咪。") {
+		t.Errorf("長文標示不符: %s", got)
+	}
+	if n := len([]rune(s)); n != snippetWindow+2 {
+		t.Errorf("摘要長度應為 %d 字 (含兩個省略號)，得到 %d", snippetWindow+2, n)
+	}
+
+	// 英文不分大小寫，起點不會切在單字中間
+	words := strings.Repeat("lorem ipsum dolor ", 20) + "the Funny Cat appears"
+	s, hs = BuildSnippet(words, []string{"funny cat"})
+	if got := marked(s, hs); !strings.Contains(got, "[Funny Cat]") || strings.HasPrefix(s, "…orem") || strings.HasPrefix(s, "…psum") || strings.HasPrefix(s, "…olor") {
+		t.Errorf("英文摘要不符: %s", got)
+	}
+
+	// 沒有命中時回傳開頭
+	s, hs = BuildSnippet(long, []string{"狗"})
+	if !strings.HasPrefix(s, "無關的文字") || len(hs) != 0 {
+		t.Errorf("沒有命中時應回傳開頭: %s", s)
+	}
+}
+
+func TestSearchSnippets(t *testing.T) {
+	body := strings.Repeat("前情提要。", 50) + "結果老闆說：這個梗太好笑了！" + strings.Repeat("後記。", 50)
+	r := setupTestServer(t,
+		ExportMeme{Title: "長篇笑話", URL: body, Tags: "PTT Joke", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.A.html"},
+		ExportMeme{Title: "好笑的貓", URL: "https://example.com/cat.gif", SourceURL: "https://www.gif-vif.com/gifs/cat", Description: "一隻很好笑的貓"},
+	)
+
+	results, err := SearchMemes("好笑", "all")
+	if err != nil || len(results) != 2 {
+		t.Fatalf("搜尋失敗: %v %d", err, len(results))
+	}
+	for _, m := range results {
+		got := marked(m.Snippet, m.Highlights)
+		switch m.Title {
+		case "長篇笑話":
+			if !strings.Contains(got, "這個梗太[好笑]了") || !strings.HasPrefix(got, "…") {
+				t.Errorf("內文摘要不符: %s", got)
+			}
+		case "好笑的貓":
+			if got != "一隻很[好笑]的貓" {
+				t.Errorf("GIF 應以描述作為摘要: %s", got)
+			}
+		}
+	}
+
+	// 搜尋語法中的片語也會標示
+	w := doRequest(r, "GET", "/api/search?q=%22%E8%80%81%E9%97%86%22")
+	if !strings.Contains(w.Body.String(), `"highlights":[{"start"`) {
+		t.Errorf("片語應標示在摘要中: %s", w.Body.String())
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,12 +28,21 @@ var plurkUsers = []string{"copypasta"}
 func main() {
 	log.Println("=== 獨立爬蟲程序啟動 ===")
 
+	// 和伺服器共用 --data-dir，爬完的資料庫才會在伺服器讀取的位置
+	cfg, _, err := ParseConfig(os.Args[1:], os.Stderr)
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := cfg.Apply(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	// 1. 強制清除舊資料 (由爬蟲負責清理)
 	log.Println("[系統] 正在重置資料庫與備份檔...")
 	ResetDBFiles()
 
 	// 2. 初始化全新資料庫
-	// 注意：這裡使用常數 DBFile (defined in database.go)
+	// 注意：DBFile (defined in database.go) 已由 --data-dir 決定
 	InitDB(DBFile)
 	log.Println("資料庫初始化完成")
 
@@ -60,11 +72,16 @@ func StartSpider() {
 // ---------------------------------------------------------
 func RunGifSpider() {
 	c := colly.NewCollector(
-		colly.CacheDir("./cache"),
+		colly.CacheDir(CacheDir),
 		colly.AllowedDomains("www.gif-vif.com"),
 	)
 	c.Limit(&colly.LimitRule{DomainGlob: "*", Delay: 2 * time.Second, Parallelism: 5})
 
+	run := StartCrawlRun("gif")
+	c.OnError(func(r *colly.Response, err error) {
+		run.Error(fmt.Errorf("%s: %v", r.Request.URL, err))
+	})
+
 	c.OnResponse(func(r *colly.Response) {
 		if strings.Contains(r.Headers.Get("Content-Type"), "application/json") {
 			var htmlFragments []string
@@ -78,18 +95,19 @@ func RunGifSpider() {
 		}
 	})
 
-	c.OnHTML(`img.media-show`, func(e *colly.HTMLElement) {
-		gifURL := e.Request.AbsoluteURL(e.Attr("src"))
-		title := e.Attr("alt")
-		if title == "" {
-			title = e.DOM.ParentsUntil("html").Find("title").Text()
+	c.OnHTML(`html`, func(e *colly.HTMLElement) {
+		meme, ok := parseGifDetail(e.DOM, e.Request.URL)
+		if !ok {
+			return
 		}
-		tags := strings.Join(strings.Split(title, " "), ", ")
-
-		meme := ExportMeme{Title: title, URL: gifURL, Tags: tags, SourceURL: e.Request.URL.String()}
 		SaveToJSON(meme)
-		if err := InsertMeme(meme); err == nil {
-			log.Printf("[GIF SAVE] %s", title)
+		err := InsertMeme(meme)
+		run.Found(err)
+		if err == nil {
+			log.Printf("[GIF SAVE] %s", meme.Title)
+			if _, err := MirrorMemeMedia(meme); err != nil {
+				log.Printf("[GIF MIRROR] %s: %v", meme.URL, err)
+			}
 		}
 	})
 
@@ -97,6 +115,7 @@ func RunGifSpider() {
 		c.Visit(fmt.Sprintf("https://www.gif-vif.com/loadMore.php?offset=%d", offset))
 	}
 	c.Wait()
+	run.Finish(nil)
 }
 
 func parseGifHTML(htmlContent string, c *colly.Collector, req *colly.Request) {
@@ -115,6 +134,110 @@ func parseGifHTML(htmlContent string, c *colly.Collector, req *colly.Request) {
 	})
 }
 
+// gif-vif 的描述後面都接著固定的宣傳文字 ("Share, comment, embed...")
+var gifDescriptionTail = regexp.MustCompile(`(?i)\s*share\s*,.*$`)
+
+// parseGifDetail 解析 gif-vif 的 GIF 詳細頁 (沒有 img.media-show 的頁面回傳 false)。
+// 除了標題外，也擷取描述、分類、hashtag、上傳日期與瀏覽數，讓英文標題以外的關鍵字也搜得到
+func parseGifDetail(doc *goquery.Selection, pageURL *url.URL) (ExportMeme, bool) {
+	img := doc.Find("img.media-show").First()
+	src, ok := img.Attr("src")
+	if !ok || src == "" {
+		return ExportMeme{}, false
+	}
+	if u, err := pageURL.Parse(src); err == nil {
+		src = u.String()
+	}
+
+	meme := ExportMeme{URL: src, SourceURL: pageURL.String()}
+
+	// JSON-LD 的 ImageObject 有最完整的描述與上傳時間
+	type imageObject struct {
+		Type        string `json:"@type"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		UploadDate  string `json:"uploadDate"`
+	}
+	var ld imageObject
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		var obj imageObject
+		if json.Unmarshal([]byte(s.Text()), &obj) == nil && obj.Type == "ImageObject" {
+			ld = obj
+			return false
+		}
+		return true
+	})
+
+	meme.Title = strings.TrimSpace(img.AttrOr("alt", ""))
+	if meme.Title == "" {
+		meme.Title = ld.Name
+	}
+	if meme.Title == "" {
+		meme.Title = strings.TrimSpace(doc.Find("title").Text())
+	}
+
+	description := ld.Description
+	if description == "" {
+		description = doc.Find(`meta[name="description"]`).AttrOr("content", "")
+	}
+	meme.Description = strings.TrimSpace(gifDescriptionTail.ReplaceAllString(description, ""))
+
+	// "@cat gifs" -> "cat"
+	category := strings.TrimSpace(doc.Find(".category-name").First().Text())
+	category = strings.TrimSuffix(strings.TrimPrefix(category, "@"), " gifs")
+	meme.Category = strings.TrimSpace(category)
+
+	if t, err := time.Parse(time.RFC3339, ld.UploadDate); err == nil {
+		meme.UploadedAt = t.Format("2006-01-02")
+	} else {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(doc.Find(".gif-upload-date").Text()), "Upload Date -"))
+		if t, err := time.Parse("January 2, 2006", text); err == nil {
+			meme.UploadedAt = t.Format("2006-01-02")
+		}
+	}
+
+	meme.SourceViews = parseViewCount(doc.Find(".views-number").First().Text())
+
+	// 標籤 = 分類 + 頁面上的 hashtag (不分大小寫去重)；都沒有時才退回把標題拆字
+	var tags []string
+	seen := map[string]bool{}
+	addTag := func(tag string) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	addTag(meme.Category)
+	for _, tag := range strings.Split(doc.Find(".hashtag-text").First().Text(), "#") {
+		addTag(tag)
+	}
+	if len(tags) == 0 {
+		for _, word := range strings.Split(meme.Title, " ") {
+			addTag(word)
+		}
+	}
+	meme.Tags = strings.Join(tags, ", ")
+	return meme, true
+}
+
+// parseViewCount 解析 "1,234"、"1.2K"、"3M" 這類瀏覽數
+func parseViewCount(text string) int64 {
+	text = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(text), ",", ""))
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(text, "K"):
+		multiplier, text = 1e3, strings.TrimSuffix(text, "K")
+	case strings.HasSuffix(text, "M"):
+		multiplier, text = 1e6, strings.TrimSuffix(text, "M")
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(n * multiplier)
+}
+
 // ---------------------------------------------------------
 // Chromedp 爬蟲 (Threads & Plurk)
 // ---------------------------------------------------------
@@ -138,6 +261,18 @@ func runChromedpScrapers() {
 }
 
 func runGenericScraper(ctx context.Context, targets []string, platform string, scrapeFunc func(context.Context, string) ([]ExportMeme, error)) {
+	run := StartCrawlRun(strings.ToLower(platform))
+	failed := 0
+	var lastErr error
+	defer func() {
+		// 每個帳號都失敗 (通常是瀏覽器沒開) 才算整次失敗
+		if failed == len(targets) {
+			run.Finish(lastErr)
+			return
+		}
+		run.Finish(nil)
+	}()
+
 	for i, target := range targets {
 		log.Printf("--- [%s][%d/%d] 處理: %s ---", platform, i+1, len(targets), target)
 		chromedp.Run(ctx, chromedp.Navigate("about:blank"))
@@ -146,13 +281,17 @@ func runGenericScraper(ctx context.Context, targets []string, platform string, s
 		memes, err := scrapeFunc(ctx, target)
 		if err != nil {
 			log.Printf("[錯誤] %s: %v", target, err)
+			failed, lastErr = failed+1, fmt.Errorf("%s: %v", target, err)
+			run.Error(lastErr)
 			continue
 		}
 
 		count := 0
 		for _, meme := range memes {
 			SaveToJSON(meme)
-			if err := InsertMeme(meme); err == nil {
+			err := InsertMeme(meme)
+			run.Found(err)
+			if err == nil {
 				count++
 			}
 		}
@@ -245,7 +384,7 @@ func parseThreadsHTML(author string, sourceURL string, html string) []ExportMeme
 				cleanText = strings.TrimPrefix(cleanText, author)
 				cleanText = strings.TrimSpace(cleanText)
 			}
-			meme := ExportMeme{Title: author, URL: cleanText, Tags: "Threads", SourceURL: sourceURL}
+			meme := ExportMeme{Title: author, URL: cleanText, Tags: "Threads", SourceURL: sourceURL, Author: author}
 			results = append(results, meme)
 		}
 	})
@@ -320,7 +459,7 @@ func parsePlurkHTML(author string, sourceURL string, html string) []ExportMeme {
 		c.Find("br").ReplaceWithHtml("\n")
 		text := strings.TrimSpace(c.Text())
 		if len(text) > 1 && !strings.Contains(text, "含有成人內容") {
-			results = append(results, ExportMeme{Title: author, URL: text, Tags: "Plurk", SourceURL: sourceURL})
+			results = append(results, ExportMeme{Title: author, URL: text, Tags: "Plurk", SourceURL: sourceURL, Author: author})
 		}
 	})
 	return results
@@ -340,6 +479,11 @@ func RunPTTSpider() {
 	})
 	c.SetCookies("https://www.ptt.cc", []*http.Cookie{{Name: "over18", Value: "1", Domain: "www.ptt.cc", Path: "/"}})
 
+	run := StartCrawlRun("ptt")
+	c.OnError(func(r *colly.Response, err error) {
+		run.Error(fmt.Errorf("%s: %v", r.Request.URL, err))
+	})
+
 	c.OnHTML("div.over18-notice", func(e *colly.HTMLElement) {
 		e.Request.Post("/ask/over18", map[string]string{"from": "/bbs/Joke/index.html", "yes": "yes"})
 	})
@@ -361,6 +505,11 @@ func RunPTTSpider() {
 		if title == "" {
 			title = doc.Find("title").Text()
 		}
+		// 作者欄位的格式為「帳號 (暱稱)」，只保留帳號
+		author := ""
+		if fields := strings.Fields(doc.Find(".article-metaline:nth-child(1) .article-meta-value").Text()); len(fields) > 0 {
+			author = fields[0]
+		}
 
 		content := ""
 		doc.Find("#main-content").Each(func(i int, s *goquery.Selection) {
@@ -375,9 +524,11 @@ func RunPTTSpider() {
 		content = strings.TrimSpace(content)
 
 		if len(content) > 30 {
-			m := ExportMeme{Title: title, URL: content, Tags: "PTT Joke", SourceURL: r.Request.URL.String()}
+			m := ExportMeme{Title: title, URL: content, Tags: "PTT Joke", SourceURL: r.Request.URL.String(), Author: author}
 			SaveToJSON(m)
-			if InsertMeme(m) == nil {
+			err := InsertMeme(m)
+			run.Found(err)
+			if err == nil {
 				log.Printf("[PTT SAVE] %s", title)
 			}
 		}
@@ -393,5 +544,6 @@ func RunPTTSpider() {
 
 	c.Visit("https://www.ptt.cc/bbs/Joke/index.html")
 	c.Wait()
+	run.Finish(nil)
 	log.Println("[Spider] PTT 爬蟲任務完成")
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/PuerkitoBio/goquery"
 )
 
 func TestParseThreadsHTML(t *testing.T) {
@@ -33,3 +36,50 @@ func TestParseThreadsHTML(t *testing.T) {
 		t.Errorf("頁尾未清除乾淨: %s", content)
 	}
 }
+
+func TestParseGifDetail(t *testing.T) {
+	mockHTML := `<html><head>
+	<title>Cat claimed his hooman GIF | GifVif</title>
+	<meta name="description" content="Cat claimed his hooman gif from the cat gifs collection on GifVif. Share, comment,embed or download your favorite GIF now!">
+	<script type="application/ld+json">{"@context":"https://schema.org","@type":"WebSite","name":"GifVif"}</script>
+	<script type="application/ld+json">{
+		"@context": "https://schema.org",
+		"@type": "ImageObject",
+		"name": "Cat claimed his hooman",
+		"description": "Cat claimed his hooman gif from the cat gifs collection on GifVif. Share,comment, embed, or download your favorite GIF now!",
+		"uploadDate": "2025-12-03T05:03:43+01:00"
+	}</script>
+	</head><body>
+	<a href="https://www.gif-vif.com/collection/cat" class="category-link"><span class="category-name">@cat gifs</span></a>
+	<div class="gif-views"><span class="views-number">1.2K</span><span class="views-text"> views</span>
+	<div class="gif-upload-date">Upload Date - December 3, 2025</div></div>
+	<img class="media-show" src="/gmedia/cat-claimed-his-hooman.gif" alt="Cat claimed his hooman">
+	<h1 class="hashtag-text">#cat #Catclaimedhishooman #Cat #claimed #his #hooman</h1>
+	</body></html>`
+
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(mockHTML))
+	page, _ := url.Parse("https://www.gif-vif.com/gifs/cat-claimed-his-hooman")
+	meme, ok := parseGifDetail(doc.Selection, page)
+	if !ok {
+		t.Fatal("解析失敗")
+	}
+
+	if meme.URL != "https://www.gif-vif.com/gmedia/cat-claimed-his-hooman.gif" || meme.Title != "Cat claimed his hooman" {
+		t.Errorf("網址或標題不符: %+v", meme)
+	}
+	if meme.Description != "Cat claimed his hooman gif from the cat gifs collection on GifVif." {
+		t.Errorf("描述未去除宣傳文字: %q", meme.Description)
+	}
+	if meme.Category != "cat" || meme.UploadedAt != "2025-12-03" || meme.SourceViews != 1200 {
+		t.Errorf("分類、日期或瀏覽數不符: %+v", meme)
+	}
+	if meme.Tags != "cat, Catclaimedhishooman, claimed, his, hooman" {
+		t.Errorf("標籤不符: %q", meme.Tags)
+	}
+
+	// 列表頁沒有 img.media-show，不應被當成一筆資料
+	list, _ := goquery.NewDocumentFromReader(strings.NewReader(`<html><body><a href="/gifs/a">a</a></body></html>`))
+	if _, ok := parseGifDetail(list.Selection, page); ok {
+		t.Error("列表頁不應解析出資料")
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [即時新資料 (Server-Sent Events)]
+// =========================================================
+
+const (
+	streamBuffer    = 64               // 每個連線最多暫存的事件數，瀏覽器跟不上時之後的事件會被丟棄
+	streamBackfill  = 100              // 帶 Last-Event-ID 重連時最多補送的筆數
+	streamHeartbeat = 25 * time.Second // 定期送出註解，避免代理伺服器把閒置連線切斷
+)
+
+// maxStreamClients 是同時連線數的上限 (STREAM_MAX_CLIENTS)
+var maxStreamClients = int64(envInt("STREAM_MAX_CLIENTS", 200))
+
+var streamClients atomic.Int64
+
+var streamSources = []string{"gif", "ptt", "threads", "plurk", "other"}
+
+// startMemeFeed 定期檢查爬蟲 (另一個程序) 寫入的資料並發布到 memeEvents。
+// 間隔由 STREAM_POLL_INTERVAL 設定 (預設 5s，設為 0 關閉)
+func startMemeFeed() {
+	interval := 5 * time.Second
+	if env := os.Getenv("STREAM_POLL_INTERVAL"); env != "" {
+		d, err := time.ParseDuration(env)
+		if err != nil {
+			log.Printf("[Stream] STREAM_POLL_INTERVAL 格式錯誤: %v", err)
+			return
+		}
+		interval = d
+	}
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for range time.Tick(interval) {
+			if err := pollNewMemes(); err != nil {
+				log.Printf("[Stream] 讀取新資料失敗: %v", err)
+			}
+		}
+	}()
+}
+
+// pollNewMemes 發布 id 大於已發布位置的資料
+func pollNewMemes() error {
+	latest, err := LatestMemeID()
+	if err != nil {
+		return err
+	}
+	// 爬蟲重建了資料庫，id 從頭開始
+	if latest < memeEvents.LastID() {
+		memeEvents.Reset(0)
+	}
+	for {
+		memes, err := ListMemesAfter(memeEvents.LastID(), "all", streamBackfill)
+		if err != nil {
+			return err
+		}
+		for _, m := range memes {
+			memeEvents.Publish(m)
+		}
+		if len(memes) < streamBackfill {
+			return nil
+		}
+	}
+}
+
+// streamHandler GET /api/stream?mode=&source=
+func streamHandler(c *gin.Context) {
+	mode := c.DefaultQuery("mode", "all")
+	if !slices.Contains(v1Modes, mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode 需為 all、image 或 text"})
+		return
+	}
+	source := c.Query("source")
+	if source != "" && !slices.Contains(streamSources, source) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未知的來源: " + source})
+		return
+	}
+	match := func(m Meme) bool {
+		return matchesKind(m, mode) && (source == "" || sourceName(m.SourceURL) == source)
+	}
+
+	if streamClients.Add(1) > maxStreamClients {
+		streamClients.Add(-1)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "連線數已達上限，請稍後再試"})
+		return
+	}
+	defer streamClients.Add(-1)
+
+	// 先訂閱再補送，補送期間寫入的資料才不會漏掉
+	events, unsubscribe := memeEvents.Subscribe(streamBuffer)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 關閉 nginx 的緩衝
+	c.Render(http.StatusOK, sse.Event{Event: "ready", Retry: 5000, Data: gin.H{"mode": mode, "source": source}})
+	c.Writer.Flush()
+
+	// 瀏覽器重連時會帶上最後收到的 id (EventSource 自動處理)
+	var sent int64
+	if lastID, err := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64); err == nil && lastID > 0 {
+		sent = lastID
+		memes, err := ListMemesAfter(lastID, mode, streamBackfill)
+		if err != nil {
+			log.Printf("[Stream] 補送失敗: %v", err)
+		}
+		for _, m := range memes {
+			if match(m) {
+				sendMemeEvent(c, m)
+			}
+			sent = m.ID
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			w.Write([]byte(": ping\n\n"))
+		case m := <-events:
+			if m.ID > sent && match(m) {
+				sendMemeEvent(c, m)
+				sent = m.ID
+			}
+		}
+		return true
+	})
+}
+
+func sendMemeEvent(c *gin.Context, m Meme) {
+	c.Render(-1, sse.Event{Event: "meme", Id: strconv.FormatInt(m.ID, 10), Data: m})
+	c.Writer.Flush()
+}
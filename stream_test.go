@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemeBusPublish(t *testing.T) {
+	bus := NewMemeBus()
+	fast, unsubscribe := bus.Subscribe(10)
+	slow, _ := bus.Subscribe(1)
+
+	bus.Publish(Meme{ID: 1})
+	bus.Publish(Meme{ID: 2})
+	bus.Publish(Meme{ID: 2}) // 同一筆不重複發布
+	bus.Publish(Meme{ID: 1})
+
+	if len(fast) != 2 {
+		t.Errorf("應收到 2 筆，得到 %d", len(fast))
+	}
+	// 跟不上的訂閱者不會卡住發布
+	if len(slow) != 1 || (<-slow).ID != 1 {
+		t.Errorf("buffer 滿了之後應丟棄")
+	}
+
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(Meme{ID: 3})
+	if len(fast) != 2 {
+		t.Errorf("取消訂閱後不應再收到")
+	}
+	if bus.LastID() != 3 {
+		t.Errorf("LastID 應為 3，得到 %d", bus.LastID())
+	}
+}
+
+// sseEvent 是解析後的一個事件
+type sseEvent struct {
+	Event, ID, Data string
+}
+
+// openStream 連上 /api/stream，回傳事件的 channel (收到 ready 之後才回傳，確保已經訂閱)
+func openStream(t *testing.T, srv *httptest.Server, query, lastEventID string) <-chan sseEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/stream"+query, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("連線失敗: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("應回傳事件串流: %d %s", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+
+	events := make(chan sseEvent, 10)
+	go func() {
+		defer res.Body.Close()
+		defer close(events)
+		var ev sseEvent
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if ev.Event != "" {
+					events <- ev
+				}
+				ev = sseEvent{}
+				continue
+			}
+			key, value, _ := strings.Cut(line, ":")
+			switch key {
+			case "event":
+				ev.Event = value
+			case "id":
+				ev.ID = value
+			case "data":
+				ev.Data = value
+			}
+		}
+	}()
+
+	if ev := nextEvent(t, events); ev.Event != "ready" {
+		t.Fatalf("第一個事件應為 ready: %+v", ev)
+	}
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatalf("串流提早結束")
+		}
+		return ev
+	case <-time.After(3 * time.Second):
+		t.Fatalf("等不到事件")
+	}
+	return sseEvent{}
+}
+
+func TestStreamPushesNewMemes(t *testing.T) {
+	srv := httptest.NewServer(setupTestServer(t,
+		ExportMeme{Title: "舊的", URL: "https://example.com/1.gif", Tags: "GIF", SourceURL: "https://www.gif-vif.com/gifs/1"},
+	))
+	t.Cleanup(srv.Close) // 先於 openStream 註冊，結束時才會先斷開串流再關閉伺服器
+
+	events := openStream(t, srv, "?mode=text", "")
+	InsertMeme(ExportMeme{Title: "新的 GIF", URL: "https://example.com/2.gif", Tags: "GIF", SourceURL: "https://www.gif-vif.com/gifs/2"})
+	InsertMeme(ExportMeme{Title: "舊的", URL: "https://example.com/1.gif", Tags: "GIF", SourceURL: "https://www.gif-vif.com/gifs/1"})
+	InsertMeme(ExportMeme{Title: "新的複製文", URL: "剛爬到的", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html"})
+
+	ev := nextEvent(t, events)
+	var m Meme
+	json.Unmarshal([]byte(ev.Data), &m)
+	if ev.Event != "meme" || ev.ID != strconv.FormatInt(m.ID, 10) || m.Title != "新的複製文" {
+		t.Errorf("應只收到符合模式的新資料: %+v", ev)
+	}
+
+	for _, q := range []string{"?mode=gif", "?source=facebook"} {
+		if w := doRequest(srv.Config.Handler, "GET", "/api/stream"+q); w.Code != http.StatusBadRequest {
+			t.Errorf("%s 應回傳 400，得到 %d", q, w.Code)
+		}
+	}
+}
+
+func TestStreamBackfillsAfterLastEventID(t *testing.T) {
+	srv := httptest.NewServer(setupTestServer(t,
+		ExportMeme{Title: "PTT 1", URL: "文字 1", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/Joke/M.1.html"},
+		ExportMeme{Title: "Plurk", URL: "文字 2", Tags: "Plurk", SourceURL: "https://www.plurk.com/p/2"},
+		ExportMeme{Title: "PTT 3", URL: "文字 3", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/Joke/M.3.html"},
+	))
+	t.Cleanup(srv.Close) // 先於 openStream 註冊，結束時才會先斷開串流再關閉伺服器
+
+	// 重連時補送斷線期間的資料，之後照常推送
+	events := openStream(t, srv, "?source=ptt", "1")
+	if ev := nextEvent(t, events); ev.ID != "3" {
+		t.Errorf("應補送 id 3: %+v", ev)
+	}
+	InsertMeme(ExportMeme{Title: "PTT 4", URL: "文字 4", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/Joke/M.4.html"})
+	if ev := nextEvent(t, events); ev.ID != "4" {
+		t.Errorf("應收到新的 id 4: %+v", ev)
+	}
+}
+
+func TestPollNewMemesPublishesOtherProcessWrites(t *testing.T) {
+	setupTestServer(t, ExportMeme{Title: "舊的", URL: "文字 1", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/a.html"})
+	events, unsubscribe := memeEvents.Subscribe(10)
+	defer unsubscribe()
+
+	// 爬蟲直接寫入資料庫，不會經過這個程序的 InsertMeme
+	db.Exec(`INSERT INTO memes (title, url, tags, source_url) VALUES ('爬蟲寫的', '文字 2', 'PTT', 'https://www.ptt.cc/bbs/b.html')`)
+	if err := pollNewMemes(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || (<-events).Title != "爬蟲寫的" {
+		t.Fatalf("應發布另一個程序寫入的資料")
+	}
+	if err := pollNewMemes(); err != nil || len(events) != 0 {
+		t.Errorf("同一筆不應重複發布")
+	}
+
+	// 爬蟲重建資料庫後 id 從頭開始
+	db.Exec(`DELETE FROM memes`)
+	db.Exec(`DELETE FROM sqlite_sequence WHERE name = 'memes'`)
+	db.Exec(`INSERT INTO memes (title, url, tags, source_url) VALUES ('重建後', '文字 3', 'PTT', 'https://www.ptt.cc/bbs/c.html')`)
+	pollNewMemes()
+	if len(events) != 1 || (<-events).Title != "重建後" {
+		t.Errorf("資料庫重建後應重新開始計算")
+	}
+}
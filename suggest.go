@@ -0,0 +1,272 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [自動完成：標題、標籤與熱門搜尋]
+// =========================================================
+
+const maxSuggestions = 20
+const maxRecordedQueryLen = 100 // 太長的查詢多半是貼上整段文字，不值得推薦
+
+// Suggestion 是一筆自動完成建議
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"` // query：熱門搜尋；title：標題；tag：標籤
+}
+
+// prefixEntry 是前綴索引中的一個詞，weight 為出現次數
+type prefixEntry struct {
+	key    string // 小寫，用來比對前綴
+	text   string
+	kind   string
+	weight int
+}
+
+// suggestIndex 把標題與標籤依小寫排序，以二分搜尋找出前綴相同的區段。
+// 新資料 (包含 startMemeFeed 讀到的爬蟲資料) 會推進 memeEvents 的位置，後台修改會累加 memeEdits，
+// 兩者有變化時才在下一次查詢重建，平常查詢不會碰資料庫
+type suggestIndex struct {
+	mu        sync.Mutex
+	entries   []prefixEntry
+	signature [2]int64
+	built     bool
+}
+
+func newSuggestIndex() *suggestIndex {
+	return &suggestIndex{}
+}
+
+func (idx *suggestIndex) snapshot() ([]prefixEntry, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// 先取位置再讀資料，重建期間的變動會讓下一次查詢再重建一次
+	sig := [2]int64{memeEvents.LastID(), memeEdits.Load()}
+	if idx.built && sig == idx.signature {
+		return idx.entries, nil
+	}
+
+	entries, err := buildPrefixEntries()
+	if err != nil {
+		return nil, err
+	}
+	idx.entries, idx.signature, idx.built = entries, sig, true
+	return entries, nil
+}
+
+// buildPrefixEntries 讀出所有標題與標籤，相同的詞 (不分大小寫) 合併並累加次數
+func buildPrefixEntries() ([]prefixEntry, error) {
+	rows, err := db.Query(`SELECT title, tags FROM memes WHERE ` + visibleSQL + aliveSQL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := map[string]*prefixEntry{}
+	add := func(text, kind string) {
+		text = strings.TrimSpace(text)
+		if text == "" || utf8.RuneCountInString(text) > maxRecordedQueryLen {
+			return
+		}
+		key := kind + "\x00" + strings.ToLower(text)
+		if e, ok := byKey[key]; ok {
+			e.weight++
+			return
+		}
+		byKey[key] = &prefixEntry{key: strings.ToLower(text), text: text, kind: kind, weight: 1}
+	}
+	for rows.Next() {
+		var title, tags string
+		if err := rows.Scan(&title, &tags); err != nil {
+			return nil, err
+		}
+		add(title, "title")
+		for _, tag := range strings.Split(tags, ",") {
+			add(tag, "tag")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]prefixEntry, 0, len(byKey))
+	for _, e := range byKey {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].key != entries[j].key {
+			return entries[i].key < entries[j].key
+		}
+		return entries[i].kind < entries[j].kind
+	})
+	return entries, nil
+}
+
+// Complete 回傳以 prefix 開頭的標題與標籤，次數多的排前面
+func (idx *suggestIndex) Complete(prefix string, limit int) ([]Suggestion, error) {
+	entries, err := idx.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.ToLower(prefix)
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key >= prefix })
+	var matches []prefixEntry
+	for i := start; i < len(entries) && strings.HasPrefix(entries[i].key, prefix); i++ {
+		matches = append(matches, entries[i])
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].weight > matches[j].weight })
+
+	suggestions := []Suggestion{}
+	for _, e := range matches {
+		if len(suggestions) >= limit {
+			break
+		}
+		suggestions = append(suggestions, Suggestion{Text: e.text, Kind: e.kind})
+	}
+	return suggestions, nil
+}
+
+// TagCount 是標籤與使用次數
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+// Tags 回傳以 prefix 開頭的標籤 (prefix 為空時為全部)，使用次數多的排前面
+func (idx *suggestIndex) Tags(prefix string, limit int) ([]TagCount, error) {
+	entries, err := idx.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	prefix = strings.ToLower(prefix)
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key >= prefix })
+	tags := []TagCount{}
+	for i := start; i < len(entries) && strings.HasPrefix(entries[i].key, prefix); i++ {
+		if entries[i].kind == "tag" {
+			tags = append(tags, TagCount{Name: entries[i].text, Count: entries[i].weight})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].Count > tags[j].Count })
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	return tags, nil
+}
+
+// TagUsage 回傳標籤 (不分大小寫) 的使用次數
+func (idx *suggestIndex) TagUsage(name string) (int, error) {
+	entries, err := idx.snapshot()
+	if err != nil {
+		return 0, err
+	}
+	key := strings.ToLower(strings.TrimSpace(name))
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].key > key || (entries[i].key == key && entries[i].kind >= "tag")
+	})
+	if i < len(entries) && entries[i].key == key && entries[i].kind == "tag" {
+		return entries[i].weight, nil
+	}
+	return 0, nil
+}
+
+// ---------------------------------------------------------
+// 熱門搜尋
+// ---------------------------------------------------------
+
+// normalizeRecordedQuery 統一空白與大小寫，太長或空白的查詢回傳空字串
+func normalizeRecordedQuery(q string) string {
+	q = strings.ToLower(strings.Join(strings.Fields(q), " "))
+	if utf8.RuneCountInString(q) > maxRecordedQueryLen {
+		return ""
+	}
+	return q
+}
+
+// RecordPopularQuery 累加一次搜尋。只記錄有結果的查詢，避免把打錯的字推薦給別人
+func RecordPopularQuery(q string) error {
+	if q = normalizeRecordedQuery(q); q == "" {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO popular_queries (query, count) VALUES (?, 1)
+		ON CONFLICT(query) DO UPDATE SET count = count + 1, last_searched_at = CURRENT_TIMESTAMP`, q)
+	return err
+}
+
+// PopularQueries 回傳以 prefix 開頭、搜尋次數最多的查詢
+func PopularQueries(prefix string, limit int) ([]string, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(prefix))
+	rows, err := db.Query(`SELECT query FROM popular_queries WHERE query LIKE ? ESCAPE '\'
+		ORDER BY count DESC, last_searched_at DESC LIMIT ?`, escaped+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []string
+	for rows.Next() {
+		var q string
+		if err := rows.Scan(&q); err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// Suggest 合併熱門搜尋與標題/標籤補完。熱門搜尋排前面但最多佔一半，重複的 (不分大小寫) 只留一個
+func (idx *suggestIndex) Suggest(prefix string, limit int) ([]Suggestion, error) {
+	prefix = strings.TrimLeft(prefix, " \t")
+	if prefix == "" {
+		return []Suggestion{}, nil
+	}
+	queries, err := PopularQueries(prefix, (limit+1)/2)
+	if err != nil {
+		return nil, err
+	}
+	completions, err := idx.Complete(prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []Suggestion{}
+	seen := map[string]bool{}
+	add := func(s Suggestion) {
+		key := strings.ToLower(s.Text)
+		if !seen[key] && len(suggestions) < limit {
+			seen[key] = true
+			suggestions = append(suggestions, s)
+		}
+	}
+	for _, q := range queries {
+		add(Suggestion{Text: q, Kind: "query"})
+	}
+	for _, s := range completions {
+		add(s)
+	}
+	return suggestions, nil
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+func registerSuggestRoutes(api *gin.RouterGroup, idx *suggestIndex) {
+	// GET /api/suggest?q=&limit=
+	api.GET("/suggest", func(c *gin.Context) {
+		suggestions, err := idx.Suggest(c.Query("q"), queryInt(c, "limit", 8, maxSuggestions))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, suggestions)
+	})
+}
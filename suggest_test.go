@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "Cat jumps", URL: "https://example.com/1.gif", Tags: "cat, jump", SourceURL: "https://www.gif-vif.com/gifs/1"},
+		ExportMeme{Title: "Catch me", URL: "https://example.com/2.gif", Tags: "cat, run", SourceURL: "https://www.gif-vif.com/gifs/2"},
+		ExportMeme{Title: "貓咪跳舞", URL: "https://example.com/3.gif", Tags: "貓咪", SourceURL: "https://www.gif-vif.com/gifs/3"},
+	)
+	suggest := func(q string) []Suggestion {
+		t.Helper()
+		var s []Suggestion
+		w := doRequest(r, "GET", "/api/suggest?q="+url.QueryEscape(q))
+		if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+			t.Fatalf("回應不是建議列表: %d %s (%v)", w.Code, w.Body.String(), err)
+		}
+		return s
+	}
+
+	// 兩筆都有的標籤 cat 次數最多，排在標題前面
+	got := suggest("CA")
+	if len(got) != 3 || got[0] != (Suggestion{Text: "cat", Kind: "tag"}) {
+		t.Fatalf("前綴 CA 的建議不符: %+v", got)
+	}
+	if got := suggest("貓"); len(got) != 2 {
+		t.Errorf("中文前綴應找到標籤與標題: %+v", got)
+	}
+	if got := suggest(""); len(got) != 0 {
+		t.Errorf("空白查詢不應有建議: %+v", got)
+	}
+
+	// 有結果的搜尋才會成為熱門搜尋，並排在最前面
+	doRequest(r, "GET", "/api/search?q="+url.QueryEscape("Cat  Jumps"))
+	doRequest(r, "GET", "/api/search?q=catastrophe")
+	got = suggest("cat")
+	if got[0] != (Suggestion{Text: "cat jumps", Kind: "query"}) {
+		t.Errorf("熱門搜尋應排第一: %+v", got)
+	}
+	for _, s := range got {
+		if s.Text == "catastrophe" {
+			t.Errorf("沒有結果的搜尋不應被推薦")
+		}
+		if s.Kind == "title" && s.Text == "Cat jumps" {
+			t.Errorf("與熱門搜尋重複的標題應只出現一次")
+		}
+	}
+
+	// 爬蟲寫入新資料後索引自動重建
+	InsertMeme(ExportMeme{Title: "Cattle", URL: "https://example.com/4.gif", SourceURL: "https://www.gif-vif.com/gifs/4"})
+	found := false
+	for _, s := range suggest("catt") {
+		found = found || s.Text == "Cattle"
+	}
+	if !found {
+		t.Errorf("新增的資料應出現在建議中")
+	}
+
+	// 後台修改或下架後也會重建
+	if _, err := UpdateMeme(4, Meme{Title: "Cattle dog", URL: "https://example.com/4.gif", SourceURL: "https://www.gif-vif.com/gifs/4"}, "test"); err != nil {
+		t.Fatalf("修改失敗: %v", err)
+	}
+	if got := suggest("cattle"); len(got) != 1 || got[0].Text != "Cattle dog" {
+		t.Errorf("修改後的標題應取代舊標題: %+v", got)
+	}
+	SetMemeDeleted(4, true, "test")
+	if got := suggest("cattle"); len(got) != 0 {
+		t.Errorf("下架的資料不應出現在建議中: %+v", got)
+	}
+}
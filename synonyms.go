@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [同義詞與中英對照：搜尋時自動展開關鍵字]
+// =========================================================
+
+// SynonymSeedFile 是內建的對照表，第一次啟動 (synonyms 表為空) 時匯入
+const SynonymSeedFile = "synonyms.txt"
+
+// 展開後最多再多搜幾個詞，避免 SQL 條件過長
+const maxExpansions = 10
+
+var (
+	ErrInvalidSynonyms    = errors.New("同義詞組至少需要兩個不重複的詞")
+	ErrInvalidSynonymTerm = errors.New("同義詞不能包含換行或其他控制字元")
+)
+
+// SynonymGroup 是一組彼此同義的詞 (例如 貓, cat, kitty)，搜尋任一個都會同時搜其他的
+type SynonymGroup struct {
+	ID        int64    `json:"id"`
+	Terms     []string `json:"terms"`
+	Source    string   `json:"source"` // seed：內建；admin：管理員新增或修改過
+	CreatedAt string   `json:"created_at"`
+}
+
+// normalizeTerms 去除空白與重複 (不分大小寫)，保留原本的順序
+func normalizeTerms(terms []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range terms {
+		t = strings.TrimSpace(t)
+		key := strings.ToLower(t)
+		if t == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// validTerms 檢查詞裡沒有控制字元 (換行是儲存時的分隔符號，混進來會把一個詞拆成兩個)
+func validTerms(terms []string) bool {
+	for _, t := range terms {
+		if strings.IndexFunc(t, unicode.IsControl) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 詞組以換行分隔存成一個欄位，詞本身不會包含換行 (寫入前由 validTerms 把關)
+func joinTerms(terms []string) string { return strings.Join(terms, "\n") }
+func splitTerms(s string) []string    { return strings.Split(s, "\n") }
+
+// ---------------------------------------------------------
+// 記憶體快取
+// ---------------------------------------------------------
+
+// synonymIndex 以小寫的詞對應到同組的其他詞，透過管理 API 修改後整個重建
+type synonymIndex struct {
+	mu     sync.RWMutex
+	byTerm map[string][]string
+	loaded bool
+}
+
+func newSynonymIndex() *synonymIndex {
+	return &synonymIndex{}
+}
+
+func (s *synonymIndex) invalidate() {
+	s.mu.Lock()
+	s.loaded = false
+	s.mu.Unlock()
+}
+
+func (s *synonymIndex) load() error {
+	s.mu.RLock()
+	loaded := s.loaded
+	s.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	groups, err := ListSynonymGroups()
+	if err != nil {
+		return err
+	}
+	byTerm := map[string][]string{}
+	for _, g := range groups {
+		for _, t := range g.Terms {
+			key := strings.ToLower(t)
+			for _, other := range g.Terms {
+				if other != t {
+					byTerm[key] = append(byTerm[key], other)
+				}
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.byTerm, s.loaded = byTerm, true
+	s.mu.Unlock()
+	return nil
+}
+
+// Expand 回傳 query 的同義詞 (不含 query 本身)。
+// 整個查詢剛好是某個詞時直接展開；查詢由多個詞組成時，逐一替換其中有對照的詞
+func (s *synonymIndex) Expand(query string) []string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+	if err := s.load(); err != nil {
+		log.Printf("[Synonyms] 載入失敗: %v", err)
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidates := append([]string{}, s.byTerm[strings.ToLower(query)]...)
+	if words := strings.Fields(query); len(words) > 1 {
+		for i, w := range words {
+			for _, alt := range s.byTerm[strings.ToLower(w)] {
+				replaced := append([]string{}, words...)
+				replaced[i] = alt
+				candidates = append(candidates, strings.Join(replaced, " "))
+			}
+		}
+	}
+
+	expanded := normalizeTerms(append([]string{query}, candidates...))[1:]
+	if len(expanded) > maxExpansions {
+		expanded = expanded[:maxExpansions]
+	}
+	return expanded
+}
+
+// ---------------------------------------------------------
+// 資料存取
+// ---------------------------------------------------------
+
+func scanSynonymGroup(row rowScanner) (SynonymGroup, error) {
+	var g SynonymGroup
+	var terms string
+	if err := row.Scan(&g.ID, &terms, &g.Source, &g.CreatedAt); err != nil {
+		return SynonymGroup{}, err
+	}
+	g.Terms = splitTerms(terms)
+	return g, nil
+}
+
+func ListSynonymGroups() ([]SynonymGroup, error) {
+	rows, err := db.Query(`SELECT id, terms, source, created_at FROM synonyms ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []SynonymGroup{}
+	for rows.Next() {
+		g, err := scanSynonymGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func getSynonymGroup(id int64) (SynonymGroup, error) {
+	g, err := scanSynonymGroup(db.QueryRow(`SELECT id, terms, source, created_at FROM synonyms WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return SynonymGroup{}, ErrNotFound
+	}
+	return g, err
+}
+
+func CreateSynonymGroup(terms []string, source string) (SynonymGroup, error) {
+	if !validTerms(terms) {
+		return SynonymGroup{}, ErrInvalidSynonymTerm
+	}
+	terms = normalizeTerms(terms)
+	if len(terms) < 2 {
+		return SynonymGroup{}, ErrInvalidSynonyms
+	}
+	res, err := db.Exec(`INSERT INTO synonyms (terms, source) VALUES (?, ?)`, joinTerms(terms), source)
+	if err != nil {
+		return SynonymGroup{}, err
+	}
+	id, _ := res.LastInsertId()
+	return getSynonymGroup(id)
+}
+
+func UpdateSynonymGroup(id int64, terms []string) (SynonymGroup, error) {
+	if !validTerms(terms) {
+		return SynonymGroup{}, ErrInvalidSynonymTerm
+	}
+	terms = normalizeTerms(terms)
+	if len(terms) < 2 {
+		return SynonymGroup{}, ErrInvalidSynonyms
+	}
+	res, err := db.Exec(`UPDATE synonyms SET terms = ?, source = 'admin' WHERE id = ?`, joinTerms(terms), id)
+	if err != nil {
+		return SynonymGroup{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return SynonymGroup{}, ErrNotFound
+	}
+	return getSynonymGroup(id)
+}
+
+func DeleteSynonymGroup(id int64) error {
+	res, err := db.Exec(`DELETE FROM synonyms WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SeedSynonyms 在 synonyms 表為空時匯入內建對照表。
+// 檔案格式：一行一組，詞之間以逗號分隔，# 開頭為註解。之後的修改以資料庫為準，不會被覆蓋。
+// 內建的對照表編譯在執行檔內，由 assetFS() 提供
+func SeedSynonyms(fsys fs.FS, name string) (int, error) {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM synonyms`).Scan(&count); err != nil || count > 0 {
+		return 0, err
+	}
+	f, err := fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if _, err := CreateSynonymGroup(strings.Split(text, ","), "seed"); err != nil {
+			return count, fmt.Errorf("%s 第 %d 行: %v", name, line, err)
+		}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+// ---------------------------------------------------------
+// HTTP 處理 (掛在管理員 API 底下)
+// ---------------------------------------------------------
+
+type synonymInput struct {
+	Terms []string `json:"terms"`
+}
+
+func respondSynonymError(c *gin.Context, err error) {
+	if errors.Is(err, ErrInvalidSynonyms) || errors.Is(err, ErrInvalidSynonymTerm) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondStoreError(c, err)
+}
+
+func registerSynonymRoutes(admin *gin.RouterGroup, syn *synonymIndex) {
+	admin.GET("/synonyms", func(c *gin.Context) {
+		groups, err := ListSynonymGroups()
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, groups)
+	})
+
+	admin.POST("/synonyms", func(c *gin.Context) {
+		var in synonymInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		g, err := CreateSynonymGroup(in.Terms, "admin")
+		if err != nil {
+			respondSynonymError(c, err)
+			return
+		}
+		syn.invalidate()
+		c.JSON(http.StatusCreated, g)
+	})
+
+	admin.PUT("/synonyms/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var in synonymInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		g, err := UpdateSynonymGroup(id, in.Terms)
+		if err != nil {
+			respondSynonymError(c, err)
+			return
+		}
+		syn.invalidate()
+		c.JSON(http.StatusOK, g)
+	})
+
+	admin.DELETE("/synonyms/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := DeleteSynonymGroup(id); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		syn.invalidate()
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBilingualSearch(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t,
+		ExportMeme{Title: "Cat jumps off the table", URL: "https://example.com/cat.gif", SourceURL: "https://www.gif-vif.com/gifs/cat"},
+		ExportMeme{Title: "貓咪打翻水杯", URL: "https://example.com/water.gif", SourceURL: "https://www.gif-vif.com/gifs/water"},
+		ExportMeme{Title: "Happy dog", URL: "https://example.com/dog.gif", SourceURL: "https://www.gif-vif.com/gifs/dog"},
+	)
+
+	seedDir := t.TempDir()
+	os.WriteFile(filepath.Join(seedDir, "synonyms.txt"), []byte("# 註解\n貓, cat, kitty\n\n狗, dog\n"), 0644)
+	seed := os.DirFS(seedDir)
+	if n, err := SeedSynonyms(seed, "synonyms.txt"); err != nil || n != 2 {
+		t.Fatalf("預期匯入 2 組，得到 %d (%v)", n, err)
+	}
+	if n, _ := SeedSynonyms(seed, "synonyms.txt"); n != 0 {
+		t.Errorf("已有資料時不應重複匯入")
+	}
+
+	// 中文查詢找到英文標題，並標示命中的詞
+	w := doRequest(r, "GET", "/api/search?q="+url.QueryEscape("貓"))
+	var results []Meme
+	json.Unmarshal(w.Body.Bytes(), &results)
+	if len(results) != 2 {
+		t.Fatalf("搜尋「貓」應同時找到中英文標題，得到 %d 筆", len(results))
+	}
+	for _, m := range results {
+		if strings.HasPrefix(m.Title, "Cat") && (len(m.MatchedTerms) != 1 || m.MatchedTerms[0] != "cat") {
+			t.Errorf("英文標題應標示命中 cat: %+v", m.MatchedTerms)
+		}
+	}
+	if got := w.Header().Get("X-Search-Expansions"); got != "cat,kitty" {
+		t.Errorf("展開的詞不符: %q", got)
+	}
+
+	// 反過來也成立，多個字的查詢會替換其中有對照的詞
+	json.Unmarshal(doRequest(r, "GET", "/api/search?q=happy+%E7%8B%97").Body.Bytes(), &results)
+	if len(results) != 1 || results[0].Title != "Happy dog" {
+		t.Errorf("「happy 狗」應找到 Happy dog: %+v", results)
+	}
+
+	// 沒有對照時不展開
+	w = doRequest(r, "GET", "/api/search?q=table")
+	if w.Header().Get("X-Search-Expansions") != "" || strings.Contains(w.Body.String(), "matched_terms") {
+		t.Errorf("沒有同義詞時不應展開: %s", w.Body.String())
+	}
+
+	// 管理員新增、修改與刪除，立即生效
+	w = adminRequest(r, "POST", "/api/admin/synonyms", map[string]any{"terms": []string{"水杯", "cup", " CUP "}})
+	var group SynonymGroup
+	json.Unmarshal(w.Body.Bytes(), &group)
+	if w.Code != http.StatusCreated || len(group.Terms) != 2 || group.Source != "admin" {
+		t.Fatalf("新增同義詞失敗: %d %s", w.Code, w.Body.String())
+	}
+	json.Unmarshal(doRequest(r, "GET", "/api/search?q=cup").Body.Bytes(), &results)
+	if len(results) != 1 || results[0].Title != "貓咪打翻水杯" {
+		t.Errorf("新增後搜尋 cup 應找到水杯: %+v", results)
+	}
+
+	if w := adminRequest(r, "PUT", "/api/admin/synonyms/1", map[string]any{"terms": []string{"貓"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("少於兩個詞預期 400，得到 %d", w.Code)
+	}
+	// 換行是儲存時的分隔符號，含控制字元的詞一律拒絕
+	if w := adminRequest(r, "POST", "/api/admin/synonyms", map[string]any{"terms": []string{"狗\n狗狗", "dog"}}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "控制字元") {
+		t.Errorf("含換行的詞預期 400，得到 %d %s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(r, "PUT", "/api/admin/synonyms/1", map[string]any{"terms": []string{"貓", "kit\tty"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("修改時含控制字元預期 400，得到 %d", w.Code)
+	}
+	if w := adminRequest(r, "DELETE", "/api/admin/synonyms/abc", nil); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "id 格式錯誤") {
+		t.Errorf("id 格式錯誤預期 400，得到 %d %s", w.Code, w.Body.String())
+	}
+	adminRequest(r, "PUT", "/api/admin/synonyms/1", map[string]any{"terms": []string{"貓", "kitty"}})
+	json.Unmarshal(doRequest(r, "GET", "/api/search?q="+url.QueryEscape("貓")).Body.Bytes(), &results)
+	if len(results) != 1 {
+		t.Errorf("移除 cat 後只應找到中文標題，得到 %d 筆", len(results))
+	}
+
+	if w := adminRequest(r, "DELETE", "/api/admin/synonyms/2", nil); w.Code != http.StatusNoContent {
+		t.Errorf("刪除預期 204，得到 %d", w.Code)
+	}
+	if w := adminRequest(r, "DELETE", "/api/admin/synonyms/2", nil); w.Code != http.StatusNotFound {
+		t.Errorf("重複刪除預期 404，得到 %d", w.Code)
+	}
+	var groups []SynonymGroup
+	json.Unmarshal(adminRequest(r, "GET", "/api/admin/synonyms", nil).Body.Bytes(), &groups)
+	if len(groups) != 2 || groups[0].Source != "admin" {
+		t.Errorf("列表不符: %+v", groups)
+	}
+}
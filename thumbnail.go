@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"log"
+	"os"
+)
+
+// =========================================================
+// [縮圖：GIF 的靜態封面與縮小版動畫]
+// =========================================================
+
+// 封面給搜尋結果卡片用，預覽在滑鼠移上去時才載入
+const posterMaxWidth = 320
+const previewMaxWidth = 200
+const maxPreviewFrames = 60 // 影格太多時平均抽掉一些，總時長不變
+
+const (
+	thumbPoster  = "poster"
+	thumbPreview = "preview"
+)
+
+// 解碼前先檢查檔頭宣告的尺寸與影格數，避免惡意檔案 (例如宣告 65535x65535 或數千格) 用光記憶體
+const (
+	maxImageSide      = 4096
+	maxGIFFrames      = 1000
+	maxGIFTotalPixels = 64 << 20 // 所有影格的像素總數上限 (影格不會超出畫面大小，以畫面大小估計)
+)
+
+var ErrNotGIF = errors.New("只支援 GIF 縮圖")
+var ErrUnsupportedImage = errors.New("無法解析圖片 (支援 GIF、JPEG、PNG)")
+var ErrImageTooLarge = errors.New("圖片尺寸或影格數超過上限")
+
+// thumbnailPath 縮圖與原檔放在同一個目錄 (media/ab/<hash>.poster.jpg)
+func thumbnailPath(hash, kind string) string {
+	if kind == thumbPoster {
+		return mediaPath(hash) + ".poster.jpg"
+	}
+	return mediaPath(hash) + ".preview.gif"
+}
+
+// thumbnailsReady 判斷鏡像檔是否有縮圖：依實際內容的 mime type (網址寫 .gif 不一定真的是 GIF)，且檔案都已產生。
+// 結果記錄在 memes.media_thumbnails，回應中的 thumbnail_url / preview_url 依此決定
+func thumbnailsReady(asset MediaAsset) bool {
+	return asset.MimeType == "image/gif" &&
+		fileExists(thumbnailPath(asset.Hash, thumbPoster)) && fileExists(thumbnailPath(asset.Hash, thumbPreview))
+}
+
+// GenerateThumbnails 讀取鏡像的 GIF，產生封面 (第一格，JPEG) 與縮小版動畫，已存在的不重做。
+// 在鏡像時呼叫 (MirrorMedia)，檔案以改名的方式寫入，同時產生同一個檔案也不會讀到寫到一半的內容
+func GenerateThumbnails(hash string) error {
+	posterPath, previewPath := thumbnailPath(hash, thumbPoster), thumbnailPath(hash, thumbPreview)
+	if fileExists(posterPath) && fileExists(previewPath) {
+		return nil
+	}
+
+	f, err := os.Open(mediaPath(hash))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if format, err := checkImageSize(f); err != nil {
+		return err
+	} else if format != "gif" {
+		return ErrNotGIF
+	}
+	g, err := gif.DecodeAll(f)
+	if err != nil || len(g.Image) == 0 {
+		return ErrNotGIF
+	}
+
+	// 預覽只用到部分影格，其他的不必複製
+	step := previewStep(len(g.Image))
+	frames := renderGIFFrames(g, func(i int) bool { return i%step == 0 })
+
+	// 封面：透明部分補白底，避免 JPEG 變成黑色
+	first := image.NewRGBA(frames[0].Bounds())
+	draw.Draw(first, first.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(first, first.Bounds(), frames[0], image.Point{}, draw.Over)
+	if err := writeFileAtomic(posterPath, func(out *os.File) error {
+		return jpeg.Encode(out, downscale(first, posterMaxWidth), &jpeg.Options{Quality: 80})
+	}); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(previewPath, func(out *os.File) error {
+		return gif.EncodeAll(out, buildPreview(g, frames))
+	})
+}
+
+// checkImageSize 只讀檔頭取得格式與尺寸 (不解碼像素)，GIF 另外數影格數，檢查完回到檔案開頭
+func checkImageSize(r io.ReadSeeker) (string, error) {
+	cfg, format, err := image.DecodeConfig(r)
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxImageSide || cfg.Height > maxImageSide {
+		return "", ErrImageTooLarge
+	}
+	if format == "gif" {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		limit := min(maxGIFFrames, maxGIFTotalPixels/(cfg.Width*cfg.Height))
+		n, err := countGIFFrames(r, limit)
+		if err != nil {
+			return "", ErrUnsupportedImage
+		}
+		if n > limit {
+			return "", ErrImageTooLarge
+		}
+	}
+	_, err = r.Seek(0, io.SeekStart)
+	return format, err
+}
+
+// countGIFFrames 依 GIF 的區塊結構數影格，只跳過壓縮資料不解碼；超過 limit 就提早停止
+func countGIFFrames(r io.Reader, limit int) (int, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return 0, err
+	}
+	if header[10]&0x80 != 0 {
+		if _, err := br.Discard(3 << (header[10]&0x07 + 1)); err != nil {
+			return 0, err
+		}
+	}
+
+	skipSubBlocks := func() error {
+		for {
+			size, err := br.ReadByte()
+			if err != nil || size == 0 {
+				return err
+			}
+			if _, err := br.Discard(int(size)); err != nil {
+				return err
+			}
+		}
+	}
+
+	frames := 0
+	for frames <= limit {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case 0x21: // 擴充區塊：標籤加上資料子區塊
+			if _, err := br.ReadByte(); err != nil {
+				return 0, err
+			}
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+		case 0x2c: // 影格：描述、區域調色盤、LZW 最小碼長與資料子區塊
+			desc := make([]byte, 9)
+			if _, err := io.ReadFull(br, desc); err != nil {
+				return 0, err
+			}
+			if desc[8]&0x80 != 0 {
+				if _, err := br.Discard(3 << (desc[8]&0x07 + 1)); err != nil {
+					return 0, err
+				}
+			}
+			if _, err := br.ReadByte(); err != nil {
+				return 0, err
+			}
+			if err := skipSubBlocks(); err != nil {
+				return 0, err
+			}
+			frames++
+		case 0x3b: // 結尾
+			return frames, nil
+		default:
+			return 0, ErrUnsupportedImage
+		}
+	}
+	return frames, nil
+}
+
+// GeneratePendingThumbnails 補產生舊鏡像檔 (或先前產生失敗) 的縮圖並更新 memes.media_thumbnails，回傳成功的檔案數
+func GeneratePendingThumbnails() (int, error) {
+	rows, err := db.Query(`SELECT DISTINCT media.hash FROM media JOIN memes ON memes.media_hash = media.hash
+		WHERE media.mime_type = 'image/gif' AND memes.media_thumbnails = 0`)
+	if err != nil {
+		return 0, err
+	}
+	var pending []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, h := range pending {
+		if err := GenerateThumbnails(h); err != nil {
+			log.Printf("[Thumbnail] 產生縮圖失敗 %s: %v", h, err)
+			continue
+		}
+		if _, err := db.Exec(`UPDATE memes SET media_thumbnails = 1 WHERE media_hash = ?`, h); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// renderGIFFrames 依 disposal 規則把每一格疊成完整畫面 (GIF 的影格通常只存和前一格不同的區塊)。
+// 所有影格都要依序疊上去，但只有 keep 回傳 true 的影格會複製保留，其他位置為 nil
+func renderGIFFrames(g *gif.GIF, keep func(i int) bool) []*image.RGBA {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() && len(g.Image) > 0 {
+		bounds = g.Image[0].Bounds()
+	}
+	canvas := image.NewRGBA(bounds)
+	frames := make([]*image.RGBA, len(g.Image))
+
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if keep(i) {
+			frames[i] = cloneRGBA(canvas)
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames
+}
+
+// previewStep 是預覽每隔幾格取一格
+func previewStep(frames int) int {
+	return (frames + maxPreviewFrames - 1) / maxPreviewFrames
+}
+
+// buildPreview 縮小每一格並重新配色，影格過多時合併相鄰影格的延遲
+func buildPreview(g *gif.GIF, frames []*image.RGBA) *gif.GIF {
+	step := previewStep(len(frames))
+	out := &gif.GIF{LoopCount: g.LoopCount}
+
+	for i, frame := range frames {
+		if i%step == 0 {
+			small := downscale(frame, previewMaxWidth)
+			// 沿用原影格的調色盤 (解碼時已帶入全域或區域調色盤)
+			dst := image.NewPaletted(small.Bounds(), g.Image[i].Palette)
+			draw.Draw(dst, dst.Bounds(), small, image.Point{}, draw.Src)
+			out.Image = append(out.Image, dst)
+			out.Delay = append(out.Delay, 0)
+		}
+		if i < len(g.Delay) {
+			out.Delay[len(out.Delay)-1] += g.Delay[i]
+		}
+	}
+	out.Config = image.Config{Width: out.Image[0].Bounds().Dx(), Height: out.Image[0].Bounds().Dy()}
+	return out
+}
+
+// downscale 以區域平均縮小到指定寬度 (不放大)，品質足夠縮圖使用且不需額外套件
+func downscale(src *image.RGBA, maxWidth int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	if sw <= maxWidth {
+		return src
+	}
+	dw := maxWidth
+	dh := max(1, sh*dw/sw)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, max((y+1)*sh/dh, y*sh/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, max((x+1)*sw/dw, x*sw/dw+1)
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				off := src.PixOffset(sb.Min.X+x0, sb.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[off])
+					g += uint32(src.Pix[off+1])
+					b += uint32(src.Pix[off+2])
+					a += uint32(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), uint8(a/n)
+		}
+	}
+	return dst
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeFileAtomic 先寫到暫存檔再改名，讀取端不會看到寫到一半的檔案
+func writeFileAtomic(path string, write func(*os.File) error) error {
+	if fileExists(path) {
+		return nil
+	}
+	tmp, err := os.CreateTemp(MediaDir, "thumb-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
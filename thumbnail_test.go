@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestGIFThumbnails(t *testing.T) {
+	// 400x300、90 格的動畫，每格只更新一小塊 (測試 disposal 疊圖)
+	anim := &gif.GIF{Config: image.Config{Width: 400, Height: 300}}
+	pal := color.Palette{color.White, color.Black, color.RGBA{255, 0, 0, 255}}
+	for i := 0; i < 90; i++ {
+		frame := image.NewPaletted(image.Rect(i*4, 0, i*4+10, 10), pal)
+		for p := range frame.Pix {
+			frame.Pix[p] = 2
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 5)
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	var buf bytes.Buffer
+	gif.EncodeAll(&buf, anim)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer origin.Close()
+
+	MediaDir = t.TempDir()
+	meme := ExportMeme{Title: "跑步的貓", URL: origin.URL + "/run.gif", SourceURL: "https://www.gif-vif.com/gifs/run"}
+	r := setupTestServer(t, meme)
+	asset, err := MirrorMemeMedia(meme)
+	if err != nil {
+		t.Fatalf("鏡像失敗: %v", err)
+	}
+
+	var got Meme
+	json.Unmarshal(doRequest(r, "GET", "/api/memes/1").Body.Bytes(), &got)
+	if got.ThumbnailURL != "/media/"+asset.Hash+"/poster" || got.PreviewURL != "/media/"+asset.Hash+"/preview" {
+		t.Fatalf("縮圖網址不符: %+v", got)
+	}
+
+	w := doRequest(r, "GET", got.ThumbnailURL)
+	poster, err := jpeg.Decode(w.Body)
+	if w.Code != http.StatusOK || err != nil || poster.Bounds().Dx() != posterMaxWidth || poster.Bounds().Dy() != 240 {
+		t.Fatalf("封面不符: %d %v", w.Code, err)
+	}
+
+	w = doRequest(r, "GET", got.PreviewURL)
+	preview, err := gif.DecodeAll(w.Body)
+	if w.Code != http.StatusOK || err != nil {
+		t.Fatalf("讀取預覽失敗: %d %v", w.Code, err)
+	}
+	total := 0
+	for _, d := range preview.Delay {
+		total += d
+	}
+	if len(preview.Image) > maxPreviewFrames || preview.Config.Width != previewMaxWidth || total != 90*5 {
+		t.Errorf("預覽不符: %d 格、寬 %d、總長 %d", len(preview.Image), preview.Config.Width, total)
+	}
+	// 最後一格應包含之前所有影格疊起來的紅色軌跡
+	last := preview.Image[len(preview.Image)-1]
+	if r, _, _, _ := last.At(10, 2).RGBA(); r>>8 != 255 {
+		t.Errorf("影格沒有正確疊圖")
+	}
+
+	// 縮圖被刪掉 (或舊資料) 時，讀取不會在請求中解碼 GIF，改由 GeneratePendingThumbnails 補產生
+	os.Remove(thumbnailPath(asset.Hash, thumbPoster))
+	db.Exec(`UPDATE memes SET media_thumbnails = 0`)
+	if w := doRequest(r, "GET", got.ThumbnailURL); w.Code != http.StatusNotFound {
+		t.Errorf("沒有縮圖時預期 404，得到 %d", w.Code)
+	}
+	got = Meme{}
+	json.Unmarshal(doRequest(r, "GET", "/api/memes/1").Body.Bytes(), &got)
+	if got.ThumbnailURL != "" {
+		t.Errorf("縮圖產生前不應提供網址: %+v", got)
+	}
+	if n, err := GeneratePendingThumbnails(); err != nil || n != 1 {
+		t.Fatalf("應補產生 1 個縮圖: %d (%v)", n, err)
+	}
+	if w := doRequest(r, "GET", got.MediaURL+"/poster"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("補產生封面失敗: %d", w.Code)
+	}
+
+	if w := doRequest(r, "GET", "/media/"+asset.Hash+"/original"); w.Code != http.StatusNotFound {
+		t.Errorf("未知的縮圖種類預期 404，得到 %d", w.Code)
+	}
+}
+
+func TestThumbnailsFollowMimeType(t *testing.T) {
+	// 網址寫 .gif，實際內容是 PNG
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+	defer origin.Close()
+
+	MediaDir = t.TempDir()
+	meme := ExportMeme{Title: "其實是 PNG", URL: origin.URL + "/fake.gif", SourceURL: "https://www.gif-vif.com/gifs/fake"}
+	r := setupTestServer(t, meme)
+	asset, err := MirrorMemeMedia(meme)
+	if err != nil || asset.MimeType != "image/png" {
+		t.Fatalf("鏡像失敗: %+v (%v)", asset, err)
+	}
+
+	var got Meme
+	json.Unmarshal(doRequest(r, "GET", "/api/memes/1").Body.Bytes(), &got)
+	if got.MediaURL == "" || got.ThumbnailURL != "" || got.PreviewURL != "" {
+		t.Errorf("不是 GIF 不應提供縮圖網址: %+v", got)
+	}
+	if n, _ := GeneratePendingThumbnails(); n != 0 {
+		t.Errorf("不是 GIF 不應產生縮圖")
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// =========================================================
+// [使用者帳號、我的最愛與收藏集]
+// =========================================================
+
+var (
+	ErrUsernameTaken   = errors.New("使用者名稱已被使用")
+	ErrBadCredentials  = errors.New("帳號或密碼錯誤")
+	ErrInvalidUsername = errors.New("使用者名稱需為 3~32 個英數字或底線")
+	ErrWeakPassword    = errors.New("密碼至少需要 8 個字元")
+	ErrCollectionTaken = errors.New("已經有同名的收藏集")
+)
+
+const sessionCookie = "session"
+const sessionTTL = 30 * 24 * time.Hour
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
+
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	CreatedAt string `json:"created_at"`
+}
+
+type Collection struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Count     int    `json:"count"`
+	CreatedAt string `json:"created_at"`
+	Memes     []Meme `json:"memes,omitempty"`
+}
+
+// CreateUser 以 bcrypt 雜湊密碼後建立帳號
+func CreateUser(username, password string) (User, error) {
+	if !usernamePattern.MatchString(username) {
+		return User{}, ErrInvalidUsername
+	}
+	if len(password) < 8 {
+		return User{}, ErrWeakPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, err
+	}
+
+	res, err := db.Exec(`INSERT INTO users (username, password_hash) VALUES (?, ?)`, username, string(hash))
+	if isUniqueViolation(err) {
+		return User{}, ErrUsernameTaken
+	}
+	if err != nil {
+		return User{}, err
+	}
+	id, _ := res.LastInsertId()
+	return getUser(id)
+}
+
+func getUser(id int64) (User, error) {
+	var u User
+	err := db.QueryRow(`SELECT id, username, created_at FROM users WHERE id = ?`, id).Scan(&u.ID, &u.Username, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return User{}, ErrNotFound
+	}
+	return u, err
+}
+
+// Authenticate 驗證帳號密碼，帳號不存在與密碼錯誤回傳同一個錯誤
+func Authenticate(username, password string) (User, error) {
+	var id int64
+	var hash string
+	err := db.QueryRow(`SELECT id, password_hash FROM users WHERE username = ?`, username).Scan(&id, &hash)
+	if err == sql.ErrNoRows {
+		return User{}, ErrBadCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return User{}, ErrBadCredentials
+	}
+	return getUser(id)
+}
+
+// CreateSession 產生登入用的 session token，資料庫只存雜湊值
+func CreateSession(userID int64) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	_, err := db.Exec(`INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)`,
+		hashToken(token), userID, time.Now().Add(sessionTTL).UTC())
+	return token, err
+}
+
+// SessionUser 回傳 token 對應的使用者，過期或不存在時回傳 ErrNotFound
+func SessionUser(token string) (User, error) {
+	var userID int64
+	err := db.QueryRow(`SELECT user_id FROM sessions WHERE token_hash = ? AND expires_at > ?`, hashToken(token), time.Now().UTC()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return User{}, ErrNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	return getUser(userID)
+}
+
+func DeleteSession(token string) error {
+	_, err := db.Exec(`DELETE FROM sessions WHERE token_hash = ?`, hashToken(token))
+	return err
+}
+
+// ---------------------------------------------------------
+// 我的最愛
+// ---------------------------------------------------------
+
+// AddFavorite 收藏一筆資料，重複收藏不會報錯
+func AddFavorite(userID, memeID int64) error {
+	if _, err := GetMemeByID(memeID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`INSERT OR IGNORE INTO favorites (user_id, meme_id) VALUES (?, ?)`, userID, memeID)
+	return err
+}
+
+func RemoveFavorite(userID, memeID int64) error {
+	_, err := db.Exec(`DELETE FROM favorites WHERE user_id = ? AND meme_id = ?`, userID, memeID)
+	return err
+}
+
+// ListFavorites 依收藏時間倒序列出，已下架的項目不顯示
+func ListFavorites(userID int64) ([]Meme, error) {
+	return queryMemes(`SELECT `+prefixedMemeColumns+` FROM favorites JOIN memes ON memes.id = favorites.meme_id
+		WHERE favorites.user_id = ? AND memes.`+visibleSQL+` ORDER BY favorites.created_at DESC, favorites.rowid DESC`, userID)
+}
+
+func favoriteIDs(userID int64) ([]int64, error) {
+	rows, err := db.Query(`SELECT meme_id FROM favorites WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ---------------------------------------------------------
+// 收藏集
+// ---------------------------------------------------------
+
+func CreateCollection(userID int64, name string) (Collection, error) {
+	res, err := db.Exec(`INSERT INTO collections (user_id, name) VALUES (?, ?)`, userID, name)
+	if isUniqueViolation(err) {
+		return Collection{}, ErrCollectionTaken
+	}
+	if err != nil {
+		return Collection{}, err
+	}
+	id, _ := res.LastInsertId()
+	return GetCollection(userID, id)
+}
+
+// GetCollection 取得使用者自己的收藏集 (含內容)，別人的收藏集視為不存在
+func GetCollection(userID, collectionID int64) (Collection, error) {
+	var c Collection
+	err := db.QueryRow(`SELECT id, name, created_at FROM collections WHERE id = ? AND user_id = ?`, collectionID, userID).
+		Scan(&c.ID, &c.Name, &c.CreatedAt)
+	if err == sql.ErrNoRows {
+		return Collection{}, ErrNotFound
+	}
+	if err != nil {
+		return Collection{}, err
+	}
+
+	c.Memes, err = queryMemes(`SELECT `+prefixedMemeColumns+` FROM collection_items JOIN memes ON memes.id = collection_items.meme_id
+		WHERE collection_items.collection_id = ? AND memes.`+visibleSQL+` ORDER BY collection_items.added_at DESC, collection_items.rowid DESC`, c.ID)
+	c.Count = len(c.Memes)
+	return c, err
+}
+
+// ListCollections 列出使用者的收藏集，筆數和 GetCollection 一樣不算已刪除的資料
+func ListCollections(userID int64) ([]Collection, error) {
+	rows, err := db.Query(`SELECT c.id, c.name, c.created_at, COUNT(memes.id) FROM collections c
+		LEFT JOIN collection_items i ON i.collection_id = c.id
+		LEFT JOIN memes ON memes.id = i.meme_id AND memes.`+visibleSQL+`
+		WHERE c.user_id = ? GROUP BY c.id ORDER BY c.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []Collection{}
+	for rows.Next() {
+		var c Collection
+		if err := rows.Scan(&c.ID, &c.Name, &c.CreatedAt, &c.Count); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+func DeleteCollection(userID, collectionID int64) error {
+	res, err := db.Exec(`DELETE FROM collections WHERE id = ? AND user_id = ?`, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	_, err = db.Exec(`DELETE FROM collection_items WHERE collection_id = ?`, collectionID)
+	return err
+}
+
+// SetCollectionItem 將資料加入 (add=true) 或移出收藏集
+func SetCollectionItem(userID, collectionID, memeID int64, add bool) error {
+	var owner int64
+	err := db.QueryRow(`SELECT user_id FROM collections WHERE id = ?`, collectionID).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != userID) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if !add {
+		_, err = db.Exec(`DELETE FROM collection_items WHERE collection_id = ? AND meme_id = ?`, collectionID, memeID)
+		return err
+	}
+	if _, err := GetMemeByID(memeID); err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT OR IGNORE INTO collection_items (collection_id, meme_id) VALUES (?, ?)`, collectionID, memeID)
+	return err
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+// loadSession 讀取 session cookie，登入中的使用者會放進 context 的 "user"
+func loadSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token, err := c.Cookie(sessionCookie); err == nil && token != "" {
+			if u, err := SessionUser(token); err == nil {
+				c.Set("user", u)
+			}
+		}
+		c.Next()
+	}
+}
+
+// requireUser 未登入時回傳 401
+func requireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("user"); !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "請先登入"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func currentUser(c *gin.Context) User {
+	u, _ := c.Get("user")
+	user, _ := u.(User)
+	return user
+}
+
+func setSessionCookie(c *gin.Context, token string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, token, maxAge, "/", "", c.Request.TLS != nil, true)
+}
+
+type credentials struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// respondUserError 把帳號相關錯誤轉成 HTTP 狀態碼
+func respondUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUsernameTaken), errors.Is(err, ErrCollectionTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidUsername), errors.Is(err, ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrBadCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		respondStoreError(c, err)
+	}
+}
+
+func registerUserRoutes(api *gin.RouterGroup) {
+	login := func(c *gin.Context, u User) {
+		token, err := CreateSession(u.ID)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		setSessionCookie(c, token, int(sessionTTL.Seconds()))
+		c.JSON(http.StatusOK, u)
+	}
+
+	api.POST("/auth/register", func(c *gin.Context) {
+		var in credentials
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "請提供 username 與 password"})
+			return
+		}
+		u, err := CreateUser(strings.TrimSpace(in.Username), in.Password)
+		if err != nil {
+			respondUserError(c, err)
+			return
+		}
+		login(c, u)
+	})
+
+	api.POST("/auth/login", func(c *gin.Context) {
+		var in credentials
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "請提供 username 與 password"})
+			return
+		}
+		u, err := Authenticate(strings.TrimSpace(in.Username), in.Password)
+		if err != nil {
+			respondUserError(c, err)
+			return
+		}
+		login(c, u)
+	})
+
+	api.POST("/auth/logout", func(c *gin.Context) {
+		if token, err := c.Cookie(sessionCookie); err == nil {
+			DeleteSession(token)
+		}
+		setSessionCookie(c, "", -1)
+		c.Status(http.StatusNoContent)
+	})
+
+	me := api.Group("/me", requireUser())
+
+	me.GET("", func(c *gin.Context) {
+		u := currentUser(c)
+		ids, err := favoriteIDs(u.ID)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": u.ID, "username": u.Username, "favorite_ids": ids})
+	})
+
+	me.GET("/favorites", func(c *gin.Context) {
+		memes, err := ListFavorites(currentUser(c).ID)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, memes)
+	})
+
+	me.PUT("/favorites/:id", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		if err := AddFavorite(currentUser(c).ID, id); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	me.DELETE("/favorites/:id", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		if err := RemoveFavorite(currentUser(c).ID, id); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	me.GET("/collections", func(c *gin.Context) {
+		collections, err := ListCollections(currentUser(c).ID)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, collections)
+	})
+
+	me.POST("/collections", func(c *gin.Context) {
+		var in struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&in); err != nil || strings.TrimSpace(in.Name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "請提供收藏集名稱"})
+			return
+		}
+		col, err := CreateCollection(currentUser(c).ID, strings.TrimSpace(in.Name))
+		if err != nil {
+			respondUserError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, col)
+	})
+
+	me.GET("/collections/:cid", func(c *gin.Context) {
+		cid, ok := parseIDParam(c, "cid")
+		if !ok {
+			return
+		}
+		col, err := GetCollection(currentUser(c).ID, cid)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, col)
+	})
+
+	me.DELETE("/collections/:cid", func(c *gin.Context) {
+		cid, ok := parseIDParam(c, "cid")
+		if !ok {
+			return
+		}
+		if err := DeleteCollection(currentUser(c).ID, cid); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	setItem := func(add bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			cid, ok := parseIDParam(c, "cid")
+			if !ok {
+				return
+			}
+			id, ok := parseMemeID(c)
+			if !ok {
+				return
+			}
+			if err := SetCollectionItem(currentUser(c).ID, cid, id, add); err != nil {
+				respondStoreError(c, err)
+				return
+			}
+			c.Status(http.StatusNoContent)
+		}
+	}
+	me.PUT("/collections/:cid/memes/:id", setItem(true))
+	me.DELETE("/collections/:cid/memes/:id", setItem(false))
+}
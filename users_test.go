@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// userClient 模擬瀏覽器，登入後自動帶上 session cookie
+type userClient struct {
+	r      http.Handler
+	cookie *http.Cookie
+}
+
+func (u *userClient) do(method, path string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if u.cookie != nil {
+		req.AddCookie(u.cookie)
+	}
+	u.r.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie {
+			u.cookie = c
+		}
+	}
+	return w
+}
+
+func TestUserFavoritesAndCollections(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "貓咪", URL: "https://example.com/cat.gif", Tags: "cat", SourceURL: "https://www.gif-vif.com/gifs/cat"},
+		ExportMeme{Title: "狗狗", URL: "https://example.com/dog.gif", Tags: "dog", SourceURL: "https://www.gif-vif.com/gifs/dog"},
+	)
+	alice := &userClient{r: r}
+	bob := &userClient{r: r}
+
+	if w := alice.do("GET", "/api/me/favorites", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("未登入預期 401，得到 %d", w.Code)
+	}
+	if w := alice.do("POST", "/api/auth/register", credentials{Username: "alice", Password: "short"}); w.Code != http.StatusBadRequest {
+		t.Errorf("弱密碼預期 400，得到 %d", w.Code)
+	}
+	if w := alice.do("POST", "/api/auth/register", credentials{Username: "alice", Password: "correct horse"}); w.Code != http.StatusOK {
+		t.Fatalf("註冊失敗: %d %s", w.Code, w.Body.String())
+	}
+	if w := bob.do("POST", "/api/auth/register", credentials{Username: "alice", Password: "another pass"}); w.Code != http.StatusConflict {
+		t.Errorf("重複帳號預期 409，得到 %d", w.Code)
+	}
+	bob.do("POST", "/api/auth/register", credentials{Username: "bob", Password: "bob password"})
+
+	alice.do("PUT", "/api/me/favorites/1", nil)
+	alice.do("PUT", "/api/me/favorites/2", nil)
+	alice.do("DELETE", "/api/me/favorites/1", nil)
+	if w := alice.do("PUT", "/api/me/favorites/999", nil); w.Code != http.StatusNotFound {
+		t.Errorf("收藏不存在的資料預期 404，得到 %d", w.Code)
+	}
+
+	var favs []Meme
+	json.Unmarshal(alice.do("GET", "/api/me/favorites", nil).Body.Bytes(), &favs)
+	if len(favs) != 1 || favs[0].Title != "狗狗" {
+		t.Errorf("我的最愛不符: %+v", favs)
+	}
+	json.Unmarshal(bob.do("GET", "/api/me/favorites", nil).Body.Bytes(), &favs)
+	if len(favs) != 0 {
+		t.Errorf("bob 不應看到 alice 的收藏: %+v", favs)
+	}
+
+	var col Collection
+	json.Unmarshal(alice.do("POST", "/api/me/collections", map[string]any{"name": "動物"}).Body.Bytes(), &col)
+	alice.do("PUT", fmt.Sprintf("/api/me/collections/%d/memes/1", col.ID), nil)
+	json.Unmarshal(alice.do("GET", fmt.Sprintf("/api/me/collections/%d", col.ID), nil).Body.Bytes(), &col)
+	if col.Count != 1 || col.Memes[0].Title != "貓咪" {
+		t.Errorf("收藏集內容不符: %+v", col)
+	}
+	if w := bob.do("PUT", fmt.Sprintf("/api/me/collections/%d/memes/2", col.ID), nil); w.Code != http.StatusNotFound {
+		t.Errorf("修改別人的收藏集預期 404，得到 %d", w.Code)
+	}
+	if w := alice.do("GET", "/api/me/collections/abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("收藏集 id 格式錯誤預期 400，得到 %d", w.Code)
+	}
+
+	// 登出後 cookie 失效，重新登入可取回資料
+	alice.do("POST", "/api/auth/logout", nil)
+	if w := alice.do("GET", "/api/me", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("登出後預期 401，得到 %d", w.Code)
+	}
+	if w := alice.do("POST", "/api/auth/login", credentials{Username: "alice", Password: "wrong password"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("密碼錯誤預期 401，得到 %d", w.Code)
+	}
+	alice.do("POST", "/api/auth/login", credentials{Username: "alice", Password: "correct horse"})
+	var me struct {
+		FavoriteIDs []int64 `json:"favorite_ids"`
+	}
+	json.Unmarshal(alice.do("GET", "/api/me", nil).Body.Bytes(), &me)
+	if fmt.Sprint(me.FavoriteIDs) != "[2]" {
+		t.Errorf("重新登入後的收藏不符: %v", me.FavoriteIDs)
+	}
+
+	// 收藏集列表的筆數不算已刪除的資料，和收藏集內容一致
+	alice.do("PUT", fmt.Sprintf("/api/me/collections/%d/memes/2", col.ID), nil)
+	SetMemeDeleted(2, true, "test")
+	var cols []Collection
+	json.Unmarshal(alice.do("GET", "/api/me/collections", nil).Body.Bytes(), &cols)
+	if len(cols) != 1 || cols[0].Count != 1 {
+		t.Errorf("收藏集筆數應排除已刪除的資料: %+v", cols)
+	}
+}
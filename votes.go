@@ -0,0 +1,540 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [投票、瀏覽/複製次數與熱門排序]
+// =========================================================
+
+// 複製比瀏覽更能代表「真的有用」，所以權重較高
+const copyWeight = 0.5
+const viewWeight = 0.05
+
+var ErrInvalidVote = errors.New("value 只接受 1、-1 或 0 (取消投票)")
+
+type MemeStats struct {
+	MemeID    int64   `json:"meme_id"`
+	Upvotes   int     `json:"upvotes"`
+	Downvotes int     `json:"downvotes"`
+	Score     int     `json:"score"`
+	Views     int     `json:"views"`
+	Copies    int     `json:"copies"`
+	Hot       float64 `json:"hot"`
+}
+
+// RankedMeme 是排行榜回傳的資料，附帶統計數字
+type RankedMeme struct {
+	Meme
+	Stats MemeStats `json:"stats"`
+}
+
+// popularity 綜合投票與互動次數
+func (s MemeStats) popularity() float64 {
+	return float64(s.Upvotes-s.Downvotes) + copyWeight*float64(s.Copies) + viewWeight*float64(s.Views)
+}
+
+// hotScore 計算熱門度，公式說明見 database.go 的 hotEpoch
+func hotScore(popularity float64, createdUnix int64) float64 {
+	order := math.Log10(math.Max(math.Abs(popularity), 1))
+	sign := 0.0
+	if popularity > 0 {
+		sign = 1
+	} else if popularity < 0 {
+		sign = -1
+	}
+	return sign*order + float64(createdUnix-hotEpoch)/hotDecaySeconds
+}
+
+// refreshStats 重新計算票數與熱門度，需在交易中呼叫
+func refreshStats(tx *sql.Tx, memeID int64) (MemeStats, error) {
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO meme_stats (meme_id) VALUES (?)`, memeID); err != nil {
+		return MemeStats{}, err
+	}
+	_, err := tx.Exec(`UPDATE meme_stats SET
+		upvotes = (SELECT COUNT(*) FROM votes WHERE meme_id = ? AND value > 0),
+		downvotes = (SELECT COUNT(*) FROM votes WHERE meme_id = ? AND value < 0)
+		WHERE meme_id = ?`, memeID, memeID, memeID)
+	if err != nil {
+		return MemeStats{}, err
+	}
+
+	s := MemeStats{MemeID: memeID}
+	var createdUnix int64
+	err = tx.QueryRow(`SELECT s.upvotes, s.downvotes, s.views, s.copies, CAST(strftime('%s', m.created_at) AS INTEGER)
+		FROM meme_stats s JOIN memes m ON m.id = s.meme_id WHERE s.meme_id = ?`, memeID).
+		Scan(&s.Upvotes, &s.Downvotes, &s.Views, &s.Copies, &createdUnix)
+	if err != nil {
+		return MemeStats{}, err
+	}
+	s.Score = s.Upvotes - s.Downvotes
+	s.Hot = hotScore(s.popularity(), createdUnix)
+
+	_, err = tx.Exec(`UPDATE meme_stats SET hot = ?, updated_at = CURRENT_TIMESTAMP WHERE meme_id = ?`, s.Hot, memeID)
+	return s, err
+}
+
+// withStats 在交易中執行 fn 後重算統計
+func withStats(memeID int64, fn func(tx *sql.Tx) error) (MemeStats, error) {
+	if _, err := GetMemeByID(memeID); err != nil {
+		return MemeStats{}, err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return MemeStats{}, err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return MemeStats{}, err
+	}
+	s, err := refreshStats(tx, memeID)
+	if err != nil {
+		return MemeStats{}, err
+	}
+	return s, tx.Commit()
+}
+
+// Vote 記錄投票；同一個投票者只算一票，value 為 0 時取消投票
+func Vote(memeID int64, voter string, value int) (MemeStats, error) {
+	if value < -1 || value > 1 {
+		return MemeStats{}, ErrInvalidVote
+	}
+	return withStats(memeID, func(tx *sql.Tx) error {
+		if value == 0 {
+			_, err := tx.Exec(`DELETE FROM votes WHERE meme_id = ? AND voter = ?`, memeID, voter)
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO votes (meme_id, voter, value) VALUES (?, ?, ?)
+			ON CONFLICT (meme_id, voter) DO UPDATE SET value = excluded.value, created_at = CURRENT_TIMESTAMP`, memeID, voter, value)
+		return err
+	})
+}
+
+// RecordMemeEvent 累加瀏覽 (view) 或複製 (copy) 次數
+func RecordMemeEvent(memeID int64, event string) (MemeStats, error) {
+	column := map[string]string{"view": "views", "copy": "copies"}[event]
+	if column == "" {
+		return MemeStats{}, fmt.Errorf("未知的事件: %s", event)
+	}
+	return withStats(memeID, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO meme_stats (meme_id) VALUES (?)`, memeID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`UPDATE meme_stats SET `+column+` = `+column+` + 1 WHERE meme_id = ?`, memeID)
+		return err
+	})
+}
+
+// ---------------------------------------------------------
+// 瀏覽次數 (先累積在記憶體，定期批次寫入)
+// ---------------------------------------------------------
+
+// viewBuffer 暫存還沒寫入資料庫的瀏覽次數，每次 GET 都開寫入交易會和爬蟲搶 SQLite 的寫入鎖
+type viewBuffer struct {
+	mu      sync.Mutex
+	pending map[int64]int
+}
+
+var pendingViews = &viewBuffer{pending: map[int64]int{}}
+
+// recordView 記錄一次瀏覽，只累加在記憶體，由 FlushViews 寫入
+func recordView(memeID int64) {
+	pendingViews.mu.Lock()
+	pendingViews.pending[memeID]++
+	pendingViews.mu.Unlock()
+}
+
+// FlushViews 把累積的瀏覽次數在同一個交易中寫入並重算熱門度，回傳寫入的筆數。
+// 寫入失敗時次數會放回去，下次再試
+func FlushViews() (int, error) {
+	pendingViews.mu.Lock()
+	views := pendingViews.pending
+	pendingViews.pending = map[int64]int{}
+	pendingViews.mu.Unlock()
+	if len(views) == 0 {
+		return 0, nil
+	}
+
+	err := flushViews(views)
+	if err != nil {
+		pendingViews.mu.Lock()
+		for id, n := range views {
+			pendingViews.pending[id] += n
+		}
+		pendingViews.mu.Unlock()
+		return 0, err
+	}
+	return len(views), nil
+}
+
+func flushViews(views map[int64]int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, n := range views {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO meme_stats (meme_id) VALUES (?)`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE meme_stats SET views = views + ? WHERE meme_id = ?`, n, id); err != nil {
+			return err
+		}
+		// 資料在寫入前被刪除時 refreshStats 找不到，略過即可
+		if _, err := refreshStats(tx, id); err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// startViewFlusher 每隔 interval 寫入一次瀏覽次數，回傳的函式會停止並寫入剩下的次數
+func startViewFlusher(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				if _, err := FlushViews(); err != nil {
+					log.Printf("[Stats] 寫入瀏覽次數失敗: %v", err)
+				}
+				return
+			}
+			if _, err := FlushViews(); err != nil {
+				log.Printf("[Stats] 寫入瀏覽次數失敗: %v", err)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-finished
+	}
+}
+
+// GetMemeStats 取得統計數字，沒有任何紀錄時回傳全 0
+func GetMemeStats(memeID int64) (MemeStats, error) {
+	s := MemeStats{MemeID: memeID}
+	err := db.QueryRow(`SELECT upvotes, downvotes, views, copies, COALESCE(hot, 0) FROM meme_stats WHERE meme_id = ?`, memeID).
+		Scan(&s.Upvotes, &s.Downvotes, &s.Views, &s.Copies, &s.Hot)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	s.Score = s.Upvotes - s.Downvotes
+	return s, err
+}
+
+// GetTrending 列出最近 days 天內有互動的項目，依熱門度排序
+func GetTrending(mode string, days, limit int) ([]RankedMeme, error) {
+	rows, err := db.Query(`SELECT `+prefixedMemeColumns+`, s.upvotes, s.downvotes, s.views, s.copies, s.hot
+		FROM meme_stats s JOIN memes ON memes.id = s.meme_id
+		WHERE `+visibleSQL+aliveSQL+modeFilterSQL(mode)+` AND s.updated_at >= datetime('now', ?)
+		ORDER BY s.hot DESC LIMIT ?`, fmt.Sprintf("-%d days", days), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranked := []RankedMeme{}
+	for rows.Next() {
+		var r RankedMeme
+		var err error
+		r.Meme, err = scanMeme(rows, &r.Stats.Upvotes, &r.Stats.Downvotes, &r.Stats.Views, &r.Stats.Copies, &r.Stats.Hot)
+		if err != nil {
+			return nil, err
+		}
+		r.Stats.MemeID = r.ID
+		r.Stats.Score = r.Stats.Upvotes - r.Stats.Downvotes
+		ranked = append(ranked, r)
+	}
+	return ranked, rows.Err()
+}
+
+// ---------------------------------------------------------
+// 依人氣加權抽取
+// ---------------------------------------------------------
+
+// weightedPoolTTL 是加權分布的快取時間，票數變動最多延遲這麼久才反映在機率上
+const weightedPoolTTL = time.Minute
+
+// weightedPool 是某個模式下所有可抽取資料的累積權重，抽取時只需二分搜尋
+type weightedPool struct {
+	ids        []int64
+	cumulative []float64
+	built      time.Time
+}
+
+var weightedPools = struct {
+	mu     sync.Mutex
+	byMode map[string]*weightedPool
+}{byMode: map[string]*weightedPool{}}
+
+// loadWeightedPool 取得 mode 的加權分布，超過 weightedPoolTTL 才重新讀取資料庫
+func loadWeightedPool(mode string) (*weightedPool, error) {
+	mode = weightedPoolMode(mode)
+	weightedPools.mu.Lock()
+	defer weightedPools.mu.Unlock()
+	if p, ok := weightedPools.byMode[mode]; ok && time.Since(p.built) < weightedPoolTTL {
+		return p, nil
+	}
+
+	rows, err := db.Query(`SELECT memes.id, COALESCE(s.upvotes, 0), COALESCE(s.downvotes, 0), COALESCE(s.views, 0), COALESCE(s.copies, 0)
+		FROM memes LEFT JOIN meme_stats s ON s.meme_id = memes.id WHERE ` + visibleSQL + aliveSQL + modeFilterSQL(mode) + ` ORDER BY memes.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	p := &weightedPool{built: time.Now()}
+	total := 0.0
+	for rows.Next() {
+		var s MemeStats
+		if err := rows.Scan(&s.MemeID, &s.Upvotes, &s.Downvotes, &s.Views, &s.Copies); err != nil {
+			return nil, err
+		}
+		total += 1 + math.Max(s.popularity(), 0)
+		p.ids = append(p.ids, s.MemeID)
+		p.cumulative = append(p.cumulative, total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	weightedPools.byMode[mode] = p
+	return p, nil
+}
+
+// resetStatsCaches 清掉快取的分布與還沒寫入的瀏覽次數，測試換資料庫時使用
+func resetStatsCaches() {
+	weightedPools.mu.Lock()
+	weightedPools.byMode = map[string]*weightedPool{}
+	weightedPools.mu.Unlock()
+
+	pendingViews.mu.Lock()
+	pendingViews.pending = map[int64]int{}
+	pendingViews.mu.Unlock()
+}
+
+// pick 依權重抽出一個 id，u 為 [0, 1) 的亂數
+func (p *weightedPool) pick(u float64) int64 {
+	target := u * p.cumulative[len(p.cumulative)-1]
+	i := sort.Search(len(p.cumulative), func(i int) bool { return p.cumulative[i] > target })
+	return p.ids[min(i, len(p.ids)-1)]
+}
+
+// dropWeightedPool 讓下一次抽取重新讀取 mode 的分布
+func dropWeightedPool(mode string) {
+	weightedPools.mu.Lock()
+	defer weightedPools.mu.Unlock()
+	delete(weightedPools.byMode, weightedPoolMode(mode))
+}
+
+// weightedPoolMode 把 image / text 以外的值都視為 all，快取才不會因任意參數無限增加
+func weightedPoolMode(mode string) string {
+	if kindSQL(mode) == "" {
+		return "all"
+	}
+	return mode
+}
+
+// sampleRetries 是抽後不放回時每一筆最多重抽的次數，超過後改從剩下的資料逐一累加權重
+const sampleRetries = 20
+
+// sample 依權重抽出最多 n 個不重複、也不在 exclude 裡的 id (抽後不放回)。
+// 以原本的分布抽取並略過抽過的 id，機率等同從剩下的資料依權重抽取
+func (p *weightedPool) sample(n int, exclude []int64, float func() float64) []int64 {
+	if len(p.ids) == 0 {
+		return nil
+	}
+	skip := make(map[int64]bool, len(exclude)+n)
+	for _, id := range exclude {
+		skip[id] = true
+	}
+	var picked []int64
+	for tries := 0; len(picked) < n && tries < sampleRetries*n; tries++ {
+		if id := p.pick(float()); !skip[id] {
+			skip[id] = true
+			picked = append(picked, id)
+		}
+	}
+
+	// 被排除的資料佔了大部分權重時重抽很難命中，改成只在剩下的資料裡累加
+	for len(picked) < n {
+		weight := func(i int) float64 {
+			if skip[p.ids[i]] {
+				return 0
+			}
+			if i == 0 {
+				return p.cumulative[0]
+			}
+			return p.cumulative[i] - p.cumulative[i-1]
+		}
+		total := 0.0
+		for i := range p.ids {
+			total += weight(i)
+		}
+		if total == 0 {
+			break // 符合條件的資料已經全部抽完
+		}
+		target := float() * total
+		last := -1
+		for i := range p.ids {
+			if w := weight(i); w > 0 {
+				last = i
+				if target -= w; target < 0 {
+					break
+				}
+			}
+		}
+		skip[p.ids[last]] = true
+		picked = append(picked, p.ids[last])
+	}
+	return picked
+}
+
+// GetWeightedRandomMemes 依人氣加權抽取 opts.Count 筆不重複的資料：權重 = 1 + max(人氣, 0)，
+// 負評項目仍保有最低機率。opts.Rand 與 opts.Exclude 的用法和 GetRandomMemes 相同
+func GetWeightedRandomMemes(opts RandomOptions) ([]Meme, error) {
+	float := rand.Float64
+	if opts.Rand != nil {
+		float = opts.Rand.Float64
+	}
+	// 分布是快取的，抽到快取後才被刪除的資料時重新讀取分布再抽一次
+	for attempt := 0; ; attempt++ {
+		p, err := loadWeightedPool(opts.Mode)
+		if err != nil {
+			return nil, err
+		}
+		memes := []Meme{}
+		stale := false
+		for _, id := range p.sample(max(opts.Count, 1), opts.Exclude, float) {
+			m, err := GetMemeByID(id)
+			if errors.Is(err, ErrNotFound) {
+				stale = true
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			memes = append(memes, m)
+		}
+		if !stale || attempt > 0 {
+			return memes, nil
+		}
+		dropWeightedPool(opts.Mode)
+	}
+}
+
+// GetWeightedRandomMeme 依人氣加權抽取一筆
+func GetWeightedRandomMeme(mode string) (Meme, error) {
+	memes, err := GetWeightedRandomMemes(RandomOptions{Mode: mode, Count: 1})
+	if err != nil {
+		return Meme{}, err
+	}
+	if len(memes) == 0 {
+		return Meme{}, ErrNotFound
+	}
+	return memes[0], nil
+}
+
+// ---------------------------------------------------------
+// HTTP 處理
+// ---------------------------------------------------------
+
+// voterID 登入的使用者以帳號計票，其餘以 API key 或 IP 雜湊計票
+func voterID(c *gin.Context) string {
+	if u := currentUser(c); u.ID != 0 {
+		return "user:" + strconv.FormatInt(u.ID, 10)
+	}
+	if id, ok := c.Get("api_key_id"); ok {
+		return fmt.Sprintf("key:%d", id)
+	}
+	sum := sha256.Sum256([]byte(c.ClientIP()))
+	return "ip:" + hex.EncodeToString(sum[:8])
+}
+
+func registerVoteRoutes(api *gin.RouterGroup) {
+	api.GET("/memes/:id/stats", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		if _, err := GetMemeByID(id); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		stats, err := GetMemeStats(id)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+
+	api.POST("/memes/:id/vote", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		var in struct {
+			Value *int `json:"value" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidVote.Error()})
+			return
+		}
+		stats, err := Vote(id, voterID(c), *in.Value)
+		if errors.Is(err, ErrInvalidVote) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+
+	api.POST("/memes/:id/copy", func(c *gin.Context) {
+		id, ok := parseMemeID(c)
+		if !ok {
+			return
+		}
+		stats, err := RecordMemeEvent(id, "copy")
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+
+	api.GET("/trending", func(c *gin.Context) {
+		ranked, err := GetTrending(c.DefaultQuery("mode", "all"), queryInt(c, "days", 7, 365), queryInt(c, "limit", 20, 50))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ranked)
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHotScore(t *testing.T) {
+	day := int64(24 * 60 * 60)
+	now := int64(hotEpoch + 100*day)
+
+	// 同一時間建立，人氣越高越熱門；負評低於 0 分
+	if !(hotScore(100, now) > hotScore(10, now) && hotScore(10, now) > hotScore(0, now) && hotScore(0, now) > hotScore(-10, now)) {
+		t.Error("熱門度應隨人氣遞增")
+	}
+	// 舊項目需要更多人氣才能超越新項目 (12.5 小時約等於 10 倍人氣)
+	if hotScore(10, now-day) > hotScore(10, now) {
+		t.Error("相同人氣時新項目應較熱門")
+	}
+	if hotScore(1000, now-day) < hotScore(1, now) {
+		t.Error("人氣差距夠大時舊項目應能勝出")
+	}
+}
+
+func TestVotingAndRanking(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "老梗", URL: "https://example.com/old.gif", Tags: "old", SourceURL: "https://www.gif-vif.com/gifs/old"},
+		ExportMeme{Title: "新梗", URL: "https://example.com/new.gif", Tags: "new", SourceURL: "https://www.gif-vif.com/gifs/new"},
+	)
+
+	// 不同投票者各投一票，同一投票者改票只算最後一次
+	Vote(1, "user:1", 1)
+	Vote(1, "user:2", 1)
+	Vote(1, "user:3", 1)
+	Vote(1, "user:3", -1)
+	stats, err := Vote(2, "user:1", -1)
+	if err != nil {
+		t.Fatalf("投票失敗: %v", err)
+	}
+	if stats.Downvotes != 1 || stats.Score != -1 {
+		t.Errorf("票數不符: %+v", stats)
+	}
+	if _, err := Vote(1, "user:1", 5); err != ErrInvalidVote {
+		t.Errorf("不合法的票值應回傳 ErrInvalidVote，得到 %v", err)
+	}
+	if s, _ := GetMemeStats(1); s.Upvotes != 2 || s.Downvotes != 1 || s.Score != 1 {
+		t.Errorf("改票後票數不符: %+v", s)
+	}
+	for _, voter := range []string{"user:4", "user:5", "user:6", "user:7", "user:8", "user:9", "user:10", "user:11", "user:12"} {
+		Vote(1, voter, 1)
+	}
+
+	// 預設依新舊排序，top / hot 則是老梗在前
+	for sort, want := range map[string]string{"new": "新梗", "top": "老梗", "hot": "老梗"} {
+		results, err := SearchMemesWithOptions(SearchOptions{Sort: sort})
+		if err != nil || len(results) != 2 || results[0].Title != want {
+			t.Errorf("sort=%s 預期第一筆為 %s，得到 %+v (%v)", sort, want, results, err)
+		}
+	}
+	if w := doRequest(r, "GET", "/api/search?sort=random"); w.Code != http.StatusBadRequest {
+		t.Errorf("不合法的 sort 預期 400，得到 %d", w.Code)
+	}
+
+	// 透過 API 投票與複製
+	w := doJSON(r, "POST", "/api/memes/2/vote", `{"value": 1}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("投票 API 預期 200，得到 %d", w.Code)
+	}
+	if w := doRequest(r, "POST", "/api/memes/2/copy"); w.Code != http.StatusOK {
+		t.Errorf("複製 API 預期 200，得到 %d", w.Code)
+	}
+	if w := doJSON(r, "POST", "/api/memes/99/vote", `{"value": 1}`); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的資料預期 404，得到 %d", w.Code)
+	}
+
+	var trending []RankedMeme
+	json.Unmarshal(doRequest(r, "GET", "/api/trending").Body.Bytes(), &trending)
+	if len(trending) != 2 || trending[0].Stats.MemeID != trending[0].ID {
+		t.Errorf("熱門排行不符: %+v", trending)
+	}
+
+	if m, err := GetWeightedRandomMeme("image"); err != nil || m.ID == 0 {
+		t.Errorf("加權隨機失敗: %+v %v", m, err)
+	}
+	if _, err := GetWeightedRandomMeme("text"); err != ErrNotFound {
+		t.Errorf("沒有文字資料時應回傳 ErrNotFound，得到 %v", err)
+	}
+}
+
+func TestViewsAreFlushedInBatches(t *testing.T) {
+	r := setupTestServer(t,
+		ExportMeme{Title: "貓咪", URL: "https://example.com/cat.gif", Tags: "cat", SourceURL: "https://www.gif-vif.com/gifs/cat"},
+	)
+
+	for range 3 {
+		if w := doRequest(r, "GET", "/api/memes/1"); w.Code != http.StatusOK {
+			t.Fatalf("預期 200，得到 %d", w.Code)
+		}
+	}
+	// 瀏覽只累積在記憶體，還沒寫入資料庫
+	if s, _ := GetMemeStats(1); s.Views != 0 {
+		t.Errorf("寫入前瀏覽次數應為 0，得到 %d", s.Views)
+	}
+
+	recordView(99) // 不存在的資料不影響其他筆
+	if n, err := FlushViews(); err != nil || n != 2 {
+		t.Fatalf("預期寫入 2 筆，得到 %d (%v)", n, err)
+	}
+	s, _ := GetMemeStats(1)
+	if s.Views != 3 || s.Hot == 0 {
+		t.Errorf("寫入後瀏覽次數應為 3 並重算熱門度: %+v", s)
+	}
+	if n, _ := FlushViews(); n != 0 {
+		t.Errorf("沒有新的瀏覽時不應寫入，得到 %d", n)
+	}
+
+	// 停止時會把剩下的次數寫入
+	recordView(1)
+	stop := startViewFlusher(time.Hour)
+	stop()
+	if s, _ := GetMemeStats(1); s.Views != 4 {
+		t.Errorf("停止時應寫入剩下的瀏覽次數，得到 %d", s.Views)
+	}
+}
+
+func TestWeightedRandomUsesCachedPool(t *testing.T) {
+	setupTestServer(t,
+		ExportMeme{Title: "冷門", URL: "https://example.com/a.gif", Tags: "a", SourceURL: "https://www.gif-vif.com/gifs/a"},
+		ExportMeme{Title: "熱門", URL: "https://example.com/b.gif", Tags: "b", SourceURL: "https://www.gif-vif.com/gifs/b"},
+	)
+	for i := range 99 {
+		Vote(2, fmt.Sprintf("user:%d", i), 1)
+	}
+
+	p, err := loadWeightedPool("image")
+	if err != nil || len(p.ids) != 2 {
+		t.Fatalf("分布應包含 2 筆: %+v (%v)", p, err)
+	}
+	// 權重為 1 與 100
+	if p.pick(0) != 1 || p.pick(0.5) != 2 || p.pick(0.999) != 2 {
+		t.Errorf("應依累積權重抽取: %+v", p.cumulative)
+	}
+	if again, _ := loadWeightedPool("image"); again != p {
+		t.Error("快取期間不應重新讀取資料庫")
+	}
+
+	// 快取後才刪除的資料不會被抽到
+	SetMemeDeleted(2, true, "test")
+	for range 20 {
+		if m, err := GetWeightedRandomMeme("image"); err != nil || m.ID != 1 {
+			t.Fatalf("不應抽到已刪除的資料: %+v (%v)", m, err)
+		}
+	}
+}
@@ -0,0 +1,651 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =========================================================
+// [已儲存的搜尋與 Webhook 通知]
+// =========================================================
+
+// SavedSearch 是一組搜尋條件，新資料符合時 POST 到 WebhookURL。
+// Secret 用來簽署請求，只在建立時回傳一次
+type SavedSearch struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	Mode       string `json:"mode"`
+	WebhookURL string `json:"webhook_url"`
+	Secret     string `json:"secret,omitempty"`
+	Active     bool   `json:"active"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// 通知的狀態
+const (
+	DeliveryPending   = "pending"   // 等待送出 (包含等待重試)
+	DeliveryDelivered = "delivered" // 收到 2xx
+	DeliveryFailed    = "failed"    // 重試次數用完
+)
+
+// WebhookDelivery 是一次通知的紀錄，同一筆資料對同一個搜尋只會通知一次
+type WebhookDelivery struct {
+	ID             int64  `json:"id"`
+	SavedSearchID  int64  `json:"saved_search_id"`
+	MemeID         int64  `json:"meme_id"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"response_status"` // 最後一次的 HTTP 狀態碼，連線失敗時為 0
+	Error          string `json:"error,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	UpdatedAt      string `json:"updated_at"`
+}
+
+// ErrInvalidSavedSearch 建立或修改時的輸入錯誤
+var ErrInvalidSavedSearch = errors.New("已儲存的搜尋格式錯誤")
+
+// WebhookConfig 由環境變數設定
+type WebhookConfig struct {
+	MaxAttempts  int           // WEBHOOK_MAX_ATTEMPTS：包含第一次，用完就標記為失敗
+	RetryBase    time.Duration // WEBHOOK_RETRY_BASE_SEC：第 n 次失敗後等待 RetryBase·2^(n-1)
+	PollInterval time.Duration // 檢查新資料與待送通知的間隔
+	Timeout      time.Duration
+}
+
+// maxRetryDelay 是兩次重試之間最長的等待時間
+const maxRetryDelay = time.Hour
+
+// webhookScanBatch 是每次從資料庫讀取多少筆新資料來比對
+const webhookScanBatch = 100
+
+func webhookConfigFromEnv() WebhookConfig {
+	return WebhookConfig{
+		MaxAttempts:  envInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		RetryBase:    time.Duration(envInt("WEBHOOK_RETRY_BASE_SEC", 30)) * time.Second,
+		PollInterval: 2 * time.Second,
+		Timeout:      10 * time.Second,
+	}
+}
+
+// retryDelay 回傳第 attempts 次失敗之後要等多久
+func (cfg WebhookConfig) retryDelay(attempts int) time.Duration {
+	d := cfg.RetryBase
+	for i := 1; i < attempts && d < maxRetryDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryDelay)
+}
+
+// ---------------------------------------------------------
+// 資料存取
+// ---------------------------------------------------------
+
+const savedSearchColumns = `id, name, query, mode, webhook_url, active, created_at`
+
+func scanSavedSearch(row rowScanner) (SavedSearch, error) {
+	var s SavedSearch
+	err := row.Scan(&s.ID, &s.Name, &s.Query, &s.Mode, &s.WebhookURL, &s.Active, &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return s, ErrNotFound
+	}
+	return s, err
+}
+
+// validate 檢查搜尋語法、模式與網址，和搜尋 API 的規則相同
+func (s *SavedSearch) validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" {
+		return fmt.Errorf("%w: 請提供名稱", ErrInvalidSavedSearch)
+	}
+	if s.Mode == "" {
+		s.Mode = "all"
+	}
+	if !slices.Contains(v1Modes, s.Mode) {
+		return fmt.Errorf("%w: mode 需為 all、image 或 text", ErrInvalidSavedSearch)
+	}
+	if _, err := ParseSearchQuery(s.Query); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSavedSearch, err)
+	}
+	u, err := url.Parse(s.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: webhook_url 需為 http 或 https 網址", ErrInvalidSavedSearch)
+	}
+	return nil
+}
+
+// CreateSavedSearch 建立搜尋並產生簽章用的金鑰，回傳值的 Secret 只有這一次看得到
+func CreateSavedSearch(s SavedSearch) (SavedSearch, error) {
+	if err := s.validate(); err != nil {
+		return SavedSearch{}, err
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return SavedSearch{}, err
+	}
+	secret := "whsec_" + hex.EncodeToString(buf)
+
+	res, err := db.Exec(`INSERT INTO saved_searches (name, query, mode, webhook_url, secret, active) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.Name, s.Query, s.Mode, s.WebhookURL, secret, s.Active)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	id, _ := res.LastInsertId()
+	created, err := GetSavedSearch(id)
+	created.Secret = secret
+	return created, err
+}
+
+func GetSavedSearch(id int64) (SavedSearch, error) {
+	return scanSavedSearch(db.QueryRow(`SELECT `+savedSearchColumns+` FROM saved_searches WHERE id = ?`, id))
+}
+
+// ListSavedSearches 列出所有搜尋，activeOnly 時只列出啟用中的
+func ListSavedSearches(activeOnly bool) ([]SavedSearch, error) {
+	rows, err := db.Query(`SELECT `+savedSearchColumns+` FROM saved_searches WHERE active = 1 OR ? = 0 ORDER BY id`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	searches := []SavedSearch{}
+	for rows.Next() {
+		s, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+// UpdateSavedSearch 修改條件、網址或啟用狀態，金鑰不變
+func UpdateSavedSearch(id int64, s SavedSearch) (SavedSearch, error) {
+	if err := s.validate(); err != nil {
+		return SavedSearch{}, err
+	}
+	res, err := db.Exec(`UPDATE saved_searches SET name = ?, query = ?, mode = ?, webhook_url = ?, active = ? WHERE id = ?`,
+		s.Name, s.Query, s.Mode, s.WebhookURL, s.Active, id)
+	if err != nil {
+		return SavedSearch{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return SavedSearch{}, ErrNotFound
+	}
+	return GetSavedSearch(id)
+}
+
+// DeleteSavedSearch 刪除搜尋與它的通知紀錄
+func DeleteSavedSearch(id int64) error {
+	res, err := db.Exec(`DELETE FROM saved_searches WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	_, err = db.Exec(`DELETE FROM webhook_deliveries WHERE saved_search_id = ?`, id)
+	return err
+}
+
+const deliveryColumns = `id, saved_search_id, meme_id, status, attempts, response_status, error, created_at, updated_at`
+
+func scanDelivery(row rowScanner) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := row.Scan(&d.ID, &d.SavedSearchID, &d.MemeID, &d.Status, &d.Attempts, &d.ResponseStatus, &d.Error, &d.CreatedAt, &d.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return d, ErrNotFound
+	}
+	return d, err
+}
+
+func GetWebhookDelivery(id int64) (WebhookDelivery, error) {
+	return scanDelivery(db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+}
+
+// ListWebhookDeliveries 依時間倒序列出某個搜尋的通知紀錄
+func ListWebhookDeliveries(savedSearchID int64, limit int) ([]WebhookDelivery, error) {
+	rows, err := db.Query(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE saved_search_id = ? ORDER BY id DESC LIMIT ?`,
+		savedSearchID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RedeliverWebhook 把通知重新排入佇列 (例如接收端修好之後)，次數從頭計算
+func RedeliverWebhook(id int64) (WebhookDelivery, error) {
+	res, err := db.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, error = '', updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, DeliveryPending, time.Now().UnixMilli(), id)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return WebhookDelivery{}, ErrNotFound
+	}
+	return GetWebhookDelivery(id)
+}
+
+// ---------------------------------------------------------
+// 比對與送出
+// ---------------------------------------------------------
+
+// webhookPayload 是 POST 給接收端的內容
+type webhookPayload struct {
+	Event       string      `json:"event"`
+	SavedSearch SavedSearch `json:"saved_search"`
+	Meme        Meme        `json:"meme"`
+	MatchedAt   string      `json:"matched_at"`
+}
+
+// WebhookDispatcher 比對新資料並送出通知。待送的通知存在資料庫，重新啟動後會繼續重試
+type WebhookDispatcher struct {
+	cfg    WebhookConfig
+	syn    *synonymIndex
+	client *http.Client
+
+	mu     sync.Mutex // 同一時間只有一個 RunOnce，避免同一筆通知送兩次
+	scanMu sync.Mutex // 同一時間只有一個 ScanNewMemes，進度才不會倒退
+	stop   func()
+}
+
+func NewWebhookDispatcher(syn *synonymIndex, cfg WebhookConfig) *WebhookDispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	return &WebhookDispatcher{
+		cfg: cfg,
+		syn: syn,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// 轉址視為失敗，避免通知被導到其他地方
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Start 在背景比對新資料並送出通知，Stop 後停止。
+// 比對進度 (webhook_cursor) 存在資料庫，停機期間或一次寫入大量資料也不會漏掉；
+// memeEvents 只用來提早喚醒，事件被丟棄也沒關係
+func (d *WebhookDispatcher) Start() {
+	if _, err := webhookCursor(); err != nil {
+		log.Printf("[Webhook] 讀取比對進度失敗: %v", err)
+	}
+	events, unsubscribe := memeEvents.Subscribe(1)
+	done := make(chan struct{})
+	d.stop = func() {
+		unsubscribe()
+		close(done)
+	}
+
+	go func() {
+		ticker := time.NewTicker(d.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-events:
+			case <-ticker.C:
+			}
+			if _, err := d.ScanNewMemes(); err != nil {
+				log.Printf("[Webhook] 比對新資料失敗: %v", err)
+			}
+			if _, err := d.RunOnce(); err != nil {
+				log.Printf("[Webhook] 送出通知失敗: %v", err)
+			}
+		}
+	}()
+}
+
+func (d *WebhookDispatcher) Stop() {
+	if d.stop != nil {
+		d.stop()
+		d.stop = nil
+	}
+}
+
+// matchSavedSearch 以和搜尋 API 相同的語法與同義詞，檢查單筆資料是否符合
+func (d *WebhookDispatcher) matchSavedSearch(s SavedSearch, memeID int64) (Meme, bool, error) {
+	pq, err := ParseSearchQuery(s.Query)
+	if err != nil {
+		return Meme{}, false, err
+	}
+	memes, err := SearchMemesWithOptions(SearchOptions{
+		Query: pq.Text, Terms: d.syn.Expand(pq.Text), Mode: s.Mode, Limit: 1, HighlightTerms: pq.Phrases,
+		FilterSQL: pq.FilterSQL + ` AND memes.id = ?`, FilterArgs: append(slices.Clone(pq.FilterArgs), memeID),
+	})
+	if err != nil || len(memes) == 0 {
+		return Meme{}, false, err
+	}
+	return memes[0], true, nil
+}
+
+// webhookCursor 讀取已比對到的最後一筆 id。第一次啟動時從目前最新的一筆開始，既有資料不會觸發通知
+func webhookCursor() (int64, error) {
+	latest, err := LatestMemeID()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := db.Exec(`INSERT OR IGNORE INTO webhook_cursor (id, last_meme_id) VALUES (1, ?)`, latest); err != nil {
+		return 0, err
+	}
+	var cursor int64
+	if err := db.QueryRow(`SELECT last_meme_id FROM webhook_cursor WHERE id = 1`).Scan(&cursor); err != nil {
+		return 0, err
+	}
+	// 爬蟲重建了資料庫，id 從頭開始；從目前最新的一筆接著比對，避免把整個資料庫當成新資料
+	if cursor > latest {
+		cursor = latest
+		return cursor, setWebhookCursor(cursor)
+	}
+	return cursor, nil
+}
+
+func setWebhookCursor(id int64) error {
+	_, err := db.Exec(`UPDATE webhook_cursor SET last_meme_id = ? WHERE id = 1`, id)
+	return err
+}
+
+// ScanNewMemes 依 id 順序比對進度之後的所有資料，每比對完一筆就更新進度，回傳排入的通知筆數
+func (d *WebhookDispatcher) ScanNewMemes() (int, error) {
+	d.scanMu.Lock()
+	defer d.scanMu.Unlock()
+
+	cursor, err := webhookCursor()
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for {
+		memes, err := ListMemesAfter(cursor, "all", webhookScanBatch)
+		if err != nil {
+			return queued, err
+		}
+		for _, m := range memes {
+			n, err := d.MatchNewMeme(m)
+			queued += n
+			if err != nil {
+				return queued, err
+			}
+			cursor = m.ID
+			if err := setWebhookCursor(cursor); err != nil {
+				return queued, err
+			}
+		}
+		if len(memes) < webhookScanBatch {
+			return queued, nil
+		}
+	}
+}
+
+// MatchNewMeme 把新資料和每個啟用中的搜尋比對，符合的排入待送佇列，回傳排入的筆數
+func (d *WebhookDispatcher) MatchNewMeme(m Meme) (int, error) {
+	searches, err := ListSavedSearches(true)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, s := range searches {
+		matched, ok, err := d.matchSavedSearch(s, m.ID)
+		if err != nil {
+			log.Printf("[Webhook] 搜尋 #%d 比對失敗: %v", s.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		payload, err := json.Marshal(webhookPayload{
+			Event: "meme.matched", SavedSearch: s, Meme: matched, MatchedAt: time.Now().UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return queued, err
+		}
+		res, err := db.Exec(`INSERT OR IGNORE INTO webhook_deliveries (saved_search_id, meme_id, payload, status, next_attempt_at)
+			VALUES (?, ?, ?, ?, ?)`, s.ID, m.ID, string(payload), DeliveryPending, time.Now().UnixMilli())
+		if err != nil {
+			return queued, err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// signWebhook 計算 X-Webhook-Signature：HMAC-SHA256(secret, "時間戳.內容")，時間戳一起簽避免重送攻擊
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RunOnce 送出所有到期的通知，回傳嘗試送出的筆數
+func (d *WebhookDispatcher) RunOnce() (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	type job struct {
+		id          int64
+		payload     string
+		attempts    int
+		url, secret string
+	}
+	rows, err := db.Query(`SELECT d.id, d.payload, d.attempts, s.webhook_url, s.secret
+		FROM webhook_deliveries d JOIN saved_searches s ON s.id = d.saved_search_id
+		WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at LIMIT 50`, DeliveryPending, time.Now().UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	var jobs []job
+	for rows.Next() {
+		var j job
+		if err := rows.Scan(&j.id, &j.payload, &j.attempts, &j.url, &j.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		jobs = append(jobs, j)
+	}
+	rows.Close()
+
+	for _, j := range jobs {
+		status, err := d.send(j.id, j.url, j.secret, []byte(j.payload))
+		attempts := j.attempts + 1
+		if err == nil {
+			_, err = db.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, error = '', updated_at = CURRENT_TIMESTAMP
+				WHERE id = ?`, DeliveryDelivered, attempts, status, j.id)
+			if err != nil {
+				return len(jobs), err
+			}
+			continue
+		}
+
+		next, state := time.Now().Add(d.cfg.retryDelay(attempts)).UnixMilli(), DeliveryPending
+		if attempts >= d.cfg.MaxAttempts {
+			state = DeliveryFailed
+			log.Printf("[Webhook] 通知 #%d 重試 %d 次仍失敗: %v", j.id, attempts, err)
+		}
+		_, dbErr := db.Exec(`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, response_status = ?, error = ?,
+			updated_at = CURRENT_TIMESTAMP WHERE id = ?`, state, attempts, next, status, err.Error(), j.id)
+		if dbErr != nil {
+			return len(jobs), dbErr
+		}
+	}
+	return len(jobs), nil
+}
+
+// send 送出一次通知，2xx 以外都算失敗
+func (d *WebhookDispatcher) send(id int64, target, secret string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MemeSearch-Webhook/1.0")
+	req.Header.Set("X-Webhook-Event", "meme.matched")
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(id, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", signWebhook(secret, timestamp, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("接收端回傳 HTTP %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// ---------------------------------------------------------
+// 管理 API
+// ---------------------------------------------------------
+
+type savedSearchInput struct {
+	Name       string `json:"name"`
+	Query      string `json:"query"`
+	Mode       string `json:"mode"`
+	WebhookURL string `json:"webhook_url"`
+	Active     *bool  `json:"active"` // 預設啟用
+}
+
+func (in savedSearchInput) toSavedSearch() SavedSearch {
+	active := in.Active == nil || *in.Active
+	return SavedSearch{Name: in.Name, Query: in.Query, Mode: in.Mode, WebhookURL: in.WebhookURL, Active: active}
+}
+
+func respondSavedSearchError(c *gin.Context, err error) {
+	if errors.Is(err, ErrInvalidSavedSearch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	respondStoreError(c, err)
+}
+
+func registerWebhookRoutes(admin *gin.RouterGroup) {
+	admin.GET("/saved-searches", func(c *gin.Context) {
+		searches, err := ListSavedSearches(false)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, searches)
+	})
+
+	admin.POST("/saved-searches", func(c *gin.Context) {
+		var in savedSearchInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		s, err := CreateSavedSearch(in.toSavedSearch())
+		if err != nil {
+			respondSavedSearchError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, s)
+	})
+
+	admin.GET("/saved-searches/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		s, err := GetSavedSearch(id)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, s)
+	})
+
+	admin.PUT("/saved-searches/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		var in savedSearchInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("資料格式錯誤: %v", err)})
+			return
+		}
+		s, err := UpdateSavedSearch(id, in.toSavedSearch())
+		if err != nil {
+			respondSavedSearchError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, s)
+	})
+
+	admin.DELETE("/saved-searches/:id", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := DeleteSavedSearch(id); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	admin.GET("/saved-searches/:id/deliveries", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		if _, err := GetSavedSearch(id); err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		deliveries, err := ListWebhookDeliveries(id, queryInt(c, "limit", 50, 200))
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, deliveries)
+	})
+
+	admin.POST("/webhook-deliveries/:id/redeliver", func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id")
+		if !ok {
+			return
+		}
+		d, err := RedeliverWebhook(id)
+		if err != nil {
+			respondStoreError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, d)
+	})
+}
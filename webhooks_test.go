@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver 記錄收到的請求，前 failures 次回傳 500
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, failures int) (*webhookReceiver, string) {
+	rec := &webhookReceiver{failures: failures}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.requests = append(rec.requests, receivedWebhook{r.Header.Clone(), body})
+		if rec.failures > 0 {
+			rec.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return rec, srv.URL
+}
+
+func (rec *webhookReceiver) setFailures(n int) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.failures = n
+}
+
+func (rec *webhookReceiver) count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.requests)
+}
+
+// createSavedSearch 透過管理 API 建立搜尋，回傳含金鑰的結果
+func createSavedSearch(t *testing.T, r http.Handler, body map[string]any) SavedSearch {
+	t.Helper()
+	w := adminRequest(r, "POST", "/api/admin/saved-searches", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("建立搜尋失敗: %d %s", w.Code, w.Body.String())
+	}
+	var s SavedSearch
+	json.Unmarshal(w.Body.Bytes(), &s)
+	return s
+}
+
+func TestWebhookDeliversSignedPayload(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t)
+	rec, target := newWebhookReceiver(t, 0)
+	s := createSavedSearch(t, r, map[string]any{"name": "產品被提到", "query": "好喝茶", "mode": "text", "webhook_url": target})
+	if s.Secret == "" || !s.Active {
+		t.Fatalf("建立時應回傳金鑰且預設啟用: %+v", s)
+	}
+	createSavedSearch(t, r, map[string]any{"name": "停用", "query": "", "webhook_url": target, "active": false})
+
+	d := NewWebhookDispatcher(newSynonymIndex(), webhookConfigFromEnv())
+	InsertMeme(ExportMeme{Title: "好喝茶真的好喝", URL: "每天都要喝一杯好喝茶", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/a.html"})
+	InsertMeme(ExportMeme{Title: "好喝茶 GIF", URL: "https://example.com/tea.gif", Tags: "GIF", SourceURL: "https://www.gif-vif.com/gifs/tea"})
+	InsertMeme(ExportMeme{Title: "別的東西", URL: "和產品無關", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/b.html"})
+	for id := int64(1); id <= 3; id++ {
+		m, _ := GetMemeByID(id)
+		d.MatchNewMeme(m)
+	}
+	// 同一筆再比對一次也不會重複通知
+	m, _ := GetMemeByID(1)
+	if n, err := d.MatchNewMeme(m); err != nil || n != 0 {
+		t.Errorf("不應重複排入: %d (%v)", n, err)
+	}
+
+	if n, err := d.RunOnce(); err != nil || n != 1 {
+		t.Fatalf("應送出 1 筆 (模式與停用的搜尋都要過濾): %d (%v)", n, err)
+	}
+	if rec.count() != 1 {
+		t.Fatalf("接收端應收到 1 次，得到 %d", rec.count())
+	}
+
+	got := rec.requests[0]
+	ts := got.header.Get("X-Webhook-Timestamp")
+	if got.header.Get("X-Webhook-Signature") != signWebhook(s.Secret, ts, got.body) {
+		t.Errorf("簽章錯誤: %s", got.header.Get("X-Webhook-Signature"))
+	}
+	if signWebhook("whsec_wrong", ts, got.body) == got.header.Get("X-Webhook-Signature") {
+		t.Errorf("不同的金鑰不應得到相同簽章")
+	}
+	var payload webhookPayload
+	json.Unmarshal(got.body, &payload)
+	if payload.Event != "meme.matched" || payload.SavedSearch.ID != s.ID || payload.Meme.ID != 1 || payload.Meme.Snippet == "" {
+		t.Errorf("內容錯誤: %s", got.body)
+	}
+	if payload.SavedSearch.Secret != "" {
+		t.Errorf("通知內容不應包含金鑰")
+	}
+
+	w := adminRequest(r, "GET", "/api/admin/saved-searches/1/deliveries", nil)
+	var deliveries []WebhookDelivery
+	json.Unmarshal(w.Body.Bytes(), &deliveries)
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryDelivered || deliveries[0].Attempts != 1 || deliveries[0].ResponseStatus != http.StatusNoContent {
+		t.Errorf("通知紀錄錯誤: %s", w.Body.String())
+	}
+
+	// 列表不會再顯示金鑰
+	w = adminRequest(r, "GET", "/api/admin/saved-searches", nil)
+	var list []SavedSearch
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list) != 2 || list[0].Secret != "" {
+		t.Errorf("列表錯誤: %s", w.Body.String())
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t, ExportMeme{Title: "好喝茶", URL: "好喝茶好喝", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/a.html"})
+	rec, target := newWebhookReceiver(t, 2)
+	createSavedSearch(t, r, map[string]any{"name": "茶", "query": "好喝茶", "webhook_url": target})
+
+	cfg := WebhookConfig{MaxAttempts: 3, RetryBase: 30 * time.Millisecond, Timeout: time.Second}
+	d := NewWebhookDispatcher(newSynonymIndex(), cfg)
+	m, _ := GetMemeByID(1)
+	d.MatchNewMeme(m)
+
+	d.RunOnce()
+	if n, _ := d.RunOnce(); n != 0 {
+		t.Errorf("還沒到重試時間不應送出")
+	}
+	delivery, _ := GetWebhookDelivery(1)
+	if delivery.Status != DeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != 500 || delivery.Error == "" {
+		t.Errorf("失敗後應等待重試: %+v", delivery)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	d.RunOnce()
+	time.Sleep(90 * time.Millisecond) // 第二次失敗後等待加倍
+	d.RunOnce()
+	delivery, _ = GetWebhookDelivery(1)
+	if delivery.Status != DeliveryDelivered || delivery.Attempts != 3 || rec.count() != 3 {
+		t.Fatalf("第三次應成功: %+v (收到 %d 次)", delivery, rec.count())
+	}
+
+	// 次數用完就標記為失敗，修好之後可以手動重送
+	rec.setFailures(10)
+	InsertMeme(ExportMeme{Title: "好喝茶 2", URL: "又是好喝茶", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/b.html"})
+	m, _ = GetMemeByID(2)
+	d.MatchNewMeme(m)
+	for i := 0; i < 3; i++ {
+		d.RunOnce()
+		time.Sleep(130 * time.Millisecond)
+	}
+	delivery, _ = GetWebhookDelivery(2)
+	if delivery.Status != DeliveryFailed || delivery.Attempts != 3 {
+		t.Fatalf("重試次數用完應標記為失敗: %+v", delivery)
+	}
+
+	rec.setFailures(0)
+	w := adminRequest(r, "POST", "/api/admin/webhook-deliveries/2/redeliver", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("重送失敗: %d %s", w.Code, w.Body.String())
+	}
+	d.RunOnce()
+	if delivery, _ = GetWebhookDelivery(2); delivery.Status != DeliveryDelivered || delivery.Attempts != 1 {
+		t.Errorf("重送後應成功: %+v", delivery)
+	}
+	if w := adminRequest(r, "POST", "/api/admin/webhook-deliveries/99/redeliver", nil); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的通知應回傳 404，得到 %d", w.Code)
+	}
+}
+
+func TestWebhookDispatcherFollowsNewMemes(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t)
+	rec, target := newWebhookReceiver(t, 0)
+	createSavedSearch(t, r, map[string]any{"name": "貓", "query": "tag:cat", "webhook_url": target})
+
+	d := NewWebhookDispatcher(newSynonymIndex(), WebhookConfig{MaxAttempts: 1, PollInterval: 10 * time.Millisecond, Timeout: time.Second})
+	d.Start()
+	t.Cleanup(d.Stop)
+
+	// 管理員新增的資料也會觸發
+	w := adminRequest(r, "POST", "/api/admin/memes", map[string]any{"title": "貓咪", "url": "https://example.com/cat.gif", "tags": "cat"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("新增失敗: %d", w.Code)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for rec.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rec.count() != 1 {
+		t.Errorf("新資料應觸發通知，收到 %d 次", rec.count())
+	}
+}
+
+func TestWebhookScanCatchesUpFromCursor(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t, ExportMeme{Title: "好喝茶 舊的", URL: "之前就有的", Tags: "PTT", SourceURL: "https://www.ptt.cc/bbs/a.html"})
+	_, target := newWebhookReceiver(t, 0)
+	createSavedSearch(t, r, map[string]any{"name": "茶", "query": "好喝茶", "webhook_url": target})
+
+	d := NewWebhookDispatcher(newSynonymIndex(), webhookConfigFromEnv())
+	if n, err := d.ScanNewMemes(); err != nil || n != 0 {
+		t.Fatalf("第一次啟動不應通知既有資料: %d (%v)", n, err)
+	}
+
+	// 伺服器停機時爬蟲寫入了一大批資料 (超過一次讀取的筆數，也不會經過 memeEvents)
+	for i := 0; i < webhookScanBatch*2+5; i++ {
+		db.Exec(`INSERT INTO memes (title, url, tags, source_url) VALUES (?, ?, 'PTT', ?)`,
+			fmt.Sprintf("好喝茶 %d", i), fmt.Sprintf("內容 %d", i), fmt.Sprintf("https://www.ptt.cc/bbs/%d.html", i))
+	}
+	if n, err := d.ScanNewMemes(); err != nil || n != webhookScanBatch*2+5 {
+		t.Fatalf("應補上所有新資料: %d (%v)", n, err)
+	}
+	if n, _ := d.ScanNewMemes(); n != 0 {
+		t.Errorf("進度已更新，不應再排入")
+	}
+	latest, _ := LatestMemeID()
+	if cursor, _ := webhookCursor(); cursor != latest {
+		t.Errorf("進度應為 %d，得到 %d", latest, cursor)
+	}
+}
+
+func TestSavedSearchValidation(t *testing.T) {
+	t.Setenv("ADMIN_TOKEN", "test-token")
+	r := setupTestServer(t)
+
+	for _, body := range []map[string]any{
+		{"name": "", "query": "貓", "webhook_url": "https://example.com/hook"},
+		{"name": "a", "query": "after:昨天", "webhook_url": "https://example.com/hook"},
+		{"name": "a", "query": "貓", "mode": "gif", "webhook_url": "https://example.com/hook"},
+		{"name": "a", "query": "貓", "webhook_url": "ftp://example.com/hook"},
+		{"name": "a", "query": "貓", "webhook_url": "/hook"},
+	} {
+		if w := adminRequest(r, "POST", "/api/admin/saved-searches", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%v 應回傳 400，得到 %d", body, w.Code)
+		}
+	}
+
+	createSavedSearch(t, r, map[string]any{"name": "a", "query": "貓", "webhook_url": "https://example.com/hook"})
+	w := adminRequest(r, "PUT", "/api/admin/saved-searches/1", map[string]any{"name": "b", "query": "狗", "webhook_url": "https://example.com/hook", "active": false})
+	var updated SavedSearch
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if w.Code != http.StatusOK || updated.Query != "狗" || updated.Active || updated.Mode != "all" {
+		t.Errorf("修改失敗: %d %s", w.Code, w.Body.String())
+	}
+	if w := adminRequest(r, "DELETE", "/api/admin/saved-searches/1", nil); w.Code != http.StatusNoContent {
+		t.Errorf("刪除失敗: %d", w.Code)
+	}
+	if w := adminRequest(r, "GET", "/api/admin/saved-searches/1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("刪除後應回傳 404，得到 %d", w.Code)
+	}
+	for _, req := range []struct{ method, path string }{
+		{"DELETE", "/api/admin/saved-searches/0"},
+		{"GET", "/api/admin/saved-searches/abc/deliveries"},
+		{"POST", "/api/admin/webhook-deliveries/x/redeliver"},
+	} {
+		if w := adminRequest(r, req.method, req.path, nil); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "id 格式錯誤") {
+			t.Errorf("%s 的 id 格式錯誤預期 400，得到 %d %s", req.path, w.Code, w.Body.String())
+		}
+	}
+	if w := doRequest(r, "GET", "/api/admin/saved-searches"); w.Code != http.StatusUnauthorized {
+		t.Errorf("管理 API 需要驗證，得到 %d", w.Code)
+	}
+
+	cfg := WebhookConfig{RetryBase: time.Minute}
+	if cfg.retryDelay(1) != time.Minute || cfg.retryDelay(3) != 4*time.Minute || cfg.retryDelay(20) != maxRetryDelay {
+		t.Errorf("退避時間錯誤: %v %v %v", cfg.retryDelay(1), cfg.retryDelay(3), cfg.retryDelay(20))
+	}
+}